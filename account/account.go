@@ -0,0 +1,201 @@
+package account
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	Cash          = "cash"
+	CreditCard    = "credit_card"
+	PromptPay     = "promptpay"
+	CorporateCard = "corporate_card"
+)
+
+var types = map[string]bool{
+	Cash:          true,
+	CreditCard:    true,
+	PromptPay:     true,
+	CorporateCard: true,
+}
+
+const DefaultCurrency = "THB"
+
+var (
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	last4        = regexp.MustCompile(`^[0-9]{4}$`)
+)
+
+// Account is where expenses are paid from and income is paid into. Its
+// balance is the sum of its ledger postings: the opening balance, income
+// and transfers in, less spending and transfers out, so a card's balance
+// goes negative by what is owed on it.
+type Account struct {
+	ID             int     `json:"id"`
+	Name           string  `json:"name"`
+	Type           string  `json:"type"`
+	Currency       string  `json:"currency"`
+	Last4          string  `json:"last4,omitempty"`
+	StatementDay   *int    `json:"statement_day,omitempty"`
+	DueDay         *int    `json:"due_day,omitempty"`
+	OpeningBalance float64 `json:"opening_balance"`
+	Balance        float64 `json:"balance"`
+}
+
+// Transaction is a ledger posting to an account with the account's
+// balance after it.
+type Transaction struct {
+	EntryID     int       `json:"entry_id"`
+	Kind        string    `json:"kind"`
+	ExpenseID   *int      `json:"expense_id,omitempty"`
+	PostedAt    time.Time `json:"posted_at"`
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount"`
+	Balance     float64   `json:"balance"`
+}
+
+// Period totals the expenses of one statement period, or of a calendar
+// month for accounts without a billing cycle. From and To are inclusive.
+type Period struct {
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+	Count int     `json:"count"`
+	Total float64 `json:"total"`
+}
+
+// Statement is a closed or open billing cycle of a card. From and To are
+// inclusive, To being the statement date. Total is what the statement
+// asks to pay: charges less refunds and other credits to the card.
+type Statement struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	DueDate string  `json:"due_date"`
+	Status  string  `json:"status"`
+	Count   int     `json:"count"`
+	Charges float64 `json:"charges"`
+	Credits float64 `json:"credits"`
+	Total   float64 `json:"total"`
+}
+
+// Statement statuses as of a date: still collecting charges, closed and
+// awaiting payment, or past its due date.
+const (
+	StatementOpen = "open"
+	StatementDue  = "due"
+	StatementPast = "past"
+)
+
+// Statements are the recent statements of a card, oldest first, and those
+// not yet past their due date.
+type Statements struct {
+	AsOf          string      `json:"as_of"`
+	Statements    []Statement `json:"statements"`
+	Upcoming      []Statement `json:"upcoming"`
+	UpcomingTotal float64     `json:"upcoming_total"`
+}
+
+const columns = "id, name, type, currency, last4, statement_day, due_day, opening_balance"
+
+func (a *Account) fields() []interface{} {
+	return []interface{}{&a.ID, &a.Name, &a.Type, &a.Currency, &a.Last4, &a.StatementDay, &a.DueDay, &a.OpeningBalance}
+}
+
+func (a Account) IsCard() bool {
+	return a.Type == CreditCard || a.Type == CorporateCard
+}
+
+// validate checks a and fills in the default currency.
+func validate(a *Account) error {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !types[a.Type] {
+		return fmt.Errorf("unknown account type %q", a.Type)
+	}
+	if a.Currency == "" {
+		a.Currency = DefaultCurrency
+	}
+	a.Currency = strings.ToUpper(a.Currency)
+	if !currencyCode.MatchString(a.Currency) {
+		return fmt.Errorf("invalid currency %q", a.Currency)
+	}
+	if a.Last4 != "" && !last4.MatchString(a.Last4) {
+		return fmt.Errorf("last4 must be 4 digits")
+	}
+
+	if !a.IsCard() {
+		if a.StatementDay != nil || a.DueDay != nil {
+			return fmt.Errorf("only cards have a billing cycle")
+		}
+		return nil
+	}
+	if a.StatementDay == nil || a.DueDay == nil {
+		return fmt.Errorf("statement_day and due_day are required for cards")
+	}
+	for name, day := range map[string]int{"statement_day": *a.StatementDay, "due_day": *a.DueDay} {
+		if day < 1 || day > 31 {
+			return fmt.Errorf("%s must be between 1 and 31", name)
+		}
+	}
+	return nil
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// clampDay returns day in the given month, moved back to the month's last
+// day when the month is shorter.
+func clampDay(year int, month time.Month, day int) time.Time {
+	if last := date(year, month+1, 0).Day(); day > last {
+		day = last
+	}
+	return date(year, month, day)
+}
+
+// statementDate is the date the statement for the given month closes.
+func (a Account) statementDate(year int, month time.Month) time.Time {
+	return clampDay(year, month, *a.StatementDay)
+}
+
+// cycle returns the first day of the period t falls in and the first day
+// of the next one. Card periods run from the day after one statement date
+// up to and including the next; other accounts use calendar months.
+func (a Account) cycle(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	if !a.IsCard() {
+		start := date(t.Year(), t.Month(), 1)
+		return start, start.AddDate(0, 1, 0)
+	}
+	closing := a.statementDate(t.Year(), t.Month())
+	if t.Day() > closing.Day() {
+		closing = a.statementDate(t.Year(), t.Month()+1)
+	}
+	previous := a.statementDate(closing.Year(), closing.Month()-1)
+	return previous.AddDate(0, 0, 1), closing.AddDate(0, 0, 1)
+}
+
+// cycles returns the bounds of the count periods up to and including the
+// one containing asOf: the first day of each, then the day after the last.
+func (a Account) cycles(asOf time.Time, count int) []time.Time {
+	bounds := make([]time.Time, count+1)
+	start, end := a.cycle(asOf)
+	bounds[count] = end
+	for i := count - 1; i >= 0; i-- {
+		bounds[i] = start
+		start, _ = a.cycle(start.AddDate(0, 0, -1))
+	}
+	return bounds
+}
+
+// dueDate is the first due day after the statement closing on closing.
+func (a Account) dueDate(closing time.Time) time.Time {
+	due := clampDay(closing.Year(), closing.Month(), *a.DueDay)
+	if !due.After(closing) {
+		due = clampDay(closing.Year(), closing.Month()+1, *a.DueDay)
+	}
+	return due
+}
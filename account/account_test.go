@@ -0,0 +1,81 @@
+//go:build unit
+
+package account
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func day(d int) *int {
+	return &d
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		account Account
+		err     string
+	}{
+		{"unknown type", Account{Name: "wallet", Type: "wallet"}, `unknown account type "wallet"`},
+		{"bad currency", Account{Name: "wallet", Type: Cash, Currency: "baht"}, `invalid currency "BAHT"`},
+		{"bad last4", Account{Name: "visa", Type: CreditCard, Last4: "12a4", StatementDay: day(15), DueDay: day(5)}, "last4 must be 4 digits"},
+		{"card without cycle", Account{Name: "visa", Type: CreditCard}, "statement_day and due_day are required for cards"},
+		{"cash with cycle", Account{Name: "wallet", Type: Cash, StatementDay: day(15)}, "only cards have a billing cycle"},
+		{"day out of range", Account{Name: "visa", Type: CorporateCard, StatementDay: day(32), DueDay: day(5)}, "statement_day must be between 1 and 31"},
+	}
+	for _, tc := range cases {
+		err := validate(&tc.account)
+		if assert.Error(t, err, tc.name) {
+			assert.Equal(t, tc.err, err.Error(), tc.name)
+		}
+	}
+
+	a := Account{Name: " wallet ", Type: PromptPay}
+	assert.NoError(t, validate(&a))
+	assert.Equal(t, "wallet", a.Name)
+	assert.Equal(t, DefaultCurrency, a.Currency)
+}
+
+func TestCycle(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	card := Account{Type: CreditCard, StatementDay: day(15), DueDay: day(5)}
+	endOfMonth := Account{Type: CreditCard, StatementDay: day(31), DueDay: day(20)}
+	cash := Account{Type: Cash}
+
+	cases := []struct {
+		account    Account
+		at         time.Time
+		start, end time.Time
+	}{
+		{card, date(2024, 1, 15), date(2023, 12, 16), date(2024, 1, 16)},
+		{card, date(2024, 1, 16), date(2024, 1, 16), date(2024, 2, 16)},
+		{card, date(2024, 12, 20), date(2024, 12, 16), date(2025, 1, 16)},
+		{endOfMonth, date(2024, 2, 29), date(2024, 2, 1), date(2024, 3, 1)},
+		{endOfMonth, date(2024, 3, 1), date(2024, 3, 1), date(2024, 4, 1)},
+		{cash, date(2024, 2, 10), date(2024, 2, 1), date(2024, 3, 1)},
+	}
+	for _, tc := range cases {
+		start, end := tc.account.cycle(tc.at)
+		assert.Equal(t, tc.start, start, tc.at.String())
+		assert.Equal(t, tc.end, end, tc.at.String())
+	}
+}
+
+func TestDueDate(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	nextMonth := Account{Type: CreditCard, StatementDay: day(15), DueDay: day(5)}
+	sameMonth := Account{Type: CreditCard, StatementDay: day(5), DueDay: day(25)}
+	endOfMonth := Account{Type: CreditCard, StatementDay: day(20), DueDay: day(31)}
+
+	assert.Equal(t, date(2024, 2, 5), nextMonth.dueDate(date(2024, 1, 15)))
+	assert.Equal(t, date(2025, 1, 5), nextMonth.dueDate(date(2024, 12, 15)))
+	assert.Equal(t, date(2024, 1, 25), sameMonth.dueDate(date(2024, 1, 5)))
+	assert.Equal(t, date(2024, 2, 29), endOfMonth.dueDate(date(2024, 2, 20)))
+}
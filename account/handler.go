@@ -0,0 +1,373 @@
+package account
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/ledger"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+// selectAccount selects the account columns followed by its balance.
+const selectAccount = `
+	SELECT a.id, a.name, a.type, a.currency, a.last4, a.statement_day, a.due_day, a.opening_balance,
+		COALESCE((SELECT SUM(p.amount) FROM postings p WHERE p.account_id = a.id), 0)
+	FROM accounts a`
+
+func scanAccount(row interface{ Scan(...interface{}) error }, a *Account) error {
+	return row.Scan(append(a.fields(), &a.Balance)...)
+}
+
+func bindAccount(c *gin.Context) (Account, bool) {
+	var a Account
+	if err := c.BindJSON(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return a, false
+	}
+	if err := validate(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return a, false
+	}
+	return a, true
+}
+
+func (h *handler) find(c *gin.Context) (Account, bool) {
+	var a Account
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return a, false
+	}
+
+	row := h.DB.QueryRow(selectAccount+" WHERE a.id = $1", id)
+	if err := scanAccount(row, &a); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+		return a, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return a, false
+	}
+	return a, true
+}
+
+func (h *handler) Create(c *gin.Context) {
+	a, ok := bindAccount(c)
+	if !ok {
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow(`
+		INSERT INTO accounts(name, type, currency, last4, statement_day, due_day, opening_balance)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+		`,
+		a.Name, a.Type, a.Currency, a.Last4, a.StatementDay, a.DueDay, a.OpeningBalance)
+	if err := row.Scan(&a.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ledger.SyncOpening(tx, a.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	a.Balance = a.OpeningBalance
+
+	c.JSON(http.StatusCreated, a)
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	rows, err := h.DB.Query(selectAccount + " ORDER BY a.name, a.id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	accounts := []Account{}
+	for rows.Next() {
+		var a Account
+		if err := scanAccount(rows, &a); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		accounts = append(accounts, a)
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	if a, ok := h.find(c); ok {
+		c.JSON(http.StatusOK, a)
+	}
+}
+
+func (h *handler) Update(c *gin.Context) {
+	existing, ok := h.find(c)
+	if !ok {
+		return
+	}
+	a, ok := bindAccount(c)
+	if !ok {
+		return
+	}
+	a.ID = existing.ID
+	a.Balance = existing.Balance - existing.OpeningBalance + a.OpeningBalance
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE accounts SET name=$2, type=$3, currency=$4, last4=$5, statement_day=$6, due_day=$7, opening_balance=$8 WHERE id=$1",
+		a.ID, a.Name, a.Type, a.Currency, a.Last4, a.StatementDay, a.DueDay, a.OpeningBalance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ledger.SyncOpening(tx, a.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, a)
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM accounts WHERE id = $1", a.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Transactions lists the ledger postings of an account, the opening
+// balance first and the rest oldest first, with the running balance after
+// each.
+func (h *handler) Transactions(c *gin.Context) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT j.id, j.kind, j.expense_id, j.posted_at, j.description, p.amount
+		FROM postings p JOIN journal_entries j ON j.id = p.entry_id
+		WHERE p.account_id = $1
+		ORDER BY j.kind <> $2, j.posted_at, j.id`, a.ID, ledger.KindOpening)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var balance float64
+	transactions := []Transaction{}
+	for rows.Next() {
+		var t Transaction
+		if err := rows.Scan(&t.EntryID, &t.Kind, &t.ExpenseID, &t.PostedAt, &t.Description, &t.Amount); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		balance += t.Amount
+		t.Balance = math.Round(balance*100) / 100
+		transactions = append(transactions, t)
+	}
+
+	c.JSON(http.StatusOK, transactions)
+}
+
+// MaxPeriods is the most statement periods listed at once, two years of
+// monthly statements.
+const MaxPeriods = 24
+
+// periodQuery reads how many periods to list, 6 by default and at most
+// MaxPeriods, and the date the last of them contains, today by default.
+func periodQuery(c *gin.Context) (int, time.Time, bool) {
+	count := 6
+	if v := c.Query("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid count %q", v)})
+			return 0, time.Time{}, false
+		}
+		if n > MaxPeriods {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be at most %d", MaxPeriods)})
+			return 0, time.Time{}, false
+		}
+		count = n
+	}
+	asOf := time.Now().UTC()
+	if v := c.Query("as_of"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid as_of %q", v)})
+			return 0, time.Time{}, false
+		}
+		asOf = t
+	}
+	return count, asOf, true
+}
+
+// Periods totals an account's expenses for its most recent statement
+// periods, the one containing as_of last.
+func (h *handler) Periods(c *gin.Context) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	count, asOf, ok := periodQuery(c)
+	if !ok {
+		return
+	}
+	bounds := a.cycles(asOf, count)
+
+	rows, err := h.DB.Query("SELECT amount, spent_at FROM expenses WHERE account_id = $1 AND spent_at >= $2 AND spent_at < $3 AND kind = $4",
+		a.ID, bounds[0], bounds[count], expense.KindExpense)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	periods := make([]Period, count)
+	for i := range periods {
+		periods[i].From = bounds[i].Format("2006-01-02")
+		periods[i].To = bounds[i+1].AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	for rows.Next() {
+		var amount float64
+		var spentAt time.Time
+		if err := rows.Scan(&amount, &spentAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := count - 1; i >= 0; i-- {
+			if !spentAt.Before(bounds[i]) {
+				periods[i].Count++
+				periods[i].Total = math.Round((periods[i].Total+amount)*100) / 100
+				break
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, periods)
+}
+
+// Statements groups a card's expenses and credits into its billing
+// cycles, the open one containing as_of last, with the due date of each,
+// and lists what is still to be paid and when. Like Periods it lists count
+// cycles, at most MaxPeriods.
+func (h *handler) Statements(c *gin.Context) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if !a.IsCard() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only cards have statements"})
+		return
+	}
+	count, asOf, ok := periodQuery(c)
+	if !ok {
+		return
+	}
+	bounds := a.cycles(asOf, count)
+	today := date(asOf.Year(), asOf.Month(), asOf.Day())
+
+	rows, err := h.DB.Query("SELECT amount, kind, spent_at FROM expenses WHERE account_id = $1 AND spent_at >= $2 AND spent_at < $3",
+		a.ID, bounds[0], bounds[count])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	statements := make([]Statement, count)
+	for i := range statements {
+		closing := bounds[i+1].AddDate(0, 0, -1)
+		due := a.dueDate(closing)
+		s := &statements[i]
+		s.From, s.To, s.DueDate = bounds[i].Format("2006-01-02"), closing.Format("2006-01-02"), due.Format("2006-01-02")
+		switch {
+		case !today.After(closing):
+			s.Status = StatementOpen
+		case !today.After(due):
+			s.Status = StatementDue
+		default:
+			s.Status = StatementPast
+		}
+	}
+	for rows.Next() {
+		var amount float64
+		var kind string
+		var spentAt time.Time
+		if err := rows.Scan(&amount, &kind, &spentAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := count - 1; i >= 0; i-- {
+			if !spentAt.Before(bounds[i]) {
+				statements[i].Count++
+				if kind == expense.KindIncome {
+					statements[i].Credits += amount
+				} else {
+					statements[i].Charges += amount
+				}
+				break
+			}
+		}
+	}
+
+	result := Statements{AsOf: today.Format("2006-01-02"), Statements: statements, Upcoming: []Statement{}}
+	for i := range statements {
+		s := &statements[i]
+		s.Charges, s.Credits = math.Round(s.Charges*100)/100, math.Round(s.Credits*100)/100
+		s.Total = math.Round((s.Charges-s.Credits)*100) / 100
+		if s.Status != StatementPast {
+			result.Upcoming = append(result.Upcoming, *s)
+			result.UpcomingTotal += s.Total
+		}
+	}
+	result.UpcomingTotal = math.Round(result.UpcomingTotal*100) / 100
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,259 @@
+//go:build unit
+
+package account
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var accountColumns = []string{"id", "name", "type", "currency", "last4", "statement_day", "due_day", "opening_balance", "balance"}
+
+func TestCreateAccount(t *testing.T) {
+	// Arrange
+	body := `{"name": "KBank Visa", "type": "credit_card", "last4": "1234", "statement_day": 15, "due_day": 5}`
+	req := httptest.NewRequest(http.MethodPost, "/accounts", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO accounts").
+		WithArgs("KBank Visa", CreditCard, "THB", "1234", 15, 5, 0.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("DELETE FROM journal_entries").
+		WithArgs("opening", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT name, type, opening_balance FROM accounts").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "type", "opening_balance"}).AddRow("KBank Visa", CreditCard, 0.0))
+	mock.ExpectCommit()
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.POST("/accounts", h.Create)
+	expect := `{"id":1,"name":"KBank Visa","type":"credit_card","currency":"THB","last4":"1234","statement_day":15,"due_day":5,"opening_balance":0,"balance":0}`
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestAccountTransactions(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/accounts/1/transactions", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	spentAt := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT (.+) FROM accounts a WHERE a.id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(accountColumns).AddRow(1, "wallet", Cash, "THB", "", nil, nil, 1000.0, 1230.0))
+	mock.ExpectQuery("SELECT (.+) FROM postings p JOIN journal_entries j").
+		WithArgs(1, "opening").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "expense_id", "posted_at", "description", "amount"}).
+			AddRow(1, "opening", nil, spentAt, "Opening balance", 1000.0).
+			AddRow(2, "expense", 1, spentAt, "lunch", -120.0).
+			AddRow(3, "income", 2, spentAt, "salary", 500.0).
+			AddRow(4, "expense", 3, spentAt, "taxi", -150.0))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.GET("/accounts/:id/transactions", h.Transactions)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"description":"lunch","amount":-120,"balance":880}`)
+	assert.Contains(t, rec.Body.String(), `"description":"salary","amount":500,"balance":1380}`)
+	assert.Contains(t, rec.Body.String(), `"description":"taxi","amount":-150,"balance":1230}`)
+}
+
+func TestAccountPeriods(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/accounts/1/periods?count=2&as_of=2024-02-20", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM accounts a WHERE a.id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(accountColumns).AddRow(1, "visa", CreditCard, "THB", "1234", 15, 5, 0.0, -500.0))
+	mock.ExpectQuery("SELECT amount, spent_at FROM expenses WHERE account_id = \\$1").
+		WithArgs(1, time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC), "expense").
+		WillReturnRows(sqlmock.NewRows([]string{"amount", "spent_at"}).
+			AddRow(100.0, time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)).
+			AddRow(150.0, time.Date(2024, 2, 15, 23, 0, 0, 0, time.UTC)).
+			AddRow(250.0, time.Date(2024, 2, 16, 0, 0, 0, 0, time.UTC)))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.GET("/accounts/:id/periods", h.Periods)
+	expect := `[{"from":"2024-01-16","to":"2024-02-15","count":2,"total":250},{"from":"2024-02-16","to":"2024-03-15","count":1,"total":250}]`
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestAccountPeriodsTooMany(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/accounts/1/periods?count=1000000000", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM accounts a WHERE a.id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(accountColumns).AddRow(1, "visa", CreditCard, "THB", "1234", 15, 5, 0.0, -500.0))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.GET("/accounts/:id/periods", h.Periods)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, `{"error":"count must be at most 24"}`, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestAccountStatements(t *testing.T) {
+	t.Run("Statements Of Cash Account Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/accounts/2/statements", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM accounts a WHERE a.id = \\$1").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(accountColumns).AddRow(2, "wallet", Cash, "THB", "", nil, nil, 0.0, 0.0))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/accounts/:id/statements", h.Statements)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Statements With Too Many Cycles Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/accounts/1/statements?count=1000000000", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM accounts a WHERE a.id = \\$1").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(accountColumns).AddRow(1, "visa", CreditCard, "THB", "1234", 15, 5, 0.0, -500.0))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/accounts/:id/statements", h.Statements)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Statements Should Total Cycles And List Upcoming Dues", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/accounts/1/statements?count=3&as_of=2024-03-03", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM accounts a WHERE a.id = \\$1").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(accountColumns).AddRow(1, "visa", CreditCard, "THB", "1234", 15, 5, 0.0, -500.0))
+		mock.ExpectQuery("SELECT amount, kind, spent_at FROM expenses WHERE account_id = \\$1").
+			WithArgs(1, time.Date(2023, 12, 16, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)).
+			WillReturnRows(sqlmock.NewRows([]string{"amount", "kind", "spent_at"}).
+				AddRow(200.0, "expense", time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)).
+				AddRow(1000.0, "expense", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)).
+				AddRow(100.0, "income", time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)).
+				AddRow(300.0, "expense", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/accounts/:id/statements", h.Statements)
+		past := `{"from":"2023-12-16","to":"2024-01-15","due_date":"2024-02-05","status":"past","count":1,"charges":200,"credits":0,"total":200}`
+		due := `{"from":"2024-01-16","to":"2024-02-15","due_date":"2024-03-05","status":"due","count":2,"charges":1000,"credits":100,"total":900}`
+		open := `{"from":"2024-02-16","to":"2024-03-15","due_date":"2024-04-05","status":"open","count":1,"charges":300,"credits":0,"total":300}`
+		expect := `{"as_of":"2024-03-03","statements":[` + past + `,` + due + `,` + open + `],"upcoming":[` + due + `,` + open + `],"upcoming_total":1200}`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
@@ -0,0 +1,161 @@
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jsritawan/assessment/user"
+)
+
+// Statuses of the sign-off workflow expenses go through before they are
+// paid back: an employee submits, a manager approves or rejects and
+// finance reimburses. Everything starts as a draft; a rejected item can be
+// submitted again.
+const (
+	StatusDraft      = "draft"
+	StatusSubmitted  = "submitted"
+	StatusApproved   = "approved"
+	StatusRejected   = "rejected"
+	StatusReimbursed = "reimbursed"
+)
+
+// Actions move an item from one status to the next.
+const (
+	Submit    = "submit"
+	Approve   = "approve"
+	Reject    = "reject"
+	Reimburse = "reimburse"
+)
+
+// Step is what an action requires and where it leads.
+type Step struct {
+	From  []string
+	To    string
+	Roles []string
+	// RequireComment makes the actor say why, as a rejection should.
+	RequireComment bool
+	// NotSubmitter keeps whoever submitted the item from taking the step,
+	// so nobody approves their own expenses.
+	NotSubmitter bool
+	// Identified requires the actor to be named, so the step is on record
+	// against someone.
+	Identified bool
+}
+
+// Workflow maps actions to their steps.
+type Workflow map[string]Step
+
+var Default = Workflow{
+	Submit: {
+		From:  []string{StatusDraft, StatusRejected},
+		To:    StatusSubmitted,
+		Roles: []string{user.RoleEmployee, user.RoleManager, user.RoleFinance},
+	},
+	Approve: {
+		From:         []string{StatusSubmitted},
+		To:           StatusApproved,
+		Roles:        []string{user.RoleManager},
+		NotSubmitter: true,
+		Identified:   true,
+	},
+	Reject: {
+		From:           []string{StatusSubmitted},
+		To:             StatusRejected,
+		Roles:          []string{user.RoleManager},
+		RequireComment: true,
+		NotSubmitter:   true,
+		Identified:     true,
+	},
+	Reimburse: {
+		From:       []string{StatusApproved},
+		To:         StatusReimbursed,
+		Roles:      []string{user.RoleFinance},
+		Identified: true,
+	},
+}
+
+// Errors returned by Next, so handlers can tell a caller who may not take
+// a step from a step that does not apply.
+var (
+	ErrUnidentified    = errors.New("user is required")
+	ErrForbidden       = errors.New("forbidden")
+	ErrInvalidStatus   = errors.New("invalid status")
+	ErrCommentRequired = errors.New("comment is required")
+)
+
+// StatusCode is the HTTP status to answer an error of Next with.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrUnidentified):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidStatus):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// Request is someone asking to take an action on an item.
+type Request struct {
+	Action  string
+	Status  string
+	Actor   string
+	Role    string
+	Comment string
+	// Submitter is who submitted the item last, if anyone.
+	Submitter string
+}
+
+// Next returns the status the request moves the item to.
+func (w Workflow) Next(r Request) (string, error) {
+	step, ok := w[r.Action]
+	if !ok {
+		return "", fmt.Errorf("unknown action %q", r.Action)
+	}
+	if step.Identified && r.Actor == "" {
+		return "", fmt.Errorf("%w to %s, name yourself in the %s header", ErrUnidentified, r.Action, user.Header)
+	}
+	if !contains(step.Roles, r.Role) {
+		return "", fmt.Errorf("%w: %s cannot %s, only %s", ErrForbidden, r.Role, r.Action, strings.Join(step.Roles, ", "))
+	}
+	if step.NotSubmitter && r.Actor == r.Submitter {
+		return "", fmt.Errorf("%w: %s cannot %s what they submitted", ErrForbidden, r.Actor, r.Action)
+	}
+	if !contains(step.From, r.Status) {
+		return "", fmt.Errorf("%w: cannot %s when %s, only when %s", ErrInvalidStatus, r.Action, r.Status, strings.Join(step.From, " or "))
+	}
+	if step.RequireComment && strings.TrimSpace(r.Comment) == "" {
+		return "", fmt.Errorf("%w to %s", ErrCommentRequired, r.Action)
+	}
+	return step.To, nil
+}
+
+// Locked reports whether an item in status has been signed off, after
+// which what was approved cannot change.
+func Locked(status string) bool {
+	return status == StatusApproved || status == StatusReimbursed
+}
+
+// Transition records one step taken.
+type Transition struct {
+	ID        int       `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Actor     string    `json:"actor"`
+	Role      string    `json:"role"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+//go:build unit
+
+package approval
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jsritawan/assessment/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Request
+		want string
+		err  error
+	}{
+		{"Employee Submits Draft", Request{Action: Submit, Status: StatusDraft, Role: user.RoleEmployee}, StatusSubmitted, nil},
+		{"Employee Resubmits Rejected", Request{Action: Submit, Status: StatusRejected, Role: user.RoleEmployee}, StatusSubmitted, nil},
+		{"Manager Approves", Request{Action: Approve, Status: StatusSubmitted, Actor: "bob", Role: user.RoleManager, Submitter: "ann"}, StatusApproved, nil},
+		{"Finance Reimburses Approved", Request{Action: Reimburse, Status: StatusApproved, Actor: "cat", Role: user.RoleFinance}, StatusReimbursed, nil},
+		{"Employee Cannot Approve", Request{Action: Approve, Status: StatusSubmitted, Actor: "ann", Role: user.RoleEmployee}, "", ErrForbidden},
+		{"Manager Cannot Approve Own Submission", Request{Action: Approve, Status: StatusSubmitted, Actor: "bob", Role: user.RoleManager, Submitter: "bob"}, "", ErrForbidden},
+		{"Approve Needs Actor", Request{Action: Approve, Status: StatusSubmitted, Role: user.RoleManager, Submitter: "ann"}, "", ErrUnidentified},
+		{"Manager Cannot Approve Draft", Request{Action: Approve, Status: StatusDraft, Actor: "bob", Role: user.RoleManager}, "", ErrInvalidStatus},
+		{"Finance Cannot Reimburse Submitted", Request{Action: Reimburse, Status: StatusSubmitted, Actor: "cat", Role: user.RoleFinance}, "", ErrInvalidStatus},
+		{"Reject Needs Comment", Request{Action: Reject, Status: StatusSubmitted, Actor: "bob", Role: user.RoleManager, Comment: " "}, "", ErrCommentRequired},
+		{"Reject With Comment", Request{Action: Reject, Status: StatusSubmitted, Actor: "bob", Role: user.RoleManager, Comment: "no receipt"}, StatusRejected, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Default.Next(tt.r)
+
+			assert.Equal(t, tt.want, got)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, tt.err), err)
+			}
+		})
+	}
+}
+
+func TestLocked(t *testing.T) {
+	assert.False(t, Locked(StatusDraft))
+	assert.False(t, Locked(StatusSubmitted))
+	assert.False(t, Locked(StatusRejected))
+	assert.True(t, Locked(StatusApproved))
+	assert.True(t, Locked(StatusReimbursed))
+}
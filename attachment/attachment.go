@@ -0,0 +1,11 @@
+package attachment
+
+type Attachment struct {
+	ID            int    `json:"id"`
+	ExpenseID     int    `json:"expense_id"`
+	Filename      string `json:"filename"`
+	ContentType   string `json:"content_type"`
+	Size          int64  `json:"size"`
+	Hash          string `json:"hash"`
+	ThumbnailHash string `json:"thumbnail_hash,omitempty"`
+}
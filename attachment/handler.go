@@ -0,0 +1,278 @@
+package attachment
+
+import (
+	"bytes"
+	"database/sql"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const DefaultMaxSize = 10 << 20
+
+var allowedTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"application/pdf": true,
+}
+
+type handler struct {
+	DB      *sql.DB
+	Store   BlobStore
+	MaxSize int64
+	// gc keeps collect from deleting a blob between an upload storing it,
+	// possibly deduplicated against an existing copy, and the attachment
+	// row that refers to it.
+	gc sync.RWMutex
+}
+
+func NewHandler(db *sql.DB, store BlobStore, maxSize int64) *handler {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &handler{
+		DB:      db,
+		Store:   store,
+		MaxSize: maxSize,
+	}
+}
+
+func (h *handler) Create(c *gin.Context) {
+	expenseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var exists bool
+	row := h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM expenses WHERE id = $1)", expenseID)
+	if err := row.Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expense not found"})
+		return
+	}
+
+	// leave room for the multipart envelope around the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MaxSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if header.Size > h.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	if !allowedTypes[contentType] {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported file type " + contentType})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attachment := Attachment{
+		ExpenseID:   expenseID,
+		Filename:    header.Filename,
+		ContentType: contentType,
+	}
+	h.gc.RLock()
+	defer h.gc.RUnlock()
+	attachment.Hash, attachment.Size, err = h.Store.Put(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		if _, err := file.Seek(0, io.SeekStart); err == nil {
+			// a receipt that cannot be decoded is still worth keeping
+			if thumb, err := thumbnail(file); err == nil {
+				attachment.ThumbnailHash, _, err = h.Store.Put(bytes.NewReader(thumb))
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+	}
+
+	row = h.DB.QueryRow(`
+		INSERT INTO attachments(expense_id, filename, content_type, size, blob_hash, thumbnail_hash)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+		`,
+		attachment.ExpenseID,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.Hash,
+		attachment.ThumbnailHash)
+	if err := row.Scan(&attachment.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	expenseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT id, expense_id, filename, content_type, size, blob_hash, thumbnail_hash
+		FROM attachments WHERE expense_id = $1 ORDER BY id`, expenseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.ExpenseID, &a.Filename, &a.ContentType, &a.Size, &a.Hash, &a.ThumbnailHash); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		attachments = append(attachments, a)
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+func (h *handler) find(c *gin.Context) (Attachment, bool) {
+	var a Attachment
+	expenseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return a, false
+	}
+	id, err := strconv.Atoi(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment id"})
+		return a, false
+	}
+
+	row := h.DB.QueryRow(`
+		SELECT id, expense_id, filename, content_type, size, blob_hash, thumbnail_hash
+		FROM attachments WHERE id = $1 AND expense_id = $2`, id, expenseID)
+	err = row.Scan(&a.ID, &a.ExpenseID, &a.Filename, &a.ContentType, &a.Size, &a.Hash, &a.ThumbnailHash)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+		return a, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return a, false
+	}
+	return a, true
+}
+
+func (h *handler) serve(c *gin.Context, hash, filename, contentType string) {
+	blob, err := h.Store.Open(hash)
+	if err == ErrBlobNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Header("ETag", `"`+hash+`"`)
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(c.Writer, c.Request, filename, time.Time{}, blob)
+}
+
+func (h *handler) Download(c *gin.Context) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+strings.ReplaceAll(a.Filename, `"`, "")+`"`)
+	h.serve(c, a.Hash, a.Filename, a.ContentType)
+}
+
+func (h *handler) Thumbnail(c *gin.Context) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if a.ThumbnailHash == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "attachment has no thumbnail"})
+		return
+	}
+	h.serve(c, a.ThumbnailHash, "thumbnail.jpg", "image/jpeg")
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	a, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM attachments WHERE id = $1", a.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, hash := range []string{a.Hash, a.ThumbnailHash} {
+		if hash == "" {
+			continue
+		}
+		if err := h.collect(hash); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// collect removes a blob once no attachment refers to it any more.
+func (h *handler) collect(hash string) error {
+	h.gc.Lock()
+	defer h.gc.Unlock()
+
+	var refs int
+	row := h.DB.QueryRow("SELECT COUNT(*) FROM attachments WHERE blob_hash = $1 OR thumbnail_hash = $1", hash)
+	if err := row.Scan(&refs); err != nil {
+		return err
+	}
+	if refs > 0 {
+		return nil
+	}
+	return h.Store.Delete(hash)
+}
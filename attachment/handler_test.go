@@ -0,0 +1,278 @@
+//go:build unit
+
+package attachment
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func multipartBody(t *testing.T, filename string, content []byte) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", filename)
+	assert.NoError(t, err)
+	_, err = part.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return &body, w.FormDataContentType()
+}
+
+func TestCreateAttachment(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n%receipt\n")
+
+	t.Run("Create Attachment For Unknown Expense Should Return Not Found", func(t *testing.T) {
+		// Arrange
+		body, contentType := multipartBody(t, "receipt.pdf", pdf)
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/attachments", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		store, _ := NewLocalStore(t.TempDir())
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db, store, 0)
+		r := gin.Default()
+		r.POST("/expenses/:id/attachments", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Create Attachment With Unsupported Type Should Return Unsupported Media Type", func(t *testing.T) {
+		// Arrange
+		body, contentType := multipartBody(t, "notes.txt", []byte("just some text"))
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/attachments", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		store, _ := NewLocalStore(t.TempDir())
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db, store, 0)
+		r := gin.Default()
+		r.POST("/expenses/:id/attachments", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	})
+
+	t.Run("Create Attachment Too Large Should Return Request Entity Too Large", func(t *testing.T) {
+		// Arrange
+		body, contentType := multipartBody(t, "receipt.pdf", pdf)
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/attachments", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		store, _ := NewLocalStore(t.TempDir())
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db, store, 4)
+		r := gin.Default()
+		r.POST("/expenses/:id/attachments", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+
+	t.Run("Create Attachment Should Return Created", func(t *testing.T) {
+		// Arrange
+		body, contentType := multipartBody(t, "receipt.pdf", pdf)
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/attachments", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("INSERT INTO attachments").
+			WithArgs(1, "receipt.pdf", "application/pdf", int64(len(pdf)), sqlmock.AnyArg(), "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+		store, _ := NewLocalStore(t.TempDir())
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db, store, 0)
+		r := gin.Default()
+		r.POST("/expenses/:id/attachments", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"id":3`)
+	})
+}
+
+func TestDownloadAttachment(t *testing.T) {
+	// Arrange
+	store, _ := NewLocalStore(t.TempDir())
+	hash, _, err := store.Put(strings.NewReader("%PDF-1.4 receipt"))
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/expenses/1/attachments/3", nil)
+	req.Header.Set("Range", "bytes=0-3")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM attachments").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "expense_id", "filename", "content_type", "size", "blob_hash", "thumbnail_hash"}).
+			AddRow(3, 1, "receipt.pdf", "application/pdf", 16, hash, ""))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db, store, 0)
+	r := gin.Default()
+	r.GET("/expenses/:id/attachments/:attachmentId", h.Download)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "%PDF", rec.Body.String())
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	t.Run("Delete Last Reference Should Remove Blob", func(t *testing.T) {
+		// Arrange
+		store, _ := NewLocalStore(t.TempDir())
+		hash, _, err := store.Put(strings.NewReader("%PDF-1.4 receipt"))
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodDelete, "/expenses/1/attachments/3", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM attachments").
+			WithArgs(3, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "expense_id", "filename", "content_type", "size", "blob_hash", "thumbnail_hash"}).
+				AddRow(3, 1, "receipt.pdf", "application/pdf", 16, hash, ""))
+		mock.ExpectExec("DELETE FROM attachments").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT COUNT").
+			WithArgs(hash).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db, store, 0)
+		r := gin.Default()
+		r.DELETE("/expenses/:id/attachments/:attachmentId", h.Delete)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		_, err = store.Open(hash)
+		assert.Equal(t, ErrBlobNotFound, err)
+	})
+
+	t.Run("Delete Shared Blob Should Keep It", func(t *testing.T) {
+		// Arrange
+		store, _ := NewLocalStore(t.TempDir())
+		hash, _, err := store.Put(strings.NewReader("%PDF-1.4 receipt"))
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodDelete, "/expenses/1/attachments/3", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM attachments").
+			WithArgs(3, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "expense_id", "filename", "content_type", "size", "blob_hash", "thumbnail_hash"}).
+				AddRow(3, 1, "receipt.pdf", "application/pdf", 16, hash, ""))
+		mock.ExpectExec("DELETE FROM attachments").
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT COUNT").
+			WithArgs(hash).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db, store, 0)
+		r := gin.Default()
+		r.DELETE("/expenses/:id/attachments/:attachmentId", h.Delete)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		_, err = store.Open(hash)
+		assert.NoError(t, err)
+	})
+}
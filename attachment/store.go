@@ -0,0 +1,100 @@
+package attachment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+type Blob interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// BlobStore keeps file contents addressed by the hex SHA-256 of their bytes,
+// so storing the same receipt twice only keeps one copy.
+type BlobStore interface {
+	Put(r io.Reader) (hash string, size int64, err error)
+	Open(hash string) (Blob, error)
+	Delete(hash string) error
+}
+
+type LocalStore struct {
+	Dir string
+}
+
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{Dir: dir}, nil
+}
+
+func (s *LocalStore) path(hash string) (string, error) {
+	if len(hash) != sha256.Size*2 {
+		return "", ErrBlobNotFound
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", ErrBlobNotFound
+	}
+	return filepath.Join(s.Dir, hash[:2], hash), nil
+}
+
+func (s *LocalStore) Put(r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(s.Dir, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), r)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	dst, _ := s.path(hash)
+	if _, err := os.Stat(dst); err == nil {
+		return hash, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", 0, err
+	}
+	return hash, size, nil
+}
+
+func (s *LocalStore) Open(hash string) (Blob, error) {
+	p, err := s.path(hash)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStore) Delete(hash string) error {
+	p, err := s.path(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
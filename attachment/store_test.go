@@ -0,0 +1,96 @@
+//go:build unit
+
+package attachment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	t.Run("Put Same Content Twice Should Return Same Hash", func(t *testing.T) {
+		store, err := NewLocalStore(t.TempDir())
+		assert.NoError(t, err)
+
+		first, size, err := store.Put(strings.NewReader("receipt"))
+		assert.NoError(t, err)
+		second, _, err := store.Put(strings.NewReader("receipt"))
+		assert.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.Equal(t, int64(7), size)
+	})
+
+	t.Run("Open Should Return Stored Content", func(t *testing.T) {
+		store, err := NewLocalStore(t.TempDir())
+		assert.NoError(t, err)
+		hash, _, err := store.Put(strings.NewReader("receipt"))
+		assert.NoError(t, err)
+
+		blob, err := store.Open(hash)
+		if assert.NoError(t, err) {
+			defer blob.Close()
+			data, err := io.ReadAll(blob)
+			assert.NoError(t, err)
+			assert.Equal(t, "receipt", string(data))
+		}
+	})
+
+	t.Run("Open Deleted Blob Should Return Not Found", func(t *testing.T) {
+		store, err := NewLocalStore(t.TempDir())
+		assert.NoError(t, err)
+		hash, _, err := store.Put(strings.NewReader("receipt"))
+		assert.NoError(t, err)
+
+		assert.NoError(t, store.Delete(hash))
+		_, err = store.Open(hash)
+
+		assert.Equal(t, ErrBlobNotFound, err)
+	})
+
+	t.Run("Open Invalid Hash Should Return Not Found", func(t *testing.T) {
+		store, err := NewLocalStore(t.TempDir())
+		assert.NoError(t, err)
+
+		_, err = store.Open("../../etc/passwd")
+
+		assert.Equal(t, ErrBlobNotFound, err)
+	})
+}
+
+func TestThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 800, 400))))
+
+	data, err := thumbnail(bytes.NewReader(buf.Bytes()))
+
+	if assert.NoError(t, err) {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, img.Bounds().Dx())
+		assert.Equal(t, 100, img.Bounds().Dy())
+	}
+}
+
+func TestThumbnailTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	// a PNG header claiming 100000x100000 pixels, with no pixel data
+	assert.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err := thumbnail(bytes.NewReader(data))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "too large")
+}
@@ -5,4 +5,268 @@ CREATE TABLE IF NOT EXISTS expenses (
     amount FLOAT,
     note TEXT,
     tags TEXT[]
-);
\ No newline at end of file
+);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS spent_at TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE INDEX IF NOT EXISTS expenses_spent_at_idx ON expenses (spent_at);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS expense_groups (
+    id SERIAL PRIMARY KEY,
+    name TEXT
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    id SERIAL PRIMARY KEY,
+    group_id INT REFERENCES expense_groups(id),
+    name TEXT
+);
+
+CREATE TABLE IF NOT EXISTS expense_shares (
+    expense_id INT REFERENCES expenses(id),
+    group_id INT REFERENCES expense_groups(id),
+    payer_id INT REFERENCES group_members(id),
+    member_id INT REFERENCES group_members(id),
+    split_type TEXT,
+    value FLOAT,
+    amount FLOAT
+);
+
+CREATE TABLE IF NOT EXISTS settlements (
+    id SERIAL PRIMARY KEY,
+    group_id INT REFERENCES expense_groups(id),
+    from_member_id INT REFERENCES group_members(id),
+    to_member_id INT REFERENCES group_members(id),
+    amount FLOAT
+);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    expense_id INT REFERENCES expenses(id),
+    filename TEXT,
+    content_type TEXT,
+    size BIGINT,
+    blob_hash TEXT,
+    thumbnail_hash TEXT
+);
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS search tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', coalesce(title, '') || ' ' || coalesce(note, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS expenses_search_idx ON expenses USING GIN (search);
+CREATE INDEX IF NOT EXISTS expenses_title_coalesce_trgm_idx ON expenses USING GIN ((coalesce(title, '')) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS expenses_note_coalesce_trgm_idx ON expenses USING GIN ((coalesce(note, '')) gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS saved_views (
+    id SERIAL PRIMARY KEY,
+    owner TEXT NOT NULL,
+    name TEXT NOT NULL,
+    filter TEXT NOT NULL DEFAULT '',
+    sort TEXT NOT NULL DEFAULT 'id',
+    columns TEXT[]
+);
+
+CREATE TABLE IF NOT EXISTS view_shares (
+    view_id INT REFERENCES saved_views(id) ON DELETE CASCADE,
+    user_name TEXT,
+    PRIMARY KEY (view_id, user_name)
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    name TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS tag_synonyms (
+    alias TEXT PRIMARY KEY,
+    tag TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rules (
+    id SERIAL PRIMARY KEY,
+    name TEXT,
+    position INT NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    conditions JSONB NOT NULL DEFAULT '[]',
+    actions JSONB NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE IF NOT EXISTS merchants (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    aliases TEXT[] NOT NULL DEFAULT '{}'
+);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS merchant_id INT REFERENCES merchants(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS expenses_merchant_id_idx ON expenses (merchant_id);
+
+CREATE TABLE IF NOT EXISTS accounts (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'THB',
+    last4 TEXT NOT NULL DEFAULT '',
+    statement_day INT,
+    due_day INT,
+    opening_balance FLOAT NOT NULL DEFAULT 0
+);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS expenses_account_id_idx ON expenses (account_id, spent_at);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'expense';
+CREATE INDEX IF NOT EXISTS expenses_kind_spent_at_idx ON expenses (kind, spent_at);
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id SERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    expense_id INT REFERENCES expenses(id) ON DELETE CASCADE,
+    account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+    posted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS journal_entries_expense_id_idx ON journal_entries (expense_id);
+
+CREATE TABLE IF NOT EXISTS postings (
+    id SERIAL PRIMARY KEY,
+    entry_id INT NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    account TEXT NOT NULL,
+    account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+    amount FLOAT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS postings_entry_id_idx ON postings (entry_id);
+CREATE INDEX IF NOT EXISTS postings_account_id_idx ON postings (account_id);
+
+CREATE TABLE IF NOT EXISTS export_mappings (
+    tag TEXT PRIMARY KEY,
+    account TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bank_transactions (
+    source TEXT NOT NULL,
+    transaction_id TEXT NOT NULL,
+    expense_id INT REFERENCES expenses(id) ON DELETE SET NULL,
+    imported_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (source, transaction_id)
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_sessions (
+    id SERIAL PRIMARY KEY,
+    account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+    source TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_lines (
+    id SERIAL PRIMARY KEY,
+    session_id INT NOT NULL REFERENCES reconciliation_sessions(id) ON DELETE CASCADE,
+    transaction_id TEXT NOT NULL,
+    posted_at TIMESTAMPTZ NOT NULL,
+    amount FLOAT NOT NULL,
+    payee TEXT NOT NULL DEFAULT '',
+    memo TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'unmatched',
+    expense_id INT REFERENCES expenses(id) ON DELETE SET NULL,
+    score FLOAT
+);
+CREATE INDEX IF NOT EXISTS reconciliation_lines_session_id_idx ON reconciliation_lines (session_id);
+CREATE UNIQUE INDEX IF NOT EXISTS reconciliation_lines_expense_id_idx ON reconciliation_lines (expense_id) WHERE expense_id IS NOT NULL;
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'draft';
+
+CREATE TABLE IF NOT EXISTS expense_transitions (
+    id SERIAL PRIMARY KEY,
+    expense_id INT NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    role TEXT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS expense_transitions_expense_id_idx ON expense_transitions (expense_id);
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_name TEXT PRIMARY KEY,
+    role TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS expense_reports (
+    id SERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    owner TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'draft',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS expense_report_items (
+    report_id INT NOT NULL REFERENCES expense_reports(id) ON DELETE CASCADE,
+    expense_id INT NOT NULL UNIQUE REFERENCES expenses(id) ON DELETE CASCADE,
+    PRIMARY KEY (report_id, expense_id)
+);
+
+CREATE TABLE IF NOT EXISTS expense_report_transitions (
+    id SERIAL PRIMARY KEY,
+    report_id INT NOT NULL REFERENCES expense_reports(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    role TEXT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS expense_report_transitions_report_id_idx ON expense_report_transitions (report_id);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS tax JSONB;
+
+CREATE TABLE IF NOT EXISTS deduction_categories (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    caps JSONB NOT NULL DEFAULT '[]'
+);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS deduction_id INT REFERENCES deduction_categories(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS expenses_deduction_id_idx ON expenses (deduction_id);
+
+CREATE TABLE IF NOT EXISTS installment_plans (
+    id SERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    total FLOAT NOT NULL,
+    months INT NOT NULL,
+    interest_rate FLOAT NOT NULL DEFAULT 0,
+    start_date TIMESTAMPTZ NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    category TEXT NOT NULL DEFAULT '',
+    account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS installments (
+    plan_id INT NOT NULL REFERENCES installment_plans(id) ON DELETE CASCADE,
+    number INT NOT NULL,
+    expense_id INT NOT NULL UNIQUE REFERENCES expenses(id) ON DELETE CASCADE,
+    PRIMARY KEY (plan_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS goals (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    target FLOAT NOT NULL,
+    deadline TIMESTAMPTZ NOT NULL,
+    start_date TIMESTAMPTZ NOT NULL,
+    monthly_budget FLOAT,
+    budget_tag TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS goal_contributions (
+    id SERIAL PRIMARY KEY,
+    goal_id INT NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    amount FLOAT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    contributed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS goal_contributions_goal_id_idx ON goal_contributions (goal_id);
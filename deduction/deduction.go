@@ -0,0 +1,117 @@
+package deduction
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Category is a kind of deductible spending, such as life insurance
+// premiums or donations, with the most that can be deducted each tax year.
+// Thai tax years are calendar years.
+type Category struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Caps        Caps   `json:"caps"`
+}
+
+type Cap struct {
+	Year   int     `json:"year"`
+	Amount float64 `json:"amount"`
+}
+
+// Caps are the yearly caps of a category, stored as JSON.
+type Caps []Cap
+
+func (c Caps) Value() (driver.Value, error) {
+	if c == nil {
+		c = Caps{}
+	}
+	return json.Marshal(c)
+}
+
+func (c *Caps) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("cannot scan %T into Caps", src)
+}
+
+// For returns the cap of year, or nil when the category has none that
+// year and so is not deductible.
+func (c Caps) For(year int) *float64 {
+	for _, limit := range c {
+		if limit.Year == year {
+			amount := limit.Amount
+			return &amount
+		}
+	}
+	return nil
+}
+
+func scanCategory(row interface{ Scan(...interface{}) error }, c *Category) error {
+	return row.Scan(&c.ID, &c.Name, &c.Description, &c.Caps)
+}
+
+// validate trims the name of c and checks its caps, at most one a year.
+func validate(c *Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.Caps == nil {
+		c.Caps = Caps{}
+	}
+	seen := map[int]bool{}
+	for _, limit := range c.Caps {
+		if limit.Year < 1900 || limit.Year > 9999 {
+			return fmt.Errorf("invalid year %d", limit.Year)
+		}
+		if limit.Amount < 0 {
+			return fmt.Errorf("invalid cap %v for %d", limit.Amount, limit.Year)
+		}
+		if seen[limit.Year] {
+			return fmt.Errorf("more than one cap for %d", limit.Year)
+		}
+		seen[limit.Year] = true
+	}
+	return nil
+}
+
+// Claim is what was spent in a category over a tax year against its cap.
+// Deductible is the part of Claimed within the cap and Remaining the
+// headroom left; both are null when the category has no cap that year.
+type Claim struct {
+	CategoryID int      `json:"category_id"`
+	Name       string   `json:"name"`
+	Count      int      `json:"count"`
+	Claimed    float64  `json:"claimed"`
+	Cap        *float64 `json:"cap"`
+	Deductible *float64 `json:"deductible"`
+	Remaining  *float64 `json:"remaining"`
+	OverCap    bool     `json:"over_cap"`
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// Settle works out the deductible part and headroom of the claim against
+// limit.
+func (c *Claim) Settle(limit *float64) {
+	c.Claimed = round(c.Claimed)
+	c.Cap, c.Deductible, c.Remaining, c.OverCap = limit, nil, nil, false
+	if limit == nil {
+		return
+	}
+	deductible := math.Min(c.Claimed, *limit)
+	remaining := round(math.Max(*limit-c.Claimed, 0))
+	c.Deductible, c.Remaining = &deductible, &remaining
+	c.OverCap = c.Claimed > *limit
+}
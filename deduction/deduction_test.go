@@ -0,0 +1,65 @@
+//go:build unit
+
+package deduction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("Valid Category Should Pass", func(t *testing.T) {
+		d := Category{Name: " Life insurance ", Caps: Caps{{Year: 2023, Amount: 100000}, {Year: 2024, Amount: 100000}}}
+
+		assert.NoError(t, validate(&d))
+		assert.Equal(t, "Life insurance", d.Name)
+	})
+
+	t.Run("Invalid Category Should Fail", func(t *testing.T) {
+		for _, d := range []Category{
+			{Name: " "},
+			{Name: "Donation", Caps: Caps{{Year: 24, Amount: 1000}}},
+			{Name: "Donation", Caps: Caps{{Year: 2024, Amount: -1}}},
+			{Name: "Donation", Caps: Caps{{Year: 2024, Amount: 1000}, {Year: 2024, Amount: 2000}}},
+		} {
+			d := d
+			assert.Error(t, validate(&d), d.Name)
+		}
+	})
+}
+
+func TestSettle(t *testing.T) {
+	limit := 50000.0
+
+	t.Run("Under Cap Should Leave Headroom", func(t *testing.T) {
+		c := Claim{Claimed: 30000.004}
+
+		c.Settle(&limit)
+
+		assert.Equal(t, 30000.0, c.Claimed)
+		assert.Equal(t, 30000.0, *c.Deductible)
+		assert.Equal(t, 20000.0, *c.Remaining)
+		assert.False(t, c.OverCap)
+	})
+
+	t.Run("Over Cap Should Deduct Only The Cap", func(t *testing.T) {
+		c := Claim{Claimed: 62000}
+
+		c.Settle(&limit)
+
+		assert.Equal(t, 50000.0, *c.Deductible)
+		assert.Equal(t, 0.0, *c.Remaining)
+		assert.True(t, c.OverCap)
+	})
+
+	t.Run("No Cap That Year Should Leave Deductible Null", func(t *testing.T) {
+		c := Claim{Claimed: 1000}
+
+		c.Settle(Caps{{Year: 2023, Amount: limit}}.For(2024))
+
+		assert.Nil(t, c.Cap)
+		assert.Nil(t, c.Deductible)
+		assert.Nil(t, c.Remaining)
+	})
+}
@@ -0,0 +1,141 @@
+package deduction
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+func bindCategory(c *gin.Context) (Category, bool) {
+	var d Category
+	if err := c.BindJSON(&d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return d, false
+	}
+	if err := validate(&d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return d, false
+	}
+	return d, true
+}
+
+func (h *handler) find(c *gin.Context) (Category, bool) {
+	var d Category
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return d, false
+	}
+
+	row := h.DB.QueryRow("SELECT id, name, description, caps FROM deduction_categories WHERE id = $1", id)
+	if err := scanCategory(row, &d); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "deduction category not found"})
+		return d, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return d, false
+	}
+	return d, true
+}
+
+// writeError reports a failed write of d, with a conflict when its name is
+// taken by another category.
+func writeError(c *gin.Context, d Category, err error) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("deduction category %q exists already", d.Name)})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+func (h *handler) Create(c *gin.Context) {
+	d, ok := bindCategory(c)
+	if !ok {
+		return
+	}
+
+	row := h.DB.QueryRow("INSERT INTO deduction_categories(name, description, caps) VALUES ($1, $2, $3) RETURNING id", d.Name, d.Description, d.Caps)
+	if err := row.Scan(&d.ID); err != nil {
+		writeError(c, d, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, d)
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT id, name, description, caps FROM deduction_categories ORDER BY name")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var d Category
+		if err := scanCategory(rows, &d); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		categories = append(categories, d)
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	if d, ok := h.find(c); ok {
+		c.JSON(http.StatusOK, d)
+	}
+}
+
+func (h *handler) Update(c *gin.Context) {
+	existing, ok := h.find(c)
+	if !ok {
+		return
+	}
+	d, ok := bindCategory(c)
+	if !ok {
+		return
+	}
+	d.ID = existing.ID
+
+	if _, err := h.DB.Exec("UPDATE deduction_categories SET name=$2, description=$3, caps=$4 WHERE id=$1", d.ID, d.Name, d.Description, d.Caps); err != nil {
+		writeError(c, d, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, d)
+}
+
+// Delete removes a category; its expenses are untagged.
+func (h *handler) Delete(c *gin.Context) {
+	d, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM deduction_categories WHERE id = $1", d.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
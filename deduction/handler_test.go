@@ -0,0 +1,162 @@
+//go:build unit
+
+package deduction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var categoryColumns = []string{"id", "name", "description", "caps"}
+
+func TestCreateCategory(t *testing.T) {
+	t.Run("Create Category With Two Caps For A Year Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/deductions", strings.NewReader(`{"name": "Donation", "caps": [{"year": 2024, "amount": 1}, {"year": 2024, "amount": 2}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/deductions", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"error":"more than one cap for 2024"}`, strings.TrimSpace(rec.Body.String()))
+	})
+
+	t.Run("Create Category With Taken Name Should Return Conflict", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/deductions", strings.NewReader(`{"name": "SSF"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("INSERT INTO deduction_categories").
+			WillReturnError(&pq.Error{Code: "23505"})
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/deductions", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, `{"error":"deduction category \"SSF\" exists already"}`, strings.TrimSpace(rec.Body.String()))
+	})
+
+	t.Run("Create Category Should Return Created", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/deductions", strings.NewReader(`{"name": "SSF", "caps": [{"year": 2024, "amount": 200000}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("INSERT INTO deduction_categories").
+			WithArgs("SSF", "", []byte(`[{"year":2024,"amount":200000}]`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/deductions", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `{"id":1,"name":"SSF","description":"","caps":[{"year":2024,"amount":200000}]}`, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestGetCategory(t *testing.T) {
+	t.Run("Get Missing Category Should Return Not Found", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/deductions/9", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM deduction_categories WHERE id").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows(categoryColumns))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/deductions/:id", h.Get)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Get Category Should Return Caps", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/deductions/1", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM deduction_categories WHERE id").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(categoryColumns).AddRow(1, "Life insurance", "premiums of 10+ year policies", []byte(`[{"year":2024,"amount":100000}]`)))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/deductions/:id", h.Get)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"id":1,"name":"Life insurance","description":"premiums of 10+ year policies","caps":[{"year":2024,"amount":100000}]}`, strings.TrimSpace(rec.Body.String()))
+	})
+}
@@ -0,0 +1,140 @@
+package expense
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/approval"
+	"github.com/jsritawan/assessment/user"
+)
+
+func (h *handler) Submit(c *gin.Context) {
+	h.transition(c, approval.Submit)
+}
+
+func (h *handler) Approve(c *gin.Context) {
+	h.transition(c, approval.Approve)
+}
+
+func (h *handler) Reject(c *gin.Context) {
+	h.transition(c, approval.Reject)
+}
+
+func (h *handler) Reimburse(c *gin.Context) {
+	h.transition(c, approval.Reimburse)
+}
+
+// transition takes a workflow action on the expense of the request for the
+// current user, with the comment of the optional JSON body, and records it.
+func (h *handler) transition(c *gin.Context, action string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	actor := user.FromContext(c)
+	role, err := user.Role(tx, actor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r := approval.Request{Action: action, Actor: actor, Role: role, Comment: body.Comment}
+	row := tx.QueryRow("SELECT status FROM expenses WHERE id = $1 AND kind = $2 FOR UPDATE", id, h.Kind)
+	if err := row.Scan(&r.Status); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expense not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Expenses in an expense report move with the report.
+	var reportID int
+	err = tx.QueryRow("SELECT report_id FROM expense_report_items WHERE expense_id = $1", id).Scan(&reportID)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("expense is in expense report %d, %s the report instead", reportID, action)})
+		return
+	} else if err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	row = tx.QueryRow("SELECT actor FROM expense_transitions WHERE expense_id = $1 AND to_status = $2 ORDER BY id DESC LIMIT 1", id, approval.StatusSubmitted)
+	if err := row.Scan(&r.Submitter); err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next, err := approval.Default.Next(r)
+	if err != nil {
+		c.JSON(approval.StatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	t := approval.Transition{From: r.Status, To: next, Actor: r.Actor, Role: r.Role, Comment: r.Comment}
+	if _, err := tx.Exec("UPDATE expenses SET status = $2 WHERE id = $1", id, next); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	row = tx.QueryRow(`
+		INSERT INTO expense_transitions(expense_id, from_status, to_status, actor, role, comment)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+		`,
+		id, t.From, t.To, t.Actor, t.Role, t.Comment)
+	if err := row.Scan(&t.ID, &t.CreatedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// Transitions lists the workflow history of an expense, oldest first.
+func (h *handler) Transitions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	rows, err := h.DB.Query("SELECT id, from_status, to_status, actor, role, comment, created_at FROM expense_transitions WHERE expense_id = $1 ORDER BY id", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	transitions := []approval.Transition{}
+	for rows.Next() {
+		var t approval.Transition
+		if err := rows.Scan(&t.ID, &t.From, &t.To, &t.Actor, &t.Role, &t.Comment, &t.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		transitions = append(transitions, t)
+	}
+
+	c.JSON(http.StatusOK, transitions)
+}
@@ -0,0 +1,213 @@
+//go:build unit
+
+package expense
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransition(t *testing.T) {
+	t.Run("Approve Should Record Transition", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/approve", strings.NewReader(`{"comment": "ok"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(user.Header, "bob")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT role FROM user_roles").
+			WithArgs("bob").
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(user.RoleManager))
+		mock.ExpectQuery("SELECT status FROM expenses").
+			WithArgs(1, KindExpense).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("submitted"))
+		mock.ExpectQuery("SELECT report_id FROM expense_report_items").
+			WithArgs(1).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT actor FROM expense_transitions").
+			WithArgs(1, "submitted").
+			WillReturnRows(sqlmock.NewRows([]string{"actor"}).AddRow("ann"))
+		mock.ExpectExec("UPDATE expenses SET status").
+			WithArgs(1, "approved").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO expense_transitions").
+			WithArgs(1, "submitted", "approved", "bob", user.RoleManager, "ok").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, spentAt))
+		mock.ExpectCommit()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.POST("/expenses/:id/approve", h.Approve)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"id":2,"from":"submitted","to":"approved","actor":"bob","role":"manager","comment":"ok","created_at":"2024-01-05T12:00:00Z"}`, strings.TrimSpace(rec.Body.String()))
+	})
+
+	t.Run("Reimburse As Employee Should Return Forbidden", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/reimburse", nil)
+		req.Header.Set(user.Header, "ann")
+		req.Header.Set("X-Role", user.RoleFinance)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT role FROM user_roles").
+			WithArgs("ann").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT status FROM expenses").
+			WithArgs(1, KindExpense).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("approved"))
+		mock.ExpectQuery("SELECT report_id FROM expense_report_items").
+			WithArgs(1).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT actor FROM expense_transitions").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.POST("/expenses/:id/reimburse", h.Reimburse)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Approve Without User Should Return Unauthorized", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/approve", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM expenses").
+			WithArgs(1, KindExpense).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("submitted"))
+		mock.ExpectQuery("SELECT report_id FROM expense_report_items").
+			WithArgs(1).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT actor FROM expense_transitions").
+			WillReturnRows(sqlmock.NewRows([]string{"actor"}).AddRow("ann"))
+		mock.ExpectRollback()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.POST("/expenses/:id/approve", h.Approve)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Submit Approved Expense Should Return Conflict", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/submit", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM expenses").
+			WithArgs(1, KindExpense).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("approved"))
+		mock.ExpectQuery("SELECT report_id FROM expense_report_items").
+			WithArgs(1).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT actor FROM expense_transitions").
+			WillReturnRows(sqlmock.NewRows([]string{"actor"}).AddRow("ann"))
+		mock.ExpectRollback()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/expenses/:id/submit", h.Submit)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("Submit Expense In Expense Report Should Return Conflict", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expenses/1/submit", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM expenses").
+			WithArgs(1, KindExpense).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("draft"))
+		mock.ExpectQuery("SELECT report_id FROM expense_report_items").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"report_id"}).AddRow(4))
+		mock.ExpectRollback()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/expenses/:id/submit", h.Submit)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, `{"error":"expense is in expense report 4, submit the report instead"}`, strings.TrimSpace(rec.Body.String()))
+	})
+}
@@ -0,0 +1,409 @@
+package expense
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/approval"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/lib/pq"
+)
+
+// DuplicateConfig decides when two expenses look like the same purchase.
+type DuplicateConfig struct {
+	// AmountTolerance is the largest relative difference between amounts,
+	// 0.01 allows 1%.
+	AmountTolerance float64
+	// MinSimilarity is the lowest normalized edit-distance similarity
+	// between titles, from 0 (nothing alike) to 1 (identical).
+	MinSimilarity float64
+	// Days is how far apart the expenses may have been spent.
+	Days int
+}
+
+var DefaultDuplicateConfig = DuplicateConfig{
+	AmountTolerance: 0.01,
+	MinSimilarity:   0.8,
+	Days:            3,
+}
+
+type Created struct {
+	Expense
+	PossibleDuplicates []Expense `json:"possible_duplicates,omitempty"`
+}
+
+type DuplicateCluster struct {
+	Expenses []Expense `json:"expenses"`
+}
+
+type DuplicateMerge struct {
+	Keep   int   `json:"keep"`
+	Remove []int `json:"remove"`
+}
+
+func normalizeTitle(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteRune(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// TitleSimilarity is 1 minus the edit distance between the normalized
+// titles divided by the length of the longer one.
+func TitleSimilarity(a, b string) float64 {
+	ra, rb := []rune(normalizeTitle(a)), []rune(normalizeTitle(b))
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func (cfg DuplicateConfig) amountRange(amount float64) (float64, float64) {
+	delta := math.Abs(amount) * cfg.AmountTolerance
+	return amount - delta, amount + delta
+}
+
+func (cfg DuplicateConfig) window() time.Duration {
+	return time.Duration(cfg.Days) * 24 * time.Hour
+}
+
+func (cfg DuplicateConfig) isDuplicate(a, b Expense) bool {
+	lo, hi := cfg.amountRange(a.Amount)
+	if b.Amount < lo || b.Amount > hi {
+		return false
+	}
+	if a.SpentAt != nil && b.SpentAt != nil {
+		gap := a.SpentAt.Sub(*b.SpentAt)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap > cfg.window() {
+			return false
+		}
+	}
+	return TitleSimilarity(a.Title, b.Title) >= cfg.MinSimilarity
+}
+
+// clusters groups expenses that are duplicates of each other, directly or
+// through a chain of duplicates. expenses must be sorted by spent_at.
+func (cfg DuplicateConfig) clusters(expenses []Expense) [][]Expense {
+	parent := make([]int, len(expenses))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range expenses {
+		for j := i + 1; j < len(expenses); j++ {
+			if expenses[i].SpentAt != nil && expenses[j].SpentAt != nil &&
+				expenses[j].SpentAt.Sub(*expenses[i].SpentAt) > cfg.window() {
+				break
+			}
+			if cfg.isDuplicate(expenses[i], expenses[j]) {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	groups := map[int][]Expense{}
+	var roots []int
+	for i, e := range expenses {
+		root := find(i)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], e)
+	}
+
+	var result [][]Expense
+	for _, root := range roots {
+		if len(groups[root]) > 1 {
+			result = append(result, groups[root])
+		}
+	}
+	return result
+}
+
+// possibleDuplicates looks up existing expenses that e would duplicate.
+func (h *handler) possibleDuplicates(e Expense) ([]Expense, error) {
+	spentAt := time.Now()
+	if e.SpentAt != nil {
+		spentAt = *e.SpentAt
+	}
+	cfg := h.Duplicates
+	lo, hi := cfg.amountRange(e.Amount)
+
+	rows, err := h.DB.Query("SELECT "+Columns+" FROM expenses WHERE amount BETWEEN $1 AND $2 AND spent_at BETWEEN $3 AND $4 AND kind = $5 ORDER BY spent_at, id",
+		lo, hi, spentAt.Add(-cfg.window()), spentAt.Add(cfg.window()), h.Kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	e.SpentAt = &spentAt
+	var duplicates []Expense
+	for rows.Next() {
+		var candidate Expense
+		if err := rows.Scan(candidate.Fields()...); err != nil {
+			return nil, err
+		}
+		if cfg.isDuplicate(e, candidate) {
+			duplicates = append(duplicates, candidate)
+		}
+	}
+	return duplicates, rows.Err()
+}
+
+func duplicateConfig(c *gin.Context, cfg DuplicateConfig) (DuplicateConfig, error) {
+	if v := c.Query("tolerance"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return cfg, fmt.Errorf("invalid tolerance %q", v)
+		}
+		cfg.AmountTolerance = f
+	}
+	if v := c.Query("similarity"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			return cfg, fmt.Errorf("invalid similarity %q", v)
+		}
+		cfg.MinSimilarity = f
+	}
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid days %q", v)
+		}
+		cfg.Days = n
+	}
+	return cfg, nil
+}
+
+func (h *handler) GetDuplicates(c *gin.Context) {
+	cfg, err := duplicateConfig(c, h.Duplicates)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := h.DB.Query("SELECT "+Columns+" FROM expenses WHERE kind = $1 ORDER BY spent_at, id", h.Kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var expenses []Expense
+	for rows.Next() {
+		var e Expense
+		if err := rows.Scan(e.Fields()...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		expenses = append(expenses, e)
+	}
+
+	clusters := []DuplicateCluster{}
+	for _, group := range cfg.clusters(expenses) {
+		clusters = append(clusters, DuplicateCluster{Expenses: group})
+	}
+
+	c.JSON(http.StatusOK, clusters)
+}
+
+// MergeDuplicates keeps one expense of a cluster, moves the tags and
+// attachments of the others onto it and deletes them. Expenses past draft
+// or rejected, in an expense report, split in a group or paid in
+// installments cannot be removed.
+func (h *handler) MergeDuplicates(c *gin.Context) {
+	var m DuplicateMerge
+	if err := c.BindJSON(&m); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(m.Remove) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "remove is required"})
+		return
+	}
+	remove := []int{}
+	listed := map[int]bool{}
+	for _, id := range m.Remove {
+		if id == m.Keep {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot remove the expense being kept"})
+			return
+		}
+		if !listed[id] {
+			listed[id] = true
+			remove = append(remove, id)
+		}
+	}
+	m.Remove = remove
+	if locked, err := h.reconciled(append([]int{m.Keep}, m.Remove...)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if locked {
+		c.JSON(http.StatusConflict, gin.H{"error": errReconciled.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var kept Expense
+	row := tx.QueryRow("SELECT "+Columns+" FROM expenses WHERE id = $1 AND kind = $2 FOR UPDATE", m.Keep, h.Kind)
+	if err := row.Scan(kept.Fields()...); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expense not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := tx.Query(`
+		SELECT e.id, e.tags, e.status,
+			EXISTS (SELECT 1 FROM expense_report_items i WHERE i.expense_id = e.id),
+			EXISTS (SELECT 1 FROM expense_shares s WHERE s.expense_id = e.id),
+			EXISTS (SELECT 1 FROM installments n WHERE n.expense_id = e.id)
+		FROM expenses e WHERE e.id = ANY($1) AND e.kind = $2
+		FOR UPDATE`, pq.Array(m.Remove), h.Kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	found := 0
+	seen := map[string]bool{}
+	for _, t := range kept.Tags {
+		seen[t] = true
+	}
+	for rows.Next() {
+		var id int
+		var tags []string
+		var status string
+		var inReport, shared, installment bool
+		if err := rows.Scan(&id, pq.Array(&tags), &status, &inReport, &shared, &installment); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// only expenses nobody has signed off or claimed yet can go
+		if status != approval.StatusDraft && status != approval.StatusRejected {
+			rows.Close()
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("expense %d is %s, only draft or rejected expenses can be removed", id, status)})
+			return
+		}
+		if inReport {
+			rows.Close()
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("expense %d is in an expense report, remove it from the report first", id)})
+			return
+		}
+		// deleting these would leave the group balances or the plan short
+		if shared {
+			rows.Close()
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("expense %d is split in a group and cannot be removed", id)})
+			return
+		}
+		if installment {
+			rows.Close()
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("expense %d is an installment of a plan and cannot be removed", id)})
+			return
+		}
+		found++
+		for _, t := range tags {
+			if !seen[t] {
+				seen[t] = true
+				kept.Tags = append(kept.Tags, t)
+			}
+		}
+	}
+	rows.Close()
+	if found != len(m.Remove) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expense not found"})
+		return
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE expenses SET tags = $2 WHERE id = $1", []interface{}{kept.ID, pq.Array(kept.Tags)}},
+		{"UPDATE attachments SET expense_id = $1 WHERE expense_id = ANY($2)", []interface{}{kept.ID, pq.Array(m.Remove)}},
+		{"DELETE FROM expenses WHERE id = ANY($1)", []interface{}{pq.Array(m.Remove)}},
+	}
+	for _, s := range statements {
+		if _, err := tx.Exec(s.query, s.args...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := ledger.SyncExpenses(tx, []int{kept.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, kept)
+}
@@ -0,0 +1,246 @@
+//go:build unit
+
+package expense
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTitleSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, TitleSimilarity("7-Eleven", "7 eleven"))
+	assert.InDelta(t, 0.947, TitleSimilarity("strawberry smoothie", "strawbery smoothie"), 0.001)
+	assert.Less(t, TitleSimilarity("strawberry smoothie", "iPhone 14"), 0.5)
+}
+
+func TestDuplicateClusters(t *testing.T) {
+	day := func(d int) *time.Time {
+		t := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	expenses := []Expense{
+		{ID: 1, Title: "strawberry smoothie", Amount: 79, SpentAt: day(1)},
+		{ID: 2, Title: "Strawberry Smoothie!", Amount: 79, SpentAt: day(2)},
+		{ID: 3, Title: "strawberry smoothie", Amount: 120, SpentAt: day(2)},
+		{ID: 4, Title: "strawbery smoothie", Amount: 79.5, SpentAt: day(4)},
+		{ID: 5, Title: "strawberry smoothie", Amount: 79, SpentAt: day(20)},
+	}
+
+	clusters := DefaultDuplicateConfig.clusters(expenses)
+
+	if assert.Len(t, clusters, 1) {
+		var ids []int
+		for _, e := range clusters[0] {
+			ids = append(ids, e.ID)
+		}
+		assert.Equal(t, []int{1, 2, 4}, ids)
+	}
+}
+
+func TestCreateDuplicateExpense(t *testing.T) {
+	// Arrange
+	body := `{"title": "strawberry smoothie", "amount": 79, "note": "", "tags": [], "spent_at": "2024-01-05T12:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/expenses?reject_duplicates=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM rules").
+		WillReturnRows(sqlmock.NewRows(ruleColumns))
+	mock.ExpectQuery("SELECT (.+) FROM merchants").
+		WillReturnRows(sqlmock.NewRows(merchantColumns))
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE amount BETWEEN").
+		WithArgs(78.21, 79.79, spentAt.Add(-72*time.Hour), spentAt.Add(72*time.Hour), KindExpense).
+		WillReturnRows(sqlmock.NewRows(expenseColumns).
+			AddRow(1, "Strawberry smoothie", 79, "", pq.Array([]string{}), spentAt.Add(-time.Hour), "", nil, nil, "draft", nil, nil).
+			AddRow(2, "mango smoothie", 79, "", pq.Array([]string{}), spentAt, "", nil, nil, "draft", nil, nil))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.POST("/expenses", h.Create)
+	expect := `{"error":"possible duplicate expense","possible_duplicates":[{"id":1,"title":"Strawberry smoothie","amount":79,"note":"","tags":[],"spent_at":"2024-01-05T11:00:00Z","status":"draft"}]}`
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+}
+
+var mergeColumns = []string{"id", "tags", "status", "in_report", "shared", "installment"}
+
+func TestMergeDuplicates(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodPost, "/expenses/duplicates/merge", strings.NewReader(`{"keep": 1, "remove": [2, 2]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT EXISTS (.+) reconciliation_lines").
+		WithArgs(pq.Array([]int{1, 2})).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = \\$1 AND kind = \\$2 FOR UPDATE").
+		WithArgs(1, KindExpense).
+		WillReturnRows(sqlmock.NewRows(expenseColumns).
+			AddRow(1, "strawberry smoothie", 79, "", pq.Array([]string{"food"}), spentAt, "", nil, nil, "draft", nil, nil))
+	mock.ExpectQuery("SELECT e.id, e.tags, e.status, (.+) FROM expenses e").
+		WithArgs(pq.Array([]int{2}), KindExpense).
+		WillReturnRows(sqlmock.NewRows(mergeColumns).AddRow(2, pq.Array([]string{"beverage", "food"}), "draft", false, false, false))
+	mock.ExpectExec("UPDATE expenses SET tags").
+		WithArgs(1, pq.Array([]string{"food", "beverage"})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE attachments").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM expenses").
+		WithArgs(pq.Array([]int{2})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSync(mock, 1, "strawberry smoothie", 79, []string{"food", "beverage"}, "Expenses:Food")
+	mock.ExpectCommit()
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.POST("/expenses/duplicates/merge", h.MergeDuplicates)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMergeApprovedDuplicate(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodPost, "/expenses/duplicates/merge", strings.NewReader(`{"keep": 1, "remove": [2]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT EXISTS (.+) reconciliation_lines").
+		WithArgs(pq.Array([]int{1, 2})).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = \\$1 AND kind = \\$2 FOR UPDATE").
+		WithArgs(1, KindExpense).
+		WillReturnRows(sqlmock.NewRows(expenseColumns).
+			AddRow(1, "strawberry smoothie", 79, "", pq.Array([]string{"food"}), spentAt, "", nil, nil, "draft", nil, nil))
+	mock.ExpectQuery("SELECT e.id, e.tags, e.status, (.+) FROM expenses e").
+		WithArgs(pq.Array([]int{2}), KindExpense).
+		WillReturnRows(sqlmock.NewRows(mergeColumns).AddRow(2, pq.Array([]string{"food"}), "approved", false, false, false))
+	mock.ExpectRollback()
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.POST("/expenses/duplicates/merge", h.MergeDuplicates)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, `{"error":"expense 2 is approved, only draft or rejected expenses can be removed"}`, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestMergeSharedDuplicate(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodPost, "/expenses/duplicates/merge", strings.NewReader(`{"keep": 1, "remove": [2]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT EXISTS (.+) reconciliation_lines").
+		WithArgs(pq.Array([]int{1, 2})).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = \\$1 AND kind = \\$2 FOR UPDATE").
+		WithArgs(1, KindExpense).
+		WillReturnRows(sqlmock.NewRows(expenseColumns).
+			AddRow(1, "strawberry smoothie", 79, "", pq.Array([]string{"food"}), spentAt, "", nil, nil, "draft", nil, nil))
+	mock.ExpectQuery("SELECT e.id, e.tags, e.status, (.+) FROM expenses e").
+		WithArgs(pq.Array([]int{2}), KindExpense).
+		WillReturnRows(sqlmock.NewRows(mergeColumns).AddRow(2, pq.Array([]string{"food"}), "draft", false, true, false))
+	mock.ExpectRollback()
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.POST("/expenses/duplicates/merge", h.MergeDuplicates)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, `{"error":"expense 2 is split in a group and cannot be removed"}`, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestMergeDuplicatesKeptError(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodPost, "/expenses/duplicates/merge", strings.NewReader(`{"keep": 1, "remove": [2]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT EXISTS (.+) reconciliation_lines").
+		WithArgs(pq.Array([]int{1, 2})).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = \\$1 AND kind = \\$2 FOR UPDATE").
+		WithArgs(1, KindExpense).
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.POST("/expenses/duplicates/merge", h.MergeDuplicates)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
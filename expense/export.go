@@ -0,0 +1,335 @@
+package expense
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/jsritawan/assessment/tag"
+)
+
+// exportCurrency is written for expenses without a payment account,
+// matching the default currency of package account.
+const exportCurrency = "THB"
+
+// Mapping maps tags to the ledger account their expenses are exported to,
+// such as food to Expenses:Groceries.
+type Mapping map[string]string
+
+// account names the Expenses or Income account of a row after the mapping
+// of its first mapped tag, a tag falling back to its parents, or after the
+// ledger's own naming when no tag is mapped.
+func (m Mapping) account(kind, category string, tags []string) string {
+	root := "Expenses:"
+	if kind == KindIncome {
+		root = "Income:"
+	}
+	for _, t := range tags {
+		for name := t; name != ""; name = tag.Parent(name) {
+			if a, ok := m[name]; ok && strings.HasPrefix(a, root) {
+				return a
+			}
+		}
+	}
+	return ledger.CategoryAccount(kind, category, tags)
+}
+
+// tags returns the tag mapped to an account, for imported transactions
+// that carry no tags of their own.
+func (m Mapping) tags(account string) []string {
+	var found string
+	for t, a := range m {
+		if a == account && (found == "" || t < found) {
+			found = t
+		}
+	}
+	if found == "" {
+		return []string{}
+	}
+	return []string{found}
+}
+
+func loadMapping(db *sql.DB) (Mapping, error) {
+	rows, err := db.Query("SELECT tag, account FROM export_mappings")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	m := Mapping{}
+	for rows.Next() {
+		var t, a string
+		if err := rows.Scan(&t, &a); err != nil {
+			return nil, err
+		}
+		m[t] = a
+	}
+	return m, rows.Err()
+}
+
+// paymentAccount is an account row as the export and import need it.
+type paymentAccount struct {
+	Name     string
+	Currency string
+}
+
+func (h *handler) paymentAccounts() (map[int]paymentAccount, error) {
+	rows, err := h.DB.Query("SELECT id, name, type, currency FROM accounts")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := map[int]paymentAccount{}
+	for rows.Next() {
+		var id int
+		var name, accountType, currency string
+		if err := rows.Scan(&id, &name, &accountType, &currency); err != nil {
+			return nil, err
+		}
+		accounts[id] = paymentAccount{Name: ledger.PaymentAccount(name, accountType), Currency: currency}
+	}
+	return accounts, rows.Err()
+}
+
+func (h *handler) GetMapping(c *gin.Context) {
+	m, err := loadMapping(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, m)
+}
+
+// PutMapping replaces the tag to account mapping used by the export.
+func (h *handler) PutMapping(c *gin.Context) {
+	var body Mapping
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m := Mapping{}
+	for t, a := range body {
+		name := tag.Clean(t)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid tag %q", t)})
+			return
+		}
+		if !ledger.ValidAccount(a) || !(strings.HasPrefix(a, "Expenses:") || strings.HasPrefix(a, "Income:")) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid account %q, use an Expenses: or Income: account such as Expenses:Food", a)})
+			return
+		}
+		m[name] = a
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM export_mappings"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for t, a := range m {
+		if _, err := tx.Exec("INSERT INTO export_mappings(tag, account) VALUES ($1, $2)", t, a); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, m)
+}
+
+func exportFormat(c *gin.Context) (string, bool) {
+	f := c.DefaultQuery("format", ledger.FormatLedger)
+	if f != ledger.FormatLedger && f != ledger.FormatBeancount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid format %q, use %s or %s", f, ledger.FormatLedger, ledger.FormatBeancount)})
+		return "", false
+	}
+	return f, true
+}
+
+// Export writes expenses as a ledger or beancount file. It takes the same
+// filters as the list endpoint, and income is exported alongside so the
+// file holds the whole book.
+func (h *handler) Export(c *gin.Context) {
+	f, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	conds, args, err := listFilter(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if q := c.Query("q"); q != "" {
+		cond, qargs, err := queryFilter(q, len(args))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, queryError(err))
+			return
+		}
+		conds = append(conds, cond)
+		args = append(args, qargs...)
+	}
+
+	mapping, err := loadMapping(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	accounts, err := h.paymentAccounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := h.DB.Query("SELECT "+Columns+", kind FROM expenses"+where(conds)+" ORDER BY spent_at, id", args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var txs []ledger.Transaction
+	for rows.Next() {
+		var e Expense
+		var kind string
+		if err := rows.Scan(append(e.Fields(), &kind)...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		t := ledger.Transaction{
+			Date:     *e.SpentAt,
+			Payee:    e.Title,
+			Note:     e.Note,
+			Tags:     e.Tags,
+			Category: e.Category,
+			Kind:     kind,
+			Account:  mapping.account(kind, e.Category, e.Tags),
+			Payment:  ledger.Unassigned,
+			Amount:   e.Amount,
+			Currency: exportCurrency,
+		}
+		if e.AccountID != nil {
+			a := accounts[*e.AccountID]
+			t.Payment, t.Currency = a.Name, a.Currency
+		}
+		txs = append(txs, t)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ledger.Write(&buf, f, txs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="expenses.%s"`, f))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", buf.Bytes())
+}
+
+// Import reads a ledger or beancount file, such as one written by Export,
+// and creates an expense or income row for each transaction. Rows are
+// prepared like new expenses, so tag synonyms, rules and merchants apply.
+// Payment accounts are matched by name and must be in the currency of the
+// amounts, THB for Assets:Unassigned, as nothing is converted.
+func (h *handler) Import(c *gin.Context) {
+	f, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	txs, err := ledger.Parse(c.Request.Body, f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mapping, err := loadMapping(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	accounts, err := h.paymentAccounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byName := map[string]int{}
+	for id, a := range accounts {
+		byName[a.Name] = id
+	}
+
+	expenses := make([]Expense, len(txs))
+	kinds := make([]string, len(txs))
+	for i, t := range txs {
+		e := Expense{Title: t.Payee, Amount: t.Amount, Note: t.Note, Tags: t.Tags, Category: t.Category}
+		spentAt := t.Date
+		e.SpentAt = &spentAt
+		currency := exportCurrency
+		if t.Payment != ledger.Unassigned {
+			id, ok := byName[t.Payment]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("line %d: unknown account %q", t.Line, t.Payment)})
+				return
+			}
+			e.AccountID = &id
+			currency = accounts[id].Currency
+		}
+		if t.Currency != "" && t.Currency != currency {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("line %d: amount is in %s but %s is in %s", t.Line, t.Currency, t.Payment, currency)})
+			return
+		}
+		if len(e.Tags) == 0 {
+			e.Tags = mapping.tags(t.Account)
+		}
+		if err := h.prepare(&e); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		expenses[i], kinds[i] = e, t.Kind
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	ids := make([]int, len(expenses))
+	for i := range expenses {
+		e := &expenses[i]
+		if err := Insert(tx, kinds[i], e); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ids[i] = e.ID
+	}
+	if err := ledger.SyncExpenses(tx, ids); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, expenses)
+}
@@ -0,0 +1,226 @@
+//go:build unit
+
+package expense
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var paymentAccountColumns = []string{"id", "name", "type", "currency"}
+
+func TestExportExpenses(t *testing.T) {
+	t.Run("Export With Unknown Format Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/expenses/export?format=csv", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/expenses/export", h.Export)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Export Ledger Should Map Tags To Accounts", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/expenses/export?format=ledger&tag=food", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT tag, account FROM export_mappings").
+			WillReturnRows(sqlmock.NewRows([]string{"tag", "account"}).AddRow("food", "Expenses:Groceries"))
+		mock.ExpectQuery("SELECT id, name, type, currency FROM accounts").
+			WillReturnRows(sqlmock.NewRows(paymentAccountColumns).AddRow(1, "visa", "credit_card", "THB"))
+		mock.ExpectQuery("SELECT (.+), kind FROM expenses WHERE EXISTS (.+) ORDER BY spent_at, id").
+			WithArgs("food", "food/%").
+			WillReturnRows(sqlmock.NewRows(append(expenseColumns, "kind")).
+				AddRow(1, "strawberry smoothie", 79.0, "night market", pq.Array([]string{"food/fruit", "beverage"}), spentAt, "", nil, 1, "draft", nil, nil, KindExpense).
+				AddRow(2, "fruit sale", 40.0, "", pq.Array([]string{"food"}), spentAt, "", nil, nil, "draft", nil, nil, KindIncome))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/expenses/export", h.Export)
+		expect := `2024-01-05 strawberry smoothie
+    ; night market
+    ; tags: food/fruit, beverage
+    ; time: 12:00:00
+    Expenses:Groceries  79.00 THB
+    Liabilities:Visa  -79.00 THB
+
+2024-01-05 fruit sale
+    ; tags: food
+    ; time: 12:00:00
+    Assets:Unassigned  40.00 THB
+    Income:Food  -40.00 THB
+`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `attachment; filename="expenses.ledger"`, rec.Header().Get("Content-Disposition"))
+		assert.Equal(t, expect, rec.Body.String())
+	})
+}
+
+func TestImportExpenses(t *testing.T) {
+	t.Run("Import Unknown Account Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		body := `2024-01-05 * "lunch" ""
+  Expenses:Food  120.00 THB
+  Assets:Wallet
+`
+		req := httptest.NewRequest(http.MethodPost, "/expenses/import?format=beancount", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT tag, account FROM export_mappings").
+			WillReturnRows(sqlmock.NewRows([]string{"tag", "account"}))
+		mock.ExpectQuery("SELECT id, name, type, currency FROM accounts").
+			WillReturnRows(sqlmock.NewRows(paymentAccountColumns).AddRow(1, "visa", "credit_card", "THB"))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/expenses/import", h.Import)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"error":"line 1: unknown account \"Assets:Wallet\""}`, strings.TrimSpace(rec.Body.String()))
+	})
+
+	t.Run("Import In Another Currency Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		body := `2024-01-05 coffee
+    Expenses:Food  4.50 USD
+    Liabilities:Visa  -4.50 USD
+`
+		req := httptest.NewRequest(http.MethodPost, "/expenses/import?format=ledger", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT tag, account FROM export_mappings").
+			WillReturnRows(sqlmock.NewRows([]string{"tag", "account"}))
+		mock.ExpectQuery("SELECT id, name, type, currency FROM accounts").
+			WillReturnRows(sqlmock.NewRows(paymentAccountColumns).AddRow(1, "visa", "credit_card", "THB"))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/expenses/import", h.Import)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"error":"line 1: amount is in USD but Liabilities:Visa is in THB"}`, strings.TrimSpace(rec.Body.String()))
+	})
+
+	t.Run("Import Ledger Should Return Created", func(t *testing.T) {
+		// Arrange
+		body := `2024-01-05 strawberry smoothie
+    ; night market
+    ; time: 12:00:00
+    Expenses:Groceries  79.00 THB
+    Liabilities:Visa  -79.00 THB
+`
+		req := httptest.NewRequest(http.MethodPost, "/expenses/import?format=ledger", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT tag, account FROM export_mappings").
+			WillReturnRows(sqlmock.NewRows([]string{"tag", "account"}).AddRow("food", "Expenses:Groceries"))
+		mock.ExpectQuery("SELECT id, name, type, currency FROM accounts").
+			WillReturnRows(sqlmock.NewRows(paymentAccountColumns).AddRow(1, "visa", "credit_card", "THB"))
+		mock.ExpectQuery("SELECT alias, tag FROM tag_synonyms").
+			WithArgs(pq.Array([]string{"food"})).
+			WillReturnRows(sqlmock.NewRows([]string{"alias", "tag"}))
+		mock.ExpectQuery("SELECT (.+) FROM rules").
+			WillReturnRows(sqlmock.NewRows(ruleColumns))
+		mock.ExpectQuery("SELECT (.+) FROM merchants").
+			WillReturnRows(sqlmock.NewRows(merchantColumns))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO expenses").
+			WithArgs("strawberry smoothie", 79.0, "night market", pq.Array(&[]string{"food"}), &spentAt, "", nil, 1, KindExpense, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at"}).AddRow(5, spentAt))
+		mock.ExpectExec("DELETE FROM journal_entries").
+			WithArgs(pq.Array([]int{5})).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT (.+) FROM expenses e LEFT JOIN accounts a").
+			WithArgs(pq.Array([]int{5})).
+			WillReturnRows(sqlmock.NewRows(ledgerColumns).AddRow(5, "strawberry smoothie", 79.0, KindExpense, pq.Array([]string{"food"}), "", spentAt, 1, "visa", "credit_card"))
+		mock.ExpectQuery("INSERT INTO journal_entries").
+			WithArgs(KindExpense, 5, nil, spentAt, "strawberry smoothie").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(10, "Expenses:Food", nil, 79.0).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(10, "Liabilities:Visa", 1, -79.0).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/expenses/import", h.Import)
+		expect := `[{"id":5,"title":"strawberry smoothie","amount":79,"note":"night market","tags":["food"],"spent_at":"2024-01-05T12:00:00Z","account_id":1}]`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
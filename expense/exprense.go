@@ -1,9 +1,42 @@
 package expense
 
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Rows of the expenses table are either money spent or money received.
+const (
+	KindExpense = "expense"
+	KindIncome  = "income"
+)
+
+// Columns lists the expenses columns in the order Fields scans them.
+const Columns = "id, title, amount, note, tags, spent_at, category, merchant_id, account_id, status, tax, deduction_id"
+
 type Expense struct {
-	ID     int      `json:"id"`
-	Title  string   `json:"title"`
-	Amount float64  `json:"amount"`
-	Note   string   `json:"note"`
-	Tags   []string `json:"tags"`
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	Amount      float64    `json:"amount"`
+	Note        string     `json:"note"`
+	Tags        []string   `json:"tags"`
+	SpentAt     *time.Time `json:"spent_at,omitempty"`
+	Category    string     `json:"category,omitempty"`
+	MerchantID  *int       `json:"merchant_id,omitempty"`
+	AccountID   *int       `json:"account_id,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	Tax         *Tax       `json:"tax,omitempty"`
+	DeductionID *int       `json:"deduction_id,omitempty"`
+}
+
+// Fields returns the scan destinations for a row selected with Columns.
+func (e *Expense) Fields() []interface{} {
+	return []interface{}{&e.ID, &e.Title, &e.Amount, &e.Note, pq.Array(&e.Tags), &e.SpentAt, &e.Category, &e.MerchantID, &e.AccountID, &e.Status, &e.Tax, &e.DeductionID}
+}
+
+type SearchResult struct {
+	Expense
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
 }
@@ -0,0 +1,69 @@
+package expense
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/query"
+	"github.com/jsritawan/assessment/tag"
+)
+
+// listFilter turns the list query parameters (tag, min_amount, max_amount)
+// into SQL conditions. A tag also matches its descendants. Placeholders are
+// numbered after the first offset arguments the caller already uses.
+func listFilter(c *gin.Context, offset int) ([]string, []interface{}, error) {
+	var conds []string
+	var args []interface{}
+	next := func(arg interface{}) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", offset+len(args))
+	}
+
+	for _, t := range c.QueryArray("tag") {
+		name := tag.Clean(t)
+		conds = append(conds, fmt.Sprintf("EXISTS (SELECT 1 FROM unnest(tags) t WHERE t = %s OR t LIKE %s)",
+			next(name), next(tag.DescendantPattern(name))))
+	}
+	if v := c.Query("min_amount"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid min_amount %q", v)
+		}
+		conds = append(conds, "amount >= "+next(amount))
+	}
+	if v := c.Query("max_amount"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid max_amount %q", v)
+		}
+		conds = append(conds, "amount <= "+next(amount))
+	}
+	return conds, args, nil
+}
+
+// queryFilter compiles the filter language accepted by the q parameter of
+// the list endpoint, see package query.
+func queryFilter(q string, offset int) (string, []interface{}, error) {
+	node, err := query.Parse(q)
+	if err != nil {
+		return "", nil, err
+	}
+	cond, args := query.Compile(node, offset)
+	return cond, args, nil
+}
+
+func queryError(err error) gin.H {
+	if qe, ok := err.(*query.Error); ok {
+		return gin.H{"error": qe.Error(), "position": qe.Pos}
+	}
+	return gin.H{"error": err.Error()}
+}
+
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
@@ -2,46 +2,153 @@ package expense
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/approval"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/jsritawan/assessment/merchant"
+	"github.com/jsritawan/assessment/rule"
+	"github.com/jsritawan/assessment/tag"
 	"github.com/lib/pq"
 )
 
 type handler struct {
-	DB *sql.DB
+	DB         *sql.DB
+	Duplicates DuplicateConfig
+	// Kind is the kind of rows the handler reads and writes, so the same
+	// endpoints serve both /expenses and /income.
+	Kind string
 }
 
 func NewHandler(db *sql.DB) *handler {
 	return &handler{
-		DB: db,
+		DB:         db,
+		Duplicates: DefaultDuplicateConfig,
+		Kind:       KindExpense,
 	}
 }
 
+func NewIncomeHandler(db *sql.DB) *handler {
+	return &handler{
+		DB:         db,
+		Duplicates: DefaultDuplicateConfig,
+		Kind:       KindIncome,
+	}
+}
+
+// Prepare normalizes the tags of an incoming expense, runs the rules over
+// it and matches its merchant before it is written.
+func Prepare(db *sql.DB, e *Expense) error {
+	tags, err := tag.Normalize(db, e.Tags)
+	if err != nil {
+		return err
+	}
+	e.Tags = tags
+
+	engine, err := rule.Load(db)
+	if err != nil {
+		return err
+	}
+	s := rule.Subject{Title: e.Title, Amount: e.Amount, Note: e.Note, Tags: e.Tags, Category: e.Category}
+	engine.Apply(&s)
+	e.Note, e.Tags, e.Category = s.Note, s.Tags, s.Category
+
+	if e.MerchantID == nil {
+		e.MerchantID, err = merchant.Find(db, e.Title)
+	}
+	return err
+}
+
+// Insert writes e in tx as a new expense of the given kind, with its tax
+// applied, and sets its ID and SpentAt, now when it has none. Callers sync
+// the ledger once their expenses are written.
+func Insert(tx *sql.Tx, kind string, e *Expense) error {
+	if err := ApplyTax(e); err != nil {
+		return err
+	}
+	row := tx.QueryRow(`
+		INSERT INTO expenses(title, amount, note, tags, spent_at, category, merchant_id, account_id, kind, tax, deduction_id)
+		VALUES ($1, $2, $3, $4, COALESCE($5, now()), $6, $7, $8, $9, $10, $11)
+		RETURNING id, spent_at
+		`,
+		e.Title, e.Amount, e.Note, pq.Array(&e.Tags), e.SpentAt, e.Category, e.MerchantID, e.AccountID, kind, e.Tax, e.DeductionID)
+	return row.Scan(&e.ID, &e.SpentAt)
+}
+
+func (h *handler) prepare(e *Expense) error {
+	return Prepare(h.DB, e)
+}
+
+// errReconciled is returned when editing an expense matched to a line of a
+// closed reconciliation session.
+var errReconciled = errors.New("expense is reconciled, reopen its reconciliation session to edit it")
+
+// reconciled reports whether any of the expenses is locked by a closed
+// reconciliation session.
+func (h *handler) reconciled(ids []int) (bool, error) {
+	var locked bool
+	err := h.DB.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM reconciliation_lines l
+			JOIN reconciliation_sessions s ON s.id = l.session_id
+			WHERE l.expense_id = ANY($1) AND l.status = 'confirmed' AND s.status = 'closed'
+		)`, pq.Array(ids)).Scan(&locked)
+	return locked, err
+}
+
 func (h *handler) Create(c *gin.Context) {
 	var expense Expense
 	if err := c.BindJSON(&expense); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	expense.Status = approval.StatusDraft
+	if err := ApplyTax(&expense); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	row := h.DB.QueryRow(`
-		INSERT INTO expenses(title, amount, note, tags)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
-		`,
-		expense.Title,
-		expense.Amount,
-		expense.Note,
-		pq.Array(&expense.Tags))
+	if err := h.prepare(&expense); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	duplicates, err := h.possibleDuplicates(expense)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if reject, _ := strconv.ParseBool(c.Query("reject_duplicates")); reject && len(duplicates) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "possible duplicate expense", "possible_duplicates": duplicates})
+		return
+	}
 
-	if err := row.Scan(&expense.ID); err != nil {
+	tx, err := h.DB.Begin()
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	defer tx.Rollback()
 
-	c.JSON(http.StatusCreated, expense)
+	if err := Insert(tx, h.Kind, &expense); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ledger.SyncExpenses(tx, []int{expense.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Created{Expense: expense, PossibleDuplicates: duplicates})
 }
 
 func (h *handler) Get(c *gin.Context) {
@@ -54,9 +161,9 @@ func (h *handler) Get(c *gin.Context) {
 	}
 
 	var expense Expense
-	row := h.DB.QueryRow(`SELECT id, title, amount, note, tags FROM expenses WHERE id = $1`, id)
+	row := h.DB.QueryRow(`SELECT `+Columns+` FROM expenses WHERE id = $1 AND kind = $2`, id, h.Kind)
 
-	if err := row.Scan(&expense.ID, &expense.Title, &expense.Amount, &expense.Note, pq.Array(&expense.Tags)); err != nil {
+	if err := row.Scan(expense.Fields()...); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -67,13 +174,31 @@ func (h *handler) Get(c *gin.Context) {
 func (h *handler) GetAll(c *gin.Context) {
 	var expenses []Expense
 
-	stmt, err := h.DB.Prepare("SELECT id, title, amount, note, tags FROM expenses")
+	conds, args, err := listFilter(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if q := c.Query("q"); q != "" {
+		cond, qargs, err := queryFilter(q, len(args))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, queryError(err))
+			return
+		}
+		conds = append(conds, cond)
+		args = append(args, qargs...)
+	}
+	args = append(args, h.Kind)
+	conds = append(conds, fmt.Sprintf("kind = $%d", len(args)))
+
+	stmt, err := h.DB.Prepare("SELECT " + Columns + " FROM expenses" + where(conds))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	rows, err := stmt.Query()
+	rows, err := stmt.Query(args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -81,7 +206,7 @@ func (h *handler) GetAll(c *gin.Context) {
 
 	for rows.Next() {
 		var expense Expense
-		if err := rows.Scan(&expense.ID, &expense.Title, &expense.Amount, &expense.Note, pq.Array(&expense.Tags)); err != nil {
+		if err := rows.Scan(expense.Fields()...); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -94,7 +219,7 @@ func (h *handler) GetAll(c *gin.Context) {
 func (h *handler) Update(c *gin.Context) {
 	id := c.Param("id")
 
-	_, err := strconv.Atoi(id)
+	expenseID, err := strconv.Atoi(id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
@@ -105,20 +230,70 @@ func (h *handler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := ApplyTax(&expense); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	stmt, err := h.DB.Prepare("UPDATE expenses SET title=$2, amount=$3, note=$4, tags=$5 WHERE id=$1")
+	if locked, err := h.reconciled([]int{expenseID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if locked {
+		c.JSON(http.StatusConflict, gin.H{"error": errReconciled.Error()})
+		return
+	}
+
+	if err := h.prepare(&expense); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	defer tx.Rollback()
 
-	if _, err := stmt.Exec(id, expense.Title, expense.Amount, expense.Note, pq.Array(&expense.Tags)); err != nil {
+	// What a manager approved is what finance pays back, so the title and
+	// amount stay as they were once the expense is signed off. The row is
+	// locked so an approval cannot slip in between the check and the update.
+	var title, status string
+	var amount float64
+	row := tx.QueryRow("SELECT title, amount, status FROM expenses WHERE id = $1 AND kind = $2 FOR UPDATE", expenseID, h.Kind)
+	if err := row.Scan(&title, &amount, &status); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expense not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if approval.Locked(status) && (expense.Title != title || math.Round(expense.Amount*100) != math.Round(amount*100)) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("title and amount cannot change once the expense is %s", status)})
+		return
+	}
+
+	stmt, err := tx.Prepare("UPDATE expenses SET title=$2, amount=$3, note=$4, tags=$5, spent_at=COALESCE($6, spent_at), category=$7, merchant_id=$8, account_id=$9, tax=$11, deduction_id=$12 WHERE id=$1 AND kind=$10")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := stmt.Exec(id, expense.Title, expense.Amount, expense.Note, pq.Array(&expense.Tags), expense.SpentAt, expense.Category, expense.MerchantID, expense.AccountID, h.Kind, expense.Tax, expense.DeductionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ledger.SyncExpenses(tx, []int{expenseID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	row := h.DB.QueryRow("SELECT id, title, amount, note, tags FROM expenses WHERE id=$1 ", id)
-	if err := row.Scan(&expense.ID, &expense.Title, &expense.Amount, &expense.Note, pq.Array(&expense.Tags)); err != nil {
+	row = h.DB.QueryRow("SELECT "+Columns+" FROM expenses WHERE id=$1 AND kind=$2", id, h.Kind)
+	if err := row.Scan(expense.Fields()...); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -74,7 +74,8 @@ func TestITCreateExpense(t *testing.T) {
 		"title": "strawberry smoothie",
 		"amount": 79,
 		"note": "night market promotion discount 10 bath", 
-		"tags": ["food", "beverage"]
+		"tags": ["food", "beverage"],
+		"spent_at": "2024-01-05T12:00:00Z"
 	}`
 	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%d/expenses", serverPort), strings.NewReader(reqBody))
 	assert.NoError(t, err)
@@ -90,12 +91,14 @@ func TestITCreateExpense(t *testing.T) {
 	resp.Body.Close()
 
 	// Assertion
+	spentAt := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
 	expect := Expense{
-		ID:     1,
-		Title:  "strawberry smoothie",
-		Amount: 79,
-		Note:   "night market promotion discount 10 bath",
-		Tags:   []string{"food", "beverage"},
+		ID:      1,
+		Title:   "strawberry smoothie",
+		Amount:  79,
+		Note:    "night market promotion discount 10 bath",
+		Tags:    []string{"food", "beverage"},
+		SpentAt: &spentAt,
 	}
 	byteExpect, err := json.Marshal(expect)
 
@@ -206,11 +209,12 @@ func TestITUpdateExpenseById(t *testing.T) {
 
 	// Assertion
 	expect := Expense{
-		ID:     createdExpense.ID,
-		Title:  "apple smoothie",
-		Amount: 89,
-		Note:   "no discount",
-		Tags:   []string{"beverage"},
+		ID:      createdExpense.ID,
+		Title:   "apple smoothie",
+		Amount:  89,
+		Note:    "no discount",
+		Tags:    []string{"beverage"},
+		SpentAt: createdExpense.SpentAt,
 	}
 	byteExpect, err := json.Marshal(expect)
 
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,36 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+var spentAt = time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+
+var expenseColumns = []string{"id", "title", "amount", "note", "tags", "spent_at", "category", "merchant_id", "account_id", "status", "tax", "deduction_id"}
+
+var merchantColumns = []string{"id", "name", "aliases"}
+
+var ledgerColumns = []string{"id", "title", "amount", "kind", "tags", "category", "spent_at", "account_id", "name", "type"}
+
+// expectLedgerSync expects the journal entry of an expense without a
+// payment account to be rewritten.
+func expectLedgerSync(mock sqlmock.Sqlmock, id int, title string, amount float64, tags []string, account string) {
+	mock.ExpectExec("DELETE FROM journal_entries WHERE expense_id").
+		WithArgs(pq.Array([]int{id})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT (.+) FROM expenses e LEFT JOIN accounts a").
+		WithArgs(pq.Array([]int{id})).
+		WillReturnRows(sqlmock.NewRows(ledgerColumns).AddRow(id, title, amount, KindExpense, pq.Array(tags), "", spentAt, nil, nil, nil))
+	mock.ExpectQuery("INSERT INTO journal_entries").
+		WithArgs(KindExpense, id, nil, spentAt, title).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectExec("INSERT INTO postings").
+		WithArgs(10, account, nil, amount).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO postings").
+		WithArgs(10, "Assets:Unassigned", nil, -amount).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+}
+
+var ruleColumns = []string{"id", "name", "position", "enabled", "conditions", "actions"}
+
 func TestCreateExpense(t *testing.T) {
 	t.Run("Create Expense With Invalid Request Shoud Return Bad Request", func(t *testing.T) {
 		// Arrange
@@ -64,23 +95,60 @@ func TestCreateExpense(t *testing.T) {
 		}
 		defer db.Close()
 
+		mock.ExpectQuery("SELECT alias, tag FROM tag_synonyms WHERE alias = ANY($1)").
+			WithArgs(pq.Array([]string{"food", "beverage"})).
+			WillReturnRows(sqlmock.NewRows([]string{"alias", "tag"}))
+		mock.ExpectQuery("SELECT id, name, position, enabled, conditions, actions FROM rules WHERE enabled ORDER BY position, id").
+			WillReturnRows(sqlmock.NewRows(ruleColumns))
+		mock.ExpectQuery("SELECT id, name, aliases FROM merchants ORDER BY id").
+			WillReturnRows(sqlmock.NewRows(merchantColumns))
+		mock.ExpectQuery("SELECT id, title, amount, note, tags, spent_at, category, merchant_id, account_id, status, tax, deduction_id FROM expenses WHERE amount BETWEEN $1 AND $2 AND spent_at BETWEEN $3 AND $4 AND kind = $5 ORDER BY spent_at, id").
+			WithArgs(78.21, 79.79, sqlmock.AnyArg(), sqlmock.AnyArg(), KindExpense).
+			WillReturnRows(sqlmock.NewRows(expenseColumns))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`
+		INSERT INTO expenses(title, amount, note, tags, spent_at, category, merchant_id, account_id, kind, tax, deduction_id)
+		VALUES ($1, $2, $3, $4, COALESCE($5, now()), $6, $7, $8, $9, $10, $11)
+		RETURNING id, spent_at`).
+			WithArgs(body.Title, body.Amount, body.Note, pq.Array(&body.Tags), nil, "", nil, nil, KindExpense, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at"}).AddRow(1, spentAt))
+		mock.ExpectExec("DELETE FROM journal_entries WHERE expense_id = ANY($1)").
+			WithArgs(pq.Array([]int{1})).
+			WillReturnResult(sqlmock.NewResult(0, 0))
 		mock.ExpectQuery(`
-		INSERT INTO expenses(title, amount, note, tags)
-		VALUES ($1, $2, $3, $4)
+		SELECT e.id, e.title, e.amount, e.kind, e.tags, e.category, e.spent_at, e.account_id, a.name, a.type
+		FROM expenses e LEFT JOIN accounts a ON a.id = e.account_id
+		WHERE e.id = ANY($1)
+		ORDER BY e.id`).
+			WithArgs(pq.Array([]int{1})).
+			WillReturnRows(sqlmock.NewRows(ledgerColumns).
+				AddRow(1, body.Title, body.Amount, KindExpense, pq.Array(body.Tags), "", spentAt, nil, nil, nil))
+		mock.ExpectQuery(`
+		INSERT INTO journal_entries(kind, expense_id, account_id, posted_at, description)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id`).
-			WithArgs(body.Title, body.Amount, body.Note, pq.Array(&body.Tags)).
-			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			WithArgs(KindExpense, 1, nil, spentAt, body.Title).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectExec("INSERT INTO postings(entry_id, account, account_id, amount) VALUES ($1, $2, $3, $4)").
+			WithArgs(10, "Expenses:Food", nil, body.Amount).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO postings(entry_id, account, account_id, amount) VALUES ($1, $2, $3, $4)").
+			WithArgs(10, "Assets:Unassigned", nil, -body.Amount).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
 
 		gin.SetMode(gin.TestMode)
 		h := NewHandler(db)
 		r := gin.Default()
 		r.POST("/expenses", h.Create)
 		b, _ := json.Marshal(Expense{
-			ID:     1,
-			Title:  "strawberry smoothie",
-			Amount: 79,
-			Note:   "night market promotion discount 10 bath",
-			Tags:   []string{"food", "beverage"},
+			ID:      1,
+			Title:   "strawberry smoothie",
+			Amount:  79,
+			Note:    "night market promotion discount 10 bath",
+			Tags:    []string{"food", "beverage"},
+			SpentAt: &spentAt,
+			Status:  "draft",
 		})
 		expect := string(b)
 
@@ -134,15 +202,15 @@ func TestGetExpenseDetailById(t *testing.T) {
 		defer db.Close()
 
 		mock.ExpectQuery("SELECT (.+) FROM expenses").
-			WithArgs("1").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).
-				AddRow(1, "strawberry smoothie", 79, "night market promotion discount 10 bath", pq.Array(&[]string{"food", "beverage"})))
+			WithArgs("1", KindExpense).
+			WillReturnRows(sqlmock.NewRows(expenseColumns).
+				AddRow(1, "strawberry smoothie", 79, "night market promotion discount 10 bath", pq.Array(&[]string{"food", "beverage"}), spentAt, "", nil, nil, "draft", nil, nil))
 
 		gin.SetMode(gin.TestMode)
 		h := NewHandler(db)
 		r := gin.Default()
 		r.GET("/expenses/:id", h.Get)
-		expect := "{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"]}"
+		expect := "{\"id\":1,\"title\":\"strawberry smoothie\",\"amount\":79,\"note\":\"night market promotion discount 10 bath\",\"tags\":[\"food\",\"beverage\"],\"spent_at\":\"2024-01-05T12:00:00Z\",\"status\":\"draft\"}"
 
 		// Act
 		r.ServeHTTP(rec, req)
@@ -168,9 +236,9 @@ func TestGetAllExpenses(t *testing.T) {
 
 	mock.ExpectPrepare("SELECT (.+) FROM expenses").
 		ExpectQuery().
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).
-			AddRow(1, "strawberry smoothie", 79, "night market promotion discount 10 bath", pq.Array(&[]string{"food", "beverage"})).
-			AddRow(2, "apple smoothie", 89, "no discount", pq.Array(&[]string{"beverage"})))
+		WillReturnRows(sqlmock.NewRows(expenseColumns).
+			AddRow(1, "strawberry smoothie", 79, "night market promotion discount 10 bath", pq.Array(&[]string{"food", "beverage"}), spentAt, "", nil, nil, "draft", nil, nil).
+			AddRow(2, "apple smoothie", 89, "no discount", pq.Array(&[]string{"beverage"}), spentAt, "", nil, nil, "draft", nil, nil))
 
 	gin.SetMode(gin.TestMode)
 	h := NewHandler(db)
@@ -178,18 +246,22 @@ func TestGetAllExpenses(t *testing.T) {
 	r.GET("/expenses", h.GetAll)
 	expect := []Expense{
 		{
-			ID:     1,
-			Title:  "strawberry smoothie",
-			Amount: 79,
-			Note:   "night market promotion discount 10 bath",
-			Tags:   []string{"food", "beverage"},
+			ID:      1,
+			Title:   "strawberry smoothie",
+			Amount:  79,
+			Note:    "night market promotion discount 10 bath",
+			Tags:    []string{"food", "beverage"},
+			SpentAt: &spentAt,
+			Status:  "draft",
 		},
 		{
-			ID:     2,
-			Title:  "apple smoothie",
-			Amount: 89,
-			Note:   "no discount",
-			Tags:   []string{"beverage"},
+			ID:      2,
+			Title:   "apple smoothie",
+			Amount:  89,
+			Note:    "no discount",
+			Tags:    []string{"beverage"},
+			SpentAt: &spentAt,
+			Status:  "draft",
 		},
 	}
 	expectBytes, err := json.Marshal(expect)
@@ -275,20 +347,36 @@ func TestUpdateExpense(t *testing.T) {
 		}
 		defer db.Close()
 
+		mock.ExpectQuery("SELECT EXISTS (.+) reconciliation_lines").
+			WithArgs(pq.Array([]int{1})).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery("SELECT alias, tag FROM tag_synonyms").
+			WithArgs(pq.Array([]string{"beverage"})).
+			WillReturnRows(sqlmock.NewRows([]string{"alias", "tag"}))
+		mock.ExpectQuery("SELECT (.+) FROM rules").
+			WillReturnRows(sqlmock.NewRows(ruleColumns))
+		mock.ExpectQuery("SELECT (.+) FROM merchants").
+			WillReturnRows(sqlmock.NewRows(merchantColumns).AddRow(4, "Apple", pq.Array([]string{})))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT title, amount, status FROM expenses (.+) FOR UPDATE").
+			WithArgs(1, KindExpense).
+			WillReturnRows(sqlmock.NewRows([]string{"title", "amount", "status"}).AddRow("strawberry smoothie", 79.0, "submitted"))
 		mock.ExpectPrepare("UPDATE expenses").
 			ExpectExec().
-			WithArgs("1", "apple smoothie", 89.0, "no discount", pq.Array([]string{"beverage"})).
+			WithArgs("1", "apple smoothie", 89.0, "no discount", pq.Array([]string{"beverage"}), nil, "", 4, nil, KindExpense, nil, nil).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectLedgerSync(mock, 1, "apple smoothie", 89.0, []string{"beverage"}, "Expenses:Beverage")
+		mock.ExpectCommit()
 		mock.ExpectQuery("SELECT (.+) FROM expenses").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags"}).
-				AddRow("1", "apple smoothie", 89.0, "no discount", pq.Array([]string{"beverage"})))
+			WillReturnRows(sqlmock.NewRows(expenseColumns).
+				AddRow("1", "apple smoothie", 89.0, "no discount", pq.Array([]string{"beverage"}), spentAt, "", nil, nil, "draft", nil, nil))
 
 		gin.SetMode(gin.TestMode)
 		h := NewHandler(db)
 		r := gin.Default()
 		r.PUT("/expenses/:id", h.Update)
 
-		expect := `{"id":1,"title":"apple smoothie","amount":89,"note":"no discount","tags":["beverage"]}`
+		expect := `{"id":1,"title":"apple smoothie","amount":89,"note":"no discount","tags":["beverage"],"spent_at":"2024-01-05T12:00:00Z","status":"draft"}`
 
 		// Act
 		r.ServeHTTP(rec, req)
@@ -298,4 +386,241 @@ func TestUpdateExpense(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
 	})
+
+	t.Run("Update Reconciled Expense Should Return Conflict", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPut, "/expenses/1", strings.NewReader(`{"title": "apple smoothie", "amount": 89}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT EXISTS (.+) reconciliation_lines").
+			WithArgs(pq.Array([]int{1})).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.PUT("/expenses/:id", h.Update)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("Update Amount Of Approved Expense Should Return Conflict", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPut, "/expenses/1", strings.NewReader(`{"title": "apple smoothie", "amount": 99}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT EXISTS (.+) reconciliation_lines").
+			WithArgs(pq.Array([]int{1})).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery("SELECT (.+) FROM rules").
+			WillReturnRows(sqlmock.NewRows(ruleColumns))
+		mock.ExpectQuery("SELECT (.+) FROM merchants").
+			WillReturnRows(sqlmock.NewRows(merchantColumns))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT title, amount, status FROM expenses (.+) FOR UPDATE").
+			WithArgs(1, KindExpense).
+			WillReturnRows(sqlmock.NewRows([]string{"title", "amount", "status"}).AddRow("apple smoothie", 89.0, "approved"))
+		mock.ExpectRollback()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.PUT("/expenses/:id", h.Update)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, `{"error":"title and amount cannot change once the expense is approved"}`, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestGetAllExpensesWithFilter(t *testing.T) {
+	t.Run("Get All Expenses With Invalid Amount Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/expenses?min_amount=abc", nil)
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/expenses", h.GetAll)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Get All Expenses With Tag And Amount Should Filter", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/expenses?tag=food&min_amount=50", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectPrepare("SELECT id, title, amount, note, tags, spent_at, category, merchant_id, account_id, status, tax, deduction_id FROM expenses WHERE EXISTS (SELECT 1 FROM unnest(tags) t WHERE t = $1 OR t LIKE $2) AND amount >= $3 AND kind = $4").
+			ExpectQuery().
+			WithArgs("food", "food/%", 50.0, KindExpense).
+			WillReturnRows(sqlmock.NewRows(expenseColumns).
+				AddRow(1, "strawberry smoothie", 79, "night market promotion discount 10 bath", pq.Array(&[]string{"food", "beverage"}), spentAt, "", nil, nil, "draft", nil, nil))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/expenses", h.GetAll)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestSearchExpenses(t *testing.T) {
+	t.Run("Search Without Query Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/expenses/search", nil)
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/expenses/search", h.Search)
+		r.GET("/expenses/:id", h.Get)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Search Should Return Ranked Results With Snippet", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/expenses/search?q=smothie&tag=food", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery(`SELECT (.+) FROM expenses, websearch_to_tsquery(.+) WHERE (.+) AND EXISTS (.+)`).
+			WithArgs("smothie", defaultSearchLimit, "food", "food/%", KindExpense).
+			WillReturnRows(sqlmock.NewRows(append(expenseColumns, "rank", "snippet")).
+				AddRow(1, "strawberry smoothie", 79, "night market", pq.Array(&[]string{"food"}), spentAt, "", nil, nil, "draft", nil, nil, 0.5, "strawberry smoothie night market"))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/expenses/search", h.Search)
+		r.GET("/expenses/:id", h.Get)
+		expect := `[{"id":1,"title":"strawberry smoothie","amount":79,"note":"night market","tags":["food"],"spent_at":"2024-01-05T12:00:00Z","status":"draft","rank":0.5,"snippet":"strawberry smoothie night market"}]`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestGetAllExpensesWithQuery(t *testing.T) {
+	t.Run("Get All Expenses With Invalid Query Should Return Error Position", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/expenses?q=amount%3E%3Dabc", nil)
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/expenses", h.GetAll)
+		expect := `{"error":"\"abc\" is not a number at position 9","position":9}`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+
+	t.Run("Get All Expenses With Query Should Combine With Filters", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/expenses?max_amount=500&q=tag%3Afood+and+not+tag%3Abeverage", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectPrepare(`SELECT id, title, amount, note, tags, spent_at, category, merchant_id, account_id, status, tax, deduction_id FROM expenses WHERE amount <= $1 AND ((EXISTS (SELECT 1 FROM unnest(tags) t WHERE t = $2 OR t LIKE $3)) AND NOT (EXISTS (SELECT 1 FROM unnest(tags) t WHERE t = $4 OR t LIKE $5))) AND kind = $6`).
+			ExpectQuery().
+			WithArgs(500.0, "food", "food/%", "beverage", "beverage/%", KindExpense).
+			WillReturnRows(sqlmock.NewRows(expenseColumns))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/expenses", h.GetAll)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
 }
@@ -0,0 +1,98 @@
+//go:build unit
+
+package expense
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateIncome(t *testing.T) {
+	// Arrange
+	body := `{"title": "salary", "amount": 50000, "note": "", "tags": [], "spent_at": "2024-01-05T12:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/income", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM rules").
+		WillReturnRows(sqlmock.NewRows(ruleColumns))
+	mock.ExpectQuery("SELECT (.+) FROM merchants").
+		WillReturnRows(sqlmock.NewRows(merchantColumns))
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE (.+) AND kind = \\$5").
+		WithArgs(49500.0, 50500.0, sqlmock.AnyArg(), sqlmock.AnyArg(), KindIncome).
+		WillReturnRows(sqlmock.NewRows(expenseColumns))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO expenses").
+		WithArgs("salary", 50000.0, "", pq.Array(&[]string{}), spentAt, "", nil, nil, KindIncome, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at"}).AddRow(3, spentAt))
+	mock.ExpectExec("DELETE FROM journal_entries").
+		WithArgs(pq.Array([]int{3})).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT (.+) FROM expenses e LEFT JOIN accounts a").
+		WithArgs(pq.Array([]int{3})).
+		WillReturnRows(sqlmock.NewRows(ledgerColumns).AddRow(3, "salary", 50000.0, KindIncome, pq.Array([]string{}), "", spentAt, 2, "KBank Savings", "cash"))
+	mock.ExpectQuery("INSERT INTO journal_entries").
+		WithArgs(KindIncome, 3, nil, spentAt, "salary").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectExec("INSERT INTO postings").
+		WithArgs(10, "Income:Uncategorized", nil, -50000.0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO postings").
+		WithArgs(10, "Assets:KBank-Savings", 2, 50000.0).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	gin.SetMode(gin.TestMode)
+	h := NewIncomeHandler(db)
+	r := gin.Default()
+	r.POST("/income", h.Create)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{"id":3,"title":"salary","amount":50000,"note":"","tags":[],"spent_at":"2024-01-05T12:00:00Z","status":"draft"}`, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestGetIncomeIsScopedToIncome(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/income/1", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM expenses WHERE id = \\$1 AND kind = \\$2").
+		WithArgs("1", KindIncome).
+		WillReturnRows(sqlmock.NewRows(expenseColumns))
+
+	gin.SetMode(gin.TestMode)
+	h := NewIncomeHandler(db)
+	r := gin.Default()
+	r.GET("/income/:id", h.Get)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.NotEqual(t, http.StatusOK, rec.Code)
+}
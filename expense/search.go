@@ -0,0 +1,80 @@
+package expense
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Search ranks expenses by full-text match on title and note, falling back to
+// trigram word similarity so that typos in title, note or tags still match.
+func (h *handler) Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		if n > maxSearchLimit {
+			n = maxSearchLimit
+		}
+		limit = n
+	}
+
+	conds, args, err := listFilter(c, 2)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	conds = append([]string{`(
+			search @@ query
+			OR $1 <% coalesce(title, '')
+			OR $1 <% coalesce(note, '')
+			OR EXISTS (SELECT 1 FROM unnest(tags) tag WHERE $1 <% tag)
+		)`}, conds...)
+	args = append([]interface{}{q, limit}, args...)
+	args = append(args, h.Kind)
+	conds = append(conds, fmt.Sprintf("kind = $%d", len(args)))
+
+	rows, err := h.DB.Query(fmt.Sprintf(`
+		SELECT `+Columns+`,
+			ts_rank(search, query) + word_similarity($1, coalesce(title, '')) AS rank,
+			ts_headline('english', coalesce(title, '') || ' ' || coalesce(note, ''), query,
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2') AS snippet
+		FROM expenses, websearch_to_tsquery('english', $1) query
+		%s
+		ORDER BY rank DESC, id
+		LIMIT $2`, where(conds)), args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(append(r.Fields(), &r.Rank, &r.Snippet)...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		results = append(results, r)
+	}
+
+	c.JSON(http.StatusOK, results)
+}
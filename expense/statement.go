@@ -0,0 +1,173 @@
+package expense
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/jsritawan/assessment/statement"
+	"github.com/lib/pq"
+)
+
+// StatementImport reports what importing a bank statement did. Lines
+// imported before and money coming in are skipped.
+type StatementImport struct {
+	Imported   []Expense `json:"imported"`
+	Duplicates int       `json:"duplicates"`
+	Credits    int       `json:"credits"`
+}
+
+// fromStatement turns a debit line of a bank statement into an expense.
+func fromStatement(t statement.Transaction, accountID *int) Expense {
+	spentAt := t.Date
+	e := Expense{Title: t.Payee, Amount: -t.Amount, Note: t.Memo, Tags: []string{}, SpentAt: &spentAt, AccountID: accountID}
+	if e.Title == "" {
+		e.Title, e.Note = t.Memo, ""
+	}
+	if e.Title == "" {
+		e.Title = "bank transaction " + t.ID
+	}
+	return e
+}
+
+// ImportStatement creates expenses from the debit lines of a bank
+// statement in any format of package statement, detecting the format
+// unless it is given.
+// The bank's transaction IDs are kept, so importing an overlapping
+// statement again only adds the lines that are new.
+func (h *handler) ImportStatement(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := c.Query("format")
+	if format == "" {
+		if format = statement.Detect(data); format == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognized statement format, pass format as one of " + strings.Join(statement.Formats(), ", ")})
+			return
+		}
+	}
+
+	var accountID *int
+	if v := c.Query("account_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid account_id %q", v)})
+			return
+		}
+		var exists bool
+		if err := h.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM accounts WHERE id = $1)", id).Scan(&exists); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		accountID = &id
+	}
+
+	s, err := statement.Parse(bytes.NewReader(data), format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Transaction IDs are unique per bank account, so they are kept
+	// together with the account the statement names, or the one it is
+	// imported into when it names none.
+	source := s.Account
+	if source == "" && accountID != nil {
+		source = "account:" + strconv.Itoa(*accountID)
+	}
+
+	ids := make([]string, len(s.Transactions))
+	for i, t := range s.Transactions {
+		ids[i] = t.ID
+	}
+	seen, err := h.importedTransactions(source, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := StatementImport{Imported: []Expense{}}
+	var lines []statement.Transaction
+	for _, t := range s.Transactions {
+		switch {
+		case seen[t.ID]:
+			result.Duplicates++
+		case !t.Debit():
+			result.Credits++
+		default:
+			e := fromStatement(t, accountID)
+			if err := h.prepare(&e); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			result.Imported = append(result.Imported, e)
+			lines = append(lines, t)
+		}
+		seen[t.ID] = true
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	created := make([]int, len(result.Imported))
+	for i := range result.Imported {
+		e := &result.Imported[i]
+		if err := Insert(tx, h.Kind, e); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		_, err := tx.Exec("INSERT INTO bank_transactions(source, transaction_id, expense_id) VALUES ($1, $2, $3)", source, lines[i].ID, e.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		created[i] = e.ID
+	}
+	if err := ledger.SyncExpenses(tx, created); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// importedTransactions returns which of the transaction IDs of a source
+// were imported before.
+func (h *handler) importedTransactions(source string, ids []string) (map[string]bool, error) {
+	seen := map[string]bool{}
+	if len(ids) == 0 {
+		return seen, nil
+	}
+	rows, err := h.DB.Query("SELECT transaction_id FROM bank_transactions WHERE source = $1 AND transaction_id = ANY($2)", source, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		seen[id] = true
+	}
+	return seen, rows.Err()
+}
@@ -0,0 +1,99 @@
+//go:build unit
+
+package expense
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const ofxStatement = `OFXHEADER:100
+<OFX>
+<BANKACCTFROM><ACCTID>123-4-56789-0</BANKACCTFROM>
+<STMTTRN><DTPOSTED>20240104<TRNAMT>-89.00<FITID>T1<NAME>Cafe</STMTTRN>
+<STMTTRN><DTPOSTED>20240105<TRNAMT>-120.00<FITID>T2<NAME>Somtam Shop<MEMO>lunch</STMTTRN>
+<STMTTRN><DTPOSTED>20240105<TRNAMT>500.00<FITID>T3<NAME>transfer in</STMTTRN>
+</OFX>
+`
+
+func TestImportStatement(t *testing.T) {
+	t.Run("Import Statement Into Unknown Account Should Return Not Found", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expenses/import/statement?account_id=9", strings.NewReader(ofxStatement))
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT EXISTS (.+) FROM accounts").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/expenses/import/statement", h.ImportStatement)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Import Statement Should Skip Imported Lines And Credits", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expenses/import/statement", strings.NewReader(ofxStatement))
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		date := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT transaction_id FROM bank_transactions").
+			WithArgs("123-4-56789-0", pq.Array([]string{"T1", "T2", "T3"})).
+			WillReturnRows(sqlmock.NewRows([]string{"transaction_id"}).AddRow("T1"))
+		mock.ExpectQuery("SELECT (.+) FROM rules").
+			WillReturnRows(sqlmock.NewRows(ruleColumns))
+		mock.ExpectQuery("SELECT (.+) FROM merchants").
+			WillReturnRows(sqlmock.NewRows(merchantColumns))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO expenses").
+			WithArgs("Somtam Shop", 120.0, "lunch", pq.Array(&[]string{}), &date, "", nil, nil, KindExpense, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at"}).AddRow(4, date))
+		mock.ExpectExec("INSERT INTO bank_transactions").
+			WithArgs("123-4-56789-0", "T2", 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectLedgerSync(mock, 4, "Somtam Shop", 120.0, []string{}, "Expenses:Uncategorized")
+		mock.ExpectCommit()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/expenses/import/statement", h.ImportStatement)
+		expect := `{"imported":[{"id":4,"title":"Somtam Shop","amount":120,"note":"lunch","tags":[],"spent_at":"2024-01-05T00:00:00Z"}],"duplicates":1,"credits":1}`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
@@ -0,0 +1,158 @@
+package expense
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// VAT modes say how the price on the receipt relates to VAT.
+const (
+	// VATInclusive prices already contain VAT, as on retail receipts. The
+	// expense amount is the gross and VAT is worked out of it.
+	VATInclusive = "inclusive"
+	// VATExclusive prices have VAT added on top, as on tax invoices between
+	// businesses. Net is the price before VAT and the amount becomes the
+	// gross.
+	VATExclusive = "exclusive"
+)
+
+// DefaultVATRate is the standard Thai VAT rate, used when a request leaves
+// vat_rate out. Zero-rated purchases send a vat_rate of 0.
+const DefaultVATRate = 7
+
+// Tax is the VAT and withholding tax breakdown of an expense. Net, VAT,
+// Withholding and Payable are worked out when the expense is written;
+// only Net is read from requests, and only in exclusive mode.
+type Tax struct {
+	VATMode string  `json:"vat_mode"`
+	VATRate float64 `json:"vat_rate"`
+	// VendorTaxID is the 13 digit tax ID on the vendor's tax invoice,
+	// without which input VAT cannot be claimed.
+	VendorTaxID string `json:"vendor_tax_id,omitempty"`
+	// WithholdingRate is the percentage of the net the payer withholds and
+	// remits for the vendor, as for services.
+	WithholdingRate float64 `json:"withholding_rate,omitempty"`
+
+	Net         float64 `json:"net"`
+	VAT         float64 `json:"vat"`
+	Withholding float64 `json:"withholding"`
+	// Payable is the gross less withholding, what the vendor is paid.
+	Payable float64 `json:"payable"`
+}
+
+// UnmarshalJSON reads a Tax, defaulting an omitted vat_rate to
+// DefaultVATRate.
+func (t *Tax) UnmarshalJSON(data []byte) error {
+	type plain Tax
+	p := plain{VATRate: DefaultVATRate}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*t = Tax(p)
+	return nil
+}
+
+// Value stores a Tax as JSON, and a nil Tax as NULL.
+func (t *Tax) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+func (t *Tax) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	}
+	return fmt.Errorf("cannot scan %T into Tax", src)
+}
+
+// cents turns an amount into whole satang, rounding half away from zero,
+// so the breakdown is worked out without float drift.
+func cents(f float64) int64 {
+	return int64(math.Round(f * 100))
+}
+
+// percentOf is p percent of c satang, rounded to the satang.
+func percentOf(c int64, p float64) int64 {
+	return int64(math.Round(float64(c) * p / 100))
+}
+
+// validTaxID checks a Thai tax ID: 13 digits, the last a check digit over
+// the first 12.
+func validTaxID(id string) bool {
+	if len(id) != 13 {
+		return false
+	}
+	sum := 0
+	for i, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+		if i < 12 {
+			sum += int(r-'0') * (13 - i)
+		}
+	}
+	return (11-sum%11)%10 == int(id[12]-'0')
+}
+
+// ApplyTax validates the tax of e and works out its breakdown. In
+// exclusive mode the amount is set to the gross. A net in the request
+// takes precedence over the amount, so writing back an expense as it was
+// read does not add VAT twice, but an amount sent with it must be that
+// gross, or an edited amount would be lost. Running it again changes nothing, so
+// callers that work anything out from the amount run it before Insert.
+func ApplyTax(e *Expense) error {
+	t := e.Tax
+	if t == nil {
+		return nil
+	}
+	if t.VATMode == "" {
+		t.VATMode = VATInclusive
+	}
+	if t.VATMode != VATInclusive && t.VATMode != VATExclusive {
+		return fmt.Errorf("invalid vat_mode %q, use %s or %s", t.VATMode, VATInclusive, VATExclusive)
+	}
+	if t.VATRate < 0 || t.VATRate > 100 {
+		return fmt.Errorf("invalid vat_rate %v", t.VATRate)
+	}
+	if t.WithholdingRate < 0 || t.WithholdingRate > 100 {
+		return fmt.Errorf("invalid withholding_rate %v", t.WithholdingRate)
+	}
+	t.VendorTaxID = strings.NewReplacer("-", "", " ", "").Replace(t.VendorTaxID)
+	if t.VendorTaxID != "" && !validTaxID(t.VendorTaxID) {
+		return fmt.Errorf("invalid vendor_tax_id %q", t.VendorTaxID)
+	}
+
+	var net, vat, gross int64
+	switch t.VATMode {
+	case VATInclusive:
+		gross = cents(e.Amount)
+		vat = int64(math.Round(float64(gross) * t.VATRate / (100 + t.VATRate)))
+		net = gross - vat
+	case VATExclusive:
+		net = cents(t.Net)
+		if net == 0 {
+			net = cents(e.Amount)
+		}
+		vat = percentOf(net, t.VATRate)
+		gross = net + vat
+		if t.Net != 0 && e.Amount != 0 && cents(e.Amount) != gross {
+			return fmt.Errorf("amount %v is not net %v plus VAT, send only one of them", e.Amount, t.Net)
+		}
+	}
+	withholding := percentOf(net, t.WithholdingRate)
+
+	e.Amount = float64(gross) / 100
+	t.Net = float64(net) / 100
+	t.VAT = float64(vat) / 100
+	t.Withholding = float64(withholding) / 100
+	t.Payable = float64(gross-withholding) / 100
+	return nil
+}
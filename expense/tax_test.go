@@ -0,0 +1,91 @@
+//go:build unit
+
+package expense
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyTax(t *testing.T) {
+	t.Run("Inclusive Should Work VAT Out Of The Amount", func(t *testing.T) {
+		e := Expense{Amount: 100, Tax: &Tax{VATMode: VATInclusive, VATRate: 7}}
+
+		err := ApplyTax(&e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, e.Amount)
+		assert.Equal(t, Tax{VATMode: VATInclusive, VATRate: 7, Net: 93.46, VAT: 6.54, Payable: 100}, *e.Tax)
+	})
+
+	t.Run("Exclusive Should Add VAT And Withhold From Net", func(t *testing.T) {
+		e := Expense{Amount: 1000, Tax: &Tax{VATMode: VATExclusive, VATRate: 7, VendorTaxID: "0-1055-55123-45-0", WithholdingRate: 3}}
+
+		err := ApplyTax(&e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1070.0, e.Amount)
+		assert.Equal(t, Tax{VATMode: VATExclusive, VATRate: 7, VendorTaxID: "0105555123450", WithholdingRate: 3, Net: 1000, VAT: 70, Withholding: 30, Payable: 1040}, *e.Tax)
+	})
+
+	t.Run("Exclusive Written Back Should Keep Its Net", func(t *testing.T) {
+		e := Expense{Amount: 1070, Tax: &Tax{VATMode: VATExclusive, VATRate: 7, Net: 1000}}
+
+		err := ApplyTax(&e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1070.0, e.Amount)
+		assert.Equal(t, 70.0, e.Tax.VAT)
+	})
+
+	t.Run("Exclusive With Edited Amount Should Fail", func(t *testing.T) {
+		e := Expense{Amount: 1200, Tax: &Tax{VATMode: VATExclusive, VATRate: 7, Net: 1000}}
+
+		err := ApplyTax(&e)
+
+		assert.EqualError(t, err, "amount 1200 is not net 1000 plus VAT, send only one of them")
+	})
+
+	t.Run("Omitted VAT Rate Should Default To Standard Rate", func(t *testing.T) {
+		var e Expense
+		assert.NoError(t, json.Unmarshal([]byte(`{"amount": 107, "tax": {"vat_mode": "inclusive"}}`), &e))
+
+		err := ApplyTax(&e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7.0, e.Tax.VATRate)
+		assert.Equal(t, 7.0, e.Tax.VAT)
+	})
+
+	t.Run("Zero VAT Rate Should Be Kept", func(t *testing.T) {
+		var e Expense
+		assert.NoError(t, json.Unmarshal([]byte(`{"amount": 100, "tax": {"vat_rate": 0}}`), &e))
+
+		err := ApplyTax(&e)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0.0, e.Tax.VAT)
+	})
+
+	t.Run("Invalid Tax Should Fail", func(t *testing.T) {
+		for _, tax := range []Tax{
+			{VATMode: "gross", VATRate: 7},
+			{VATMode: VATInclusive, VATRate: -1},
+			{VATMode: VATInclusive, VATRate: 7, WithholdingRate: 101},
+			{VATMode: VATInclusive, VATRate: 7, VendorTaxID: "0105555123451"},
+		} {
+			tax := tax
+			e := Expense{Amount: 100, Tax: &tax}
+			assert.Error(t, ApplyTax(&e))
+		}
+	})
+
+	t.Run("No Tax Should Leave Expense As Is", func(t *testing.T) {
+		e := Expense{Amount: 100}
+
+		assert.NoError(t, ApplyTax(&e))
+		assert.Nil(t, e.Tax)
+	})
+}
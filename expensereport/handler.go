@@ -0,0 +1,464 @@
+package expensereport
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/approval"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/user"
+	"github.com/lib/pq"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+// selectItems selects the expenses of a report with the currency of their
+// account, THB when they have none.
+const selectItems = `
+	SELECT ` + expense.Columns + `, currency FROM (
+		SELECT e.*, COALESCE(a.currency, 'THB') AS currency
+		FROM expense_report_items i
+		JOIN expenses e ON e.id = i.expense_id
+		LEFT JOIN accounts a ON a.id = e.account_id
+		WHERE i.report_id = $1
+	) e
+	ORDER BY spent_at, id`
+
+// load reads a report with its expenses and totals.
+func (h *handler) load(id int) (Report, error) {
+	var r Report
+	row := h.DB.QueryRow("SELECT id, title, owner, status, created_at FROM expense_reports WHERE id = $1", id)
+	if err := row.Scan(&r.ID, &r.Title, &r.Owner, &r.Status, &r.CreatedAt); err != nil {
+		return r, err
+	}
+
+	rows, err := h.DB.Query(selectItems, id)
+	if err != nil {
+		return r, err
+	}
+	defer rows.Close()
+
+	r.Expenses = []Item{}
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(append(item.Fields(), &item.Currency)...); err != nil {
+			return r, err
+		}
+		r.Expenses = append(r.Expenses, item)
+	}
+	r.summarize()
+	return r, rows.Err()
+}
+
+// find loads the report of the request.
+func (h *handler) find(c *gin.Context) (Report, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return Report{}, false
+	}
+	r, err := h.load(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expense report not found"})
+		return r, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return r, false
+	}
+	return r, true
+}
+
+// editable reports whether expenses may still be added to or removed from
+// a report in status.
+func editable(status string) bool {
+	return status == approval.StatusDraft || status == approval.StatusRejected
+}
+
+// addExpenses puts expenses into a report. Only expenses that are drafts
+// or were rejected, and are in no other report, can be added.
+func addExpenses(tx *sql.Tx, reportID int, ids []int) error {
+	rows, err := tx.Query(`
+		SELECT e.id, e.status, i.report_id
+		FROM expenses e
+		LEFT JOIN expense_report_items i ON i.expense_id = e.id
+		WHERE e.id = ANY($1) AND e.kind = $2
+		FOR UPDATE OF e
+		`, pq.Array(ids), expense.KindExpense)
+	if err != nil {
+		return err
+	}
+	found := map[int]bool{}
+	for rows.Next() {
+		var id int
+		var status string
+		var in *int
+		if err := rows.Scan(&id, &status, &in); err != nil {
+			rows.Close()
+			return err
+		}
+		found[id] = true
+		if in != nil && *in != reportID {
+			rows.Close()
+			return &inputError{http.StatusConflict, fmt.Sprintf("expense %d is in expense report %d", id, *in)}
+		}
+		if !editable(status) {
+			rows.Close()
+			return &inputError{http.StatusConflict, fmt.Sprintf("expense %d is %s", id, status)}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return &inputError{http.StatusNotFound, fmt.Sprintf("expense %d not found", id)}
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO expense_report_items(report_id, expense_id)
+		SELECT $1, unnest($2::INT[])
+		ON CONFLICT DO NOTHING
+		`, reportID, pq.Array(ids))
+	return err
+}
+
+// inputError is a request the data rules out, with the status to answer.
+type inputError struct {
+	status  int
+	message string
+}
+
+func (e *inputError) Error() string {
+	return e.message
+}
+
+func errorStatus(err error) int {
+	var ie *inputError
+	if errors.As(err, &ie) {
+		return ie.status
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *handler) Create(c *gin.Context) {
+	var body struct {
+		Title string `json:"title"`
+		Expenses
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(body.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var id int
+	row := tx.QueryRow("INSERT INTO expense_reports(title, owner, status) VALUES ($1, $2, $3) RETURNING id", body.Title, user.FromContext(c), approval.StatusDraft)
+	if err := row.Scan(&id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(body.ExpenseIDs) > 0 {
+		if err := addExpenses(tx, id, body.ExpenseIDs); err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	r, err := h.load(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, r)
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT id, title, owner, status, created_at FROM expense_reports ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		var r Report
+		if err := rows.Scan(&r.ID, &r.Title, &r.Owner, &r.Status, &r.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		reports = append(reports, r)
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	r, ok := h.find(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// AddExpenses puts more expenses into a draft or rejected report.
+func (h *handler) AddExpenses(c *gin.Context) {
+	r, ok := h.find(c)
+	if !ok {
+		return
+	}
+	var body Expenses
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(body.ExpenseIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expense_ids is required"})
+		return
+	}
+	if !editable(r.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "expense report is " + r.Status})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := addExpenses(tx, r.ID, body.ExpenseIDs); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	r, err = h.load(r.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+// RemoveExpense takes an expense out of a draft or rejected report.
+func (h *handler) RemoveExpense(c *gin.Context) {
+	r, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if !editable(r.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "expense report is " + r.Status})
+		return
+	}
+	expenseID, err := strconv.Atoi(c.Param("expenseId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expense id"})
+		return
+	}
+
+	res, err := h.DB.Exec("DELETE FROM expense_report_items WHERE report_id = $1 AND expense_id = $2", r.ID, expenseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expense is not in the expense report"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *handler) Submit(c *gin.Context) {
+	h.transition(c, approval.Submit)
+}
+
+func (h *handler) Approve(c *gin.Context) {
+	h.transition(c, approval.Approve)
+}
+
+func (h *handler) Reject(c *gin.Context) {
+	h.transition(c, approval.Reject)
+}
+
+func (h *handler) Reimburse(c *gin.Context) {
+	h.transition(c, approval.Reimburse)
+}
+
+// transition takes a workflow action on the report of the request and
+// moves its expenses along with it, which locks them once approved.
+func (h *handler) transition(c *gin.Context, action string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	actor := user.FromContext(c)
+	role, err := user.Role(tx, actor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	r := approval.Request{Action: action, Actor: actor, Role: role, Comment: body.Comment}
+	row := tx.QueryRow("SELECT status FROM expense_reports WHERE id = $1 FOR UPDATE", id)
+	if err := row.Scan(&r.Status); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expense report not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM expense_report_items WHERE report_id = $1", id).Scan(&count); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expense report has no expenses"})
+		return
+	}
+	row = tx.QueryRow("SELECT actor FROM expense_report_transitions WHERE report_id = $1 AND to_status = $2 ORDER BY id DESC LIMIT 1", id, approval.StatusSubmitted)
+	if err := row.Scan(&r.Submitter); err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	next, err := approval.Default.Next(r)
+	if err != nil {
+		c.JSON(approval.StatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	t := approval.Transition{From: r.Status, To: next, Actor: r.Actor, Role: r.Role, Comment: r.Comment}
+	if _, err := tx.Exec("UPDATE expense_reports SET status = $2 WHERE id = $1", id, next); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("UPDATE expenses SET status = $2 WHERE id IN (SELECT expense_id FROM expense_report_items WHERE report_id = $1)", id, next); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	row = tx.QueryRow(`
+		INSERT INTO expense_report_transitions(report_id, from_status, to_status, actor, role, comment)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+		`,
+		id, t.From, t.To, t.Actor, t.Role, t.Comment)
+	if err := row.Scan(&t.ID, &t.CreatedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// Transitions lists the workflow history of a report, oldest first.
+func (h *handler) Transitions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	rows, err := h.DB.Query("SELECT id, from_status, to_status, actor, role, comment, created_at FROM expense_report_transitions WHERE report_id = $1 ORDER BY id", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	transitions := []approval.Transition{}
+	for rows.Next() {
+		var t approval.Transition
+		if err := rows.Scan(&t.ID, &t.From, &t.To, &t.Actor, &t.Role, &t.Comment, &t.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		transitions = append(transitions, t)
+	}
+
+	c.JSON(http.StatusOK, transitions)
+}
+
+// Export renders a report as a printable HTML page, the default, or as
+// CSV with format=csv.
+func (h *handler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatHTML)
+	if format != FormatHTML && format != FormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown format %q, use %s or %s", format, FormatHTML, FormatCSV)})
+		return
+	}
+	r, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, format, &r); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	contentType := "text/html; charset=utf-8"
+	if format == FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="expense-report-%d.csv"`, r.ID))
+	}
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
@@ -0,0 +1,226 @@
+//go:build unit
+
+package expensereport
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/user"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	createdAt     = time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	spentAt       = time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	reportColumns = []string{"id", "title", "owner", "status", "created_at"}
+	itemColumns   = []string{"id", "title", "amount", "note", "tags", "spent_at", "category", "merchant_id", "account_id", "status", "tax", "deduction_id", "currency"}
+)
+
+func expectReport(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectQuery("SELECT (.+) FROM expense_reports WHERE id").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(reportColumns).AddRow(3, "Chiang Mai trip", "ann", status, createdAt))
+	mock.ExpectQuery("SELECT (.+) FROM expense_report_items i").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(itemColumns).
+			AddRow(1, "flight", 1890.5, "", pq.Array([]string{"travel"}), spentAt, "", nil, nil, status, nil, nil, "THB"))
+}
+
+func TestCreateReport(t *testing.T) {
+	t.Run("Create Report Should Return Created With Totals", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expense-reports", strings.NewReader(`{"title": "Chiang Mai trip", "expense_ids": [1]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(user.Header, "ann")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO expense_reports").
+			WithArgs("Chiang Mai trip", "ann", "draft").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery("SELECT e.id, e.status, i.report_id FROM expenses e").
+			WithArgs(pq.Array([]int{1}), expense.KindExpense).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "report_id"}).AddRow(1, "draft", nil))
+		mock.ExpectExec("INSERT INTO expense_report_items").
+			WithArgs(3, pq.Array([]int{1})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectReport(mock, "draft")
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.POST("/expense-reports", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"totals":[{"currency":"THB","count":1,"amount":1890.5}],"by_tag":[{"tag":"travel","currency":"THB","count":1,"amount":1890.5}]`)
+	})
+
+	t.Run("Create Report With Approved Expense Should Return Conflict", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expense-reports", strings.NewReader(`{"title": "Chiang Mai trip", "expense_ids": [1]}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO expense_reports").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery("SELECT e.id, e.status, i.report_id FROM expenses e").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "report_id"}).AddRow(1, "approved", nil))
+		mock.ExpectRollback()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/expense-reports", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, `{"error":"expense 1 is approved"}`, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestReportTransition(t *testing.T) {
+	t.Run("Submit Should Move Expenses Along", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expense-reports/3/submit", nil)
+		req.Header.Set(user.Header, "ann")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT role FROM user_roles").
+			WithArgs("ann").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT status FROM expense_reports").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("draft"))
+		mock.ExpectQuery("SELECT COUNT(.+) FROM expense_report_items").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT actor FROM expense_report_transitions").
+			WithArgs(3, "submitted").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectExec("UPDATE expense_reports SET status").
+			WithArgs(3, "submitted").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE expenses SET status").
+			WithArgs(3, "submitted").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery("INSERT INTO expense_report_transitions").
+			WithArgs(3, "draft", "submitted", "ann", user.RoleEmployee, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, createdAt))
+		mock.ExpectCommit()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.POST("/expense-reports/:id/submit", h.Submit)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Submit Empty Report Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/expense-reports/3/submit", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM expense_reports").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("draft"))
+		mock.ExpectQuery("SELECT COUNT(.+) FROM expense_report_items").
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectRollback()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/expense-reports/:id/submit", h.Submit)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestExportReport(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/expense-reports/3/export?format=csv", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectReport(mock, "approved")
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.GET("/expense-reports/:id/export", h.Export)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="expense-report-3.csv"`, rec.Header().Get("Content-Disposition"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "date,title,amount,currency,tags,category,note\n2024-03-04,flight,1890.50,THB,travel,,\n"))
+}
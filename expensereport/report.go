@@ -0,0 +1,195 @@
+package expensereport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jsritawan/assessment/expense"
+)
+
+// Untagged is the tag expenses without tags are totalled under.
+const Untagged = "(untagged)"
+
+// Report bundles expenses, such as those of one trip, so they are
+// submitted and approved together.
+type Report struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Owner     string     `json:"owner"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	Expenses  []Item     `json:"expenses,omitempty"`
+	Totals    []Total    `json:"totals,omitempty"`
+	ByTag     []TagTotal `json:"by_tag,omitempty"`
+}
+
+// Expenses names the expenses to put in a report.
+type Expenses struct {
+	ExpenseIDs []int `json:"expense_ids"`
+}
+
+// Item is an expense of a report with the currency of the account it was
+// paid from.
+type Item struct {
+	expense.Expense
+	Currency string `json:"currency"`
+}
+
+type Total struct {
+	Currency string  `json:"currency"`
+	Count    int     `json:"count"`
+	Amount   float64 `json:"amount"`
+}
+
+// TagTotal sums the expenses carrying a tag. An expense with several tags
+// counts towards each, so these do not add up to the totals.
+type TagTotal struct {
+	Tag      string  `json:"tag"`
+	Currency string  `json:"currency"`
+	Count    int     `json:"count"`
+	Amount   float64 `json:"amount"`
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// summarize totals the expenses of r by currency and by tag.
+func (r *Report) summarize() {
+	totals := map[string]*Total{}
+	byTag := map[[2]string]*TagTotal{}
+	for _, e := range r.Expenses {
+		t, ok := totals[e.Currency]
+		if !ok {
+			t = &Total{Currency: e.Currency}
+			totals[e.Currency] = t
+		}
+		t.Count++
+		t.Amount += e.Amount
+
+		tags := e.Tags
+		if len(tags) == 0 {
+			tags = []string{Untagged}
+		}
+		for _, tag := range tags {
+			key := [2]string{tag, e.Currency}
+			tt, ok := byTag[key]
+			if !ok {
+				tt = &TagTotal{Tag: tag, Currency: e.Currency}
+				byTag[key] = tt
+			}
+			tt.Count++
+			tt.Amount += e.Amount
+		}
+	}
+
+	r.Totals = make([]Total, 0, len(totals))
+	for _, t := range totals {
+		t.Amount = round(t.Amount)
+		r.Totals = append(r.Totals, *t)
+	}
+	sort.Slice(r.Totals, func(i, j int) bool { return r.Totals[i].Currency < r.Totals[j].Currency })
+
+	r.ByTag = make([]TagTotal, 0, len(byTag))
+	for _, t := range byTag {
+		t.Amount = round(t.Amount)
+		r.ByTag = append(r.ByTag, *t)
+	}
+	sort.Slice(r.ByTag, func(i, j int) bool {
+		if r.ByTag[i].Tag != r.ByTag[j].Tag {
+			return r.ByTag[i].Tag < r.ByTag[j].Tag
+		}
+		return r.ByTag[i].Currency < r.ByTag[j].Currency
+	})
+}
+
+// Export formats of a report.
+const (
+	FormatHTML = "html"
+	FormatCSV  = "csv"
+)
+
+func amount(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+func date(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// writeCSV writes one row per expense followed by the totals per currency
+// and per tag, each section under its own header row.
+func writeCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "title", "amount", "currency", "tags", "category", "note"})
+	for _, e := range r.Expenses {
+		cw.Write([]string{date(e.SpentAt), e.Title, amount(e.Amount), e.Currency, strings.Join(e.Tags, " "), e.Category, e.Note})
+	}
+	cw.Write(nil)
+	cw.Write([]string{"total", "count", "amount", "currency"})
+	for _, t := range r.Totals {
+		cw.Write([]string{"", strconv.Itoa(t.Count), amount(t.Amount), t.Currency})
+	}
+	cw.Write(nil)
+	cw.Write([]string{"tag", "count", "amount", "currency"})
+	for _, t := range r.ByTag {
+		cw.Write([]string{t.Tag, strconv.Itoa(t.Count), amount(t.Amount), t.Currency})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"amount": amount,
+	"date":   date,
+	"join":   strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+td.amount, th.amount { text-align: right; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Expense report #{{.ID}}{{with .Owner}} by {{.}}{{end}}, {{.Status}}</p>
+<table>
+<tr><th>Date</th><th>Title</th><th>Tags</th><th>Category</th><th class="amount">Amount</th><th>Currency</th></tr>
+{{range .Expenses}}<tr><td>{{date .SpentAt}}</td><td>{{.Title}}</td><td>{{join .Tags ", "}}</td><td>{{.Category}}</td><td class="amount">{{amount .Amount}}</td><td>{{.Currency}}</td></tr>
+{{end}}{{range .Totals}}<tr><th colspan="4">Total ({{.Count}})</th><th class="amount">{{amount .Amount}}</th><th>{{.Currency}}</th></tr>
+{{end}}</table>
+<h2>By tag</h2>
+<table>
+<tr><th>Tag</th><th>Count</th><th class="amount">Amount</th><th>Currency</th></tr>
+{{range .ByTag}}<tr><td>{{.Tag}}</td><td>{{.Count}}</td><td class="amount">{{amount .Amount}}</td><td>{{.Currency}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// Write exports r as a printable HTML page or as CSV.
+func Write(w io.Writer, format string, r *Report) error {
+	switch format {
+	case FormatHTML:
+		return page.Execute(w, r)
+	case FormatCSV:
+		return writeCSV(w, r)
+	}
+	return fmt.Errorf("unknown format %q, use %s or %s", format, FormatHTML, FormatCSV)
+}
@@ -0,0 +1,85 @@
+//go:build unit
+
+package expensereport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jsritawan/assessment/expense"
+	"github.com/stretchr/testify/assert"
+)
+
+func testReport() *Report {
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	r := &Report{
+		ID:     3,
+		Title:  "Chiang Mai trip",
+		Owner:  "ann",
+		Status: "draft",
+		Expenses: []Item{
+			{Expense: expense.Expense{ID: 1, Title: "flight", Amount: 1890.5, Tags: []string{"travel"}, SpentAt: &day}, Currency: "THB"},
+			{Expense: expense.Expense{ID: 2, Title: "hotel", Amount: 2400.25, Tags: []string{"travel", "lodging"}, SpentAt: &day}, Currency: "THB"},
+			{Expense: expense.Expense{ID: 3, Title: "sim card <eSIM>", Amount: 12, Tags: []string{}, SpentAt: &day}, Currency: "USD"},
+		},
+	}
+	r.summarize()
+	return r
+}
+
+func TestSummarize(t *testing.T) {
+	r := testReport()
+
+	assert.Equal(t, []Total{
+		{Currency: "THB", Count: 2, Amount: 4290.75},
+		{Currency: "USD", Count: 1, Amount: 12},
+	}, r.Totals)
+	assert.Equal(t, []TagTotal{
+		{Tag: Untagged, Currency: "USD", Count: 1, Amount: 12},
+		{Tag: "lodging", Currency: "THB", Count: 1, Amount: 2400.25},
+		{Tag: "travel", Currency: "THB", Count: 2, Amount: 4290.75},
+	}, r.ByTag)
+}
+
+func TestWrite(t *testing.T) {
+	t.Run("CSV Should List Expenses Then Totals", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := Write(&buf, FormatCSV, testReport())
+
+		assert.NoError(t, err)
+		assert.Equal(t, `date,title,amount,currency,tags,category,note
+2024-03-04,flight,1890.50,THB,travel,,
+2024-03-04,hotel,2400.25,THB,travel lodging,,
+2024-03-04,sim card <eSIM>,12.00,USD,,,
+
+total,count,amount,currency
+,2,4290.75,THB
+,1,12.00,USD
+
+tag,count,amount,currency
+(untagged),1,12.00,USD
+lodging,1,2400.25,THB
+travel,2,4290.75,THB
+`, buf.String())
+	})
+
+	t.Run("HTML Should Escape Titles", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := Write(&buf, FormatHTML, testReport())
+
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "<title>Chiang Mai trip</title>")
+		assert.Contains(t, buf.String(), "sim card &lt;eSIM&gt;")
+		assert.Contains(t, buf.String(), `<th colspan="4">Total (2)</th><th class="amount">4290.75</th><th>THB</th>`)
+	})
+
+	t.Run("Unknown Format Should Fail", func(t *testing.T) {
+		err := Write(&bytes.Buffer{}, "pdf", testReport())
+
+		assert.True(t, strings.HasPrefix(err.Error(), `unknown format "pdf"`))
+	})
+}
@@ -0,0 +1,188 @@
+package goal
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jsritawan/assessment/tag"
+)
+
+// DefaultHistory is how many complete months the projected completion is
+// taken from.
+const DefaultHistory = 3
+
+// Goal is an amount to save by a deadline, in THB. Savings are explicit
+// contributions and, when the goal has a monthly budget, what each
+// complete month since the start spent under it, counting all expenses or
+// those tagged BudgetTag or below, paid in THB.
+//
+// There is no budgets resource to refer to, so the budget is set on the
+// goal itself as monthly_budget.
+type Goal struct {
+	ID            int        `json:"id"`
+	Name          string     `json:"name"`
+	Target        float64    `json:"target"`
+	Deadline      *time.Time `json:"deadline"`
+	StartDate     *time.Time `json:"start_date"`
+	MonthlyBudget *float64   `json:"monthly_budget,omitempty"`
+	BudgetTag     string     `json:"budget_tag,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type Contribution struct {
+	ID            int        `json:"id"`
+	GoalID        int        `json:"goal_id"`
+	Amount        float64    `json:"amount"`
+	Note          string     `json:"note"`
+	ContributedAt *time.Time `json:"contributed_at"`
+}
+
+// Month is what was saved towards a goal in one month. Spent and
+// Underspent are only known for goals with a budget.
+type Month struct {
+	Month       string   `json:"month"`
+	Contributed float64  `json:"contributed"`
+	Spent       *float64 `json:"spent,omitempty"`
+	Underspent  float64  `json:"underspent"`
+	Saved       float64  `json:"saved"`
+}
+
+// Progress is how far a goal is as of a date. RequiredMonthly is what
+// is left spread over the months to the deadline, the current one
+// included, or all of it once the deadline month has passed. Projected
+// completion assumes saving goes on at the average of recent months and
+// is null when nothing was saved lately.
+type Progress struct {
+	Goal
+	AsOf                string  `json:"as_of"`
+	Contributed         float64 `json:"contributed"`
+	Underspent          float64 `json:"underspent"`
+	Saved               float64 `json:"saved"`
+	Remaining           float64 `json:"remaining"`
+	Percent             float64 `json:"percent"`
+	Completed           bool    `json:"completed"`
+	MonthsLeft          int     `json:"months_left"`
+	RequiredMonthly     float64 `json:"required_monthly"`
+	AverageMonthly      float64 `json:"average_monthly"`
+	ProjectedCompletion *string `json:"projected_completion"`
+	OnTrack             bool    `json:"on_track"`
+	History             []Month `json:"history"`
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthsBetween counts the months from the month of from to that of to,
+// both included, or 0 when to is in an earlier month.
+func monthsBetween(from, to time.Time) int {
+	n := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// validate trims the name and budget tag of g, checks its terms and fills
+// in the start date, today unless given.
+func validate(g *Goal, now time.Time) error {
+	g.Name = strings.TrimSpace(g.Name)
+	if g.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if g.Target <= 0 {
+		return fmt.Errorf("target must be positive")
+	}
+	if g.StartDate == nil {
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		g.StartDate = &day
+	}
+	if g.Deadline == nil {
+		return fmt.Errorf("deadline is required")
+	}
+	if g.Deadline.Before(*g.StartDate) {
+		return fmt.Errorf("deadline is before the start date")
+	}
+	if g.MonthlyBudget != nil && *g.MonthlyBudget <= 0 {
+		return fmt.Errorf("monthly_budget must be positive")
+	}
+	g.BudgetTag = tag.Clean(g.BudgetTag)
+	if g.BudgetTag != "" && g.MonthlyBudget == nil {
+		return fmt.Errorf("budget_tag needs a monthly_budget")
+	}
+	return nil
+}
+
+// progress works out where g stands as of asOf from its contributions and
+// the spending of each complete month since it started, keyed by month
+// start, and projects its completion from the last history months.
+func progress(g Goal, contributions []Contribution, spending map[time.Time]float64, asOf time.Time, history int) Progress {
+	p := Progress{Goal: g, AsOf: asOf.Format("2006-01-02"), History: []Month{}}
+	current := monthStart(asOf)
+	start := monthStart(*g.StartDate)
+	last := monthStart(*g.Deadline)
+
+	months := map[time.Time]*Month{}
+	var order []time.Time
+	for m := start; m.Before(current) && !m.After(last); m = m.AddDate(0, 1, 0) {
+		months[m] = &Month{Month: m.Format("2006-01")}
+		order = append(order, m)
+		if g.MonthlyBudget != nil {
+			spent := round(spending[m])
+			months[m].Spent = &spent
+			months[m].Underspent = round(math.Max(0, *g.MonthlyBudget-spent))
+			p.Underspent += months[m].Underspent
+		}
+	}
+	for _, c := range contributions {
+		p.Contributed += c.Amount
+		if m := months[monthStart(*c.ContributedAt)]; m != nil {
+			m.Contributed += c.Amount
+		}
+	}
+
+	p.Contributed, p.Underspent = round(p.Contributed), round(p.Underspent)
+	p.Saved = round(p.Contributed + p.Underspent)
+	p.Remaining = round(math.Max(0, g.Target-p.Saved))
+	p.Percent = round(math.Min(100, p.Saved/g.Target*100))
+	p.Completed = p.Remaining == 0
+
+	if len(order) > history {
+		order = order[len(order)-history:]
+	}
+	for _, m := range order {
+		month := months[m]
+		month.Contributed = round(month.Contributed)
+		month.Saved = round(month.Contributed + month.Underspent)
+		p.AverageMonthly += month.Saved
+		p.History = append(p.History, *month)
+	}
+	if len(order) > 0 {
+		p.AverageMonthly = round(p.AverageMonthly / float64(len(order)))
+	}
+
+	p.MonthsLeft = monthsBetween(current, *g.Deadline)
+	if p.Completed {
+		p.OnTrack = true
+		return p
+	}
+	p.RequiredMonthly = p.Remaining
+	if p.MonthsLeft > 0 {
+		p.RequiredMonthly = round(p.Remaining / float64(p.MonthsLeft))
+	}
+	if p.AverageMonthly > 0 {
+		n := int(math.Ceil(p.Remaining / p.AverageMonthly))
+		done := current.AddDate(0, n, -1)
+		projected := done.Format("2006-01-02")
+		p.ProjectedCompletion = &projected
+		p.OnTrack = !done.After(monthStart(*g.Deadline).AddDate(0, 1, -1))
+	}
+	return p
+}
@@ -0,0 +1,90 @@
+//go:build unit
+
+package goal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC)
+	budget := 10000.0
+
+	g := Goal{Name: " Japan trip ", Target: 30000, Deadline: date(2024, 12, 31), MonthlyBudget: &budget, BudgetTag: " Food "}
+	assert.NoError(t, validate(&g, now))
+	assert.Equal(t, "Japan trip", g.Name)
+	assert.Equal(t, "food", g.BudgetTag)
+	assert.Equal(t, date(2024, 1, 10), g.StartDate)
+
+	for _, g := range []Goal{
+		{Target: 30000, Deadline: date(2024, 12, 31)},
+		{Name: "trip", Deadline: date(2024, 12, 31)},
+		{Name: "trip", Target: 30000},
+		{Name: "trip", Target: 30000, Deadline: date(2023, 12, 31)},
+		{Name: "trip", Target: 30000, Deadline: date(2024, 12, 31), BudgetTag: "food"},
+	} {
+		g := g
+		assert.Error(t, validate(&g, now), g.Name)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	budget := 10000.0
+	g := Goal{ID: 1, Name: "Japan trip", Target: 30000, StartDate: date(2024, 1, 10), Deadline: date(2024, 12, 31), MonthlyBudget: &budget}
+	contributions := []Contribution{
+		{Amount: 2000, ContributedAt: date(2024, 2, 5)},
+		{Amount: 1000, ContributedAt: date(2024, 4, 20)},
+		{Amount: 500, ContributedAt: date(2024, 5, 2)},
+	}
+	spending := map[time.Time]float64{
+		*date(2024, 1, 1): 9000,
+		*date(2024, 2, 1): 11000,
+		*date(2024, 3, 1): 8000,
+		*date(2024, 4, 1): 7000,
+	}
+
+	t.Run("Savings Should Project Completion From Recent Months", func(t *testing.T) {
+		p := progress(g, contributions, spending, *date(2024, 5, 15), DefaultHistory)
+
+		assert.Equal(t, 3500.0, p.Contributed)
+		assert.Equal(t, 6000.0, p.Underspent)
+		assert.Equal(t, 9500.0, p.Saved)
+		assert.Equal(t, 20500.0, p.Remaining)
+		assert.Equal(t, 31.67, p.Percent)
+		assert.Equal(t, 8, p.MonthsLeft)
+		assert.Equal(t, 2562.5, p.RequiredMonthly)
+		assert.Equal(t, 2666.67, p.AverageMonthly)
+		if assert.NotNil(t, p.ProjectedCompletion) {
+			assert.Equal(t, "2024-12-31", *p.ProjectedCompletion)
+		}
+		assert.True(t, p.OnTrack)
+		assert.Equal(t, []string{"2024-02", "2024-03", "2024-04"}, []string{p.History[0].Month, p.History[1].Month, p.History[2].Month})
+		assert.Equal(t, 4000.0, p.History[2].Saved)
+	})
+
+	t.Run("Nothing Saved Lately Should Leave Projection Null", func(t *testing.T) {
+		p := progress(Goal{Target: 1000, StartDate: date(2024, 1, 1), Deadline: date(2024, 3, 31)}, nil, nil, *date(2024, 5, 1), DefaultHistory)
+
+		assert.Nil(t, p.ProjectedCompletion)
+		assert.False(t, p.OnTrack)
+		assert.Equal(t, 0, p.MonthsLeft)
+		assert.Equal(t, 1000.0, p.RequiredMonthly)
+	})
+
+	t.Run("Reached Target Should Be Completed", func(t *testing.T) {
+		p := progress(Goal{Target: 1000, StartDate: date(2024, 1, 1), Deadline: date(2024, 3, 31)}, []Contribution{{Amount: 1200, ContributedAt: date(2024, 1, 5)}}, nil, *date(2024, 2, 1), DefaultHistory)
+
+		assert.True(t, p.Completed)
+		assert.Equal(t, 100.0, p.Percent)
+		assert.Equal(t, 0.0, p.RequiredMonthly)
+		assert.True(t, p.OnTrack)
+	})
+}
@@ -0,0 +1,235 @@
+package goal
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/account"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/tag"
+)
+
+const columns = "id, name, target, deadline, start_date, monthly_budget, budget_tag, created_at"
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+func scanGoal(row interface{ Scan(...interface{}) error }, g *Goal) error {
+	return row.Scan(&g.ID, &g.Name, &g.Target, &g.Deadline, &g.StartDate, &g.MonthlyBudget, &g.BudgetTag, &g.CreatedAt)
+}
+
+func (h *handler) find(c *gin.Context) (Goal, bool) {
+	var g Goal
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return g, false
+	}
+
+	row := h.DB.QueryRow("SELECT "+columns+" FROM goals WHERE id = $1", id)
+	if err := scanGoal(row, &g); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"})
+		return g, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return g, false
+	}
+	return g, true
+}
+
+func (h *handler) Create(c *gin.Context) {
+	var g Goal
+	if err := c.BindJSON(&g); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validate(&g, time.Now().UTC()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	row := h.DB.QueryRow(`
+		INSERT INTO goals(name, target, deadline, start_date, monthly_budget, budget_tag)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+		`,
+		g.Name, g.Target, g.Deadline, g.StartDate, g.MonthlyBudget, g.BudgetTag)
+	if err := row.Scan(&g.ID, &g.CreatedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, g)
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT " + columns + " FROM goals ORDER BY deadline, id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	goals := []Goal{}
+	for rows.Next() {
+		var g Goal
+		if err := scanGoal(rows, &g); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		goals = append(goals, g)
+	}
+
+	c.JSON(http.StatusOK, goals)
+}
+
+// contributions loads the contributions to a goal made before end.
+func (h *handler) contributions(id int, end time.Time) ([]Contribution, error) {
+	rows, err := h.DB.Query("SELECT id, goal_id, amount, note, contributed_at FROM goal_contributions WHERE goal_id = $1 AND contributed_at < $2 ORDER BY contributed_at, id", id, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contributions []Contribution
+	for rows.Next() {
+		var c Contribution
+		if err := rows.Scan(&c.ID, &c.GoalID, &c.Amount, &c.Note, &c.ContributedAt); err != nil {
+			return nil, err
+		}
+		contributions = append(contributions, c)
+	}
+	return contributions, rows.Err()
+}
+
+// spending totals the expenses counted against the budget of g per month,
+// from its start up to end. Only expenses in the base currency count, as
+// the budget is in it; those paid from foreign currency accounts are left
+// out rather than added up as if they were baht.
+func (h *handler) spending(g Goal, end time.Time) (map[time.Time]float64, error) {
+	stmt := `
+		SELECT date_trunc('month', e.spent_at), SUM(e.amount)
+		FROM expenses e LEFT JOIN accounts a ON a.id = e.account_id
+		WHERE e.kind = $1 AND e.spent_at >= $2 AND e.spent_at < $3 AND COALESCE(a.currency, $4) = $4`
+	args := []interface{}{expense.KindExpense, monthStart(*g.StartDate), end, account.DefaultCurrency}
+	if g.BudgetTag != "" {
+		stmt += " AND EXISTS (SELECT 1 FROM unnest(e.tags) t WHERE t = $5 OR t LIKE $6)"
+		args = append(args, g.BudgetTag, tag.DescendantPattern(g.BudgetTag))
+	}
+	rows, err := h.DB.Query(stmt+" GROUP BY 1", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spending := map[time.Time]float64{}
+	for rows.Next() {
+		var month time.Time
+		var amount float64
+		if err := rows.Scan(&month, &amount); err != nil {
+			return nil, err
+		}
+		spending[monthStart(month)] = amount
+	}
+	return spending, rows.Err()
+}
+
+// Get shows a goal with its progress as of as_of, today by default,
+// projected from the last history complete months.
+func (h *handler) Get(c *gin.Context) {
+	g, ok := h.find(c)
+	if !ok {
+		return
+	}
+	asOf := time.Now().UTC()
+	if v := c.Query("as_of"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid as_of %q", v)})
+			return
+		}
+		asOf = t
+	}
+	history := DefaultHistory
+	if v := c.Query("history"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid history %q", v)})
+			return
+		}
+		history = n
+	}
+
+	day := time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+	contributions, err := h.contributions(g.ID, day.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var spending map[time.Time]float64
+	if g.MonthlyBudget != nil {
+		if spending, err = h.spending(g, monthStart(asOf)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, progress(g, contributions, spending, asOf, history))
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	g, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM goals WHERE id = $1", g.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Contribute records money put towards a goal, now unless contributed_at
+// is given.
+func (h *handler) Contribute(c *gin.Context) {
+	g, ok := h.find(c)
+	if !ok {
+		return
+	}
+	var contribution Contribution
+	if err := c.BindJSON(&contribution); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if contribution.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive"})
+		return
+	}
+	contribution.GoalID = g.ID
+
+	row := h.DB.QueryRow(`
+		INSERT INTO goal_contributions(goal_id, amount, note, contributed_at)
+		VALUES ($1, $2, $3, COALESCE($4, now()))
+		RETURNING id, contributed_at
+		`,
+		contribution.GoalID, contribution.Amount, contribution.Note, contribution.ContributedAt)
+	if err := row.Scan(&contribution.ID, &contribution.ContributedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, contribution)
+}
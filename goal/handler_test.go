@@ -0,0 +1,146 @@
+//go:build unit
+
+package goal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var goalColumns = []string{"id", "name", "target", "deadline", "start_date", "monthly_budget", "budget_tag", "created_at"}
+
+func TestCreateGoal(t *testing.T) {
+	t.Run("Create Goal Without Deadline Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/goals", strings.NewReader(`{"name": "Japan trip", "target": 30000}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/goals", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"error":"deadline is required"}`, strings.TrimSpace(rec.Body.String()))
+	})
+
+	t.Run("Create Goal Should Return Created", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/goals", strings.NewReader(`{"name": "Japan trip", "target": 30000, "start_date": "2024-01-10T00:00:00Z", "deadline": "2024-12-31T00:00:00Z", "monthly_budget": 10000, "budget_tag": "food"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		budget := 10000.0
+		mock.ExpectQuery("INSERT INTO goals").
+			WithArgs("Japan trip", 30000.0, date(2024, 12, 31), date(2024, 1, 10), &budget, "food").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, *date(2024, 1, 10)))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/goals", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+}
+
+func TestGetGoal(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/goals/1?as_of=2024-03-15&history=2", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM goals WHERE id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(goalColumns).AddRow(1, "Japan trip", 30000.0, *date(2024, 12, 31), *date(2024, 1, 10), 10000.0, "food", *date(2024, 1, 10)))
+	mock.ExpectQuery("SELECT (.+) FROM goal_contributions WHERE goal_id").
+		WithArgs(1, *date(2024, 3, 16)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "goal_id", "amount", "note", "contributed_at"}).
+			AddRow(1, 1, 2000.0, "bonus", *date(2024, 2, 5)))
+	mock.ExpectQuery("SELECT date_trunc(.+) FROM expenses e LEFT JOIN accounts a (.+) COALESCE\\(a.currency, \\$4\\) = \\$4 AND EXISTS").
+		WithArgs("expense", *date(2024, 1, 1), *date(2024, 3, 1), "THB", "food", "food/%").
+		WillReturnRows(sqlmock.NewRows([]string{"month", "sum"}).
+			AddRow(*date(2024, 1, 1), 9000.0).
+			AddRow(*date(2024, 2, 1), 8000.0))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.GET("/goals/:id", h.Get)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"as_of":"2024-03-15","contributed":2000,"underspent":3000,"saved":5000,"remaining":25000,"percent":16.67,"completed":false,"months_left":10,"required_monthly":2500,"average_monthly":2500,"projected_completion":"2024-12-31","on_track":true`)
+	assert.Contains(t, rec.Body.String(), `"history":[{"month":"2024-01","contributed":0,"spent":9000,"underspent":1000,"saved":1000},{"month":"2024-02","contributed":2000,"spent":8000,"underspent":2000,"saved":4000}]`)
+}
+
+func TestContribute(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodPost, "/goals/1/contributions", strings.NewReader(`{"amount": 1500, "note": "bonus"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM goals WHERE id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(goalColumns).AddRow(1, "Japan trip", 30000.0, *date(2024, 12, 31), *date(2024, 1, 10), nil, "", *date(2024, 1, 10)))
+	mock.ExpectQuery("INSERT INTO goal_contributions").
+		WithArgs(1, 1500.0, "bonus", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "contributed_at"}).AddRow(4, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.POST("/goals/:id/contributions", h.Contribute)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{"id":4,"goal_id":1,"amount":1500,"note":"bonus","contributed_at":"2024-03-01T09:00:00Z"}`, strings.TrimSpace(rec.Body.String()))
+}
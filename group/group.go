@@ -0,0 +1,64 @@
+package group
+
+import "github.com/jsritawan/assessment/expense"
+
+const (
+	SplitEqual   = "equal"
+	SplitExact   = "exact"
+	SplitPercent = "percent"
+	SplitShares  = "shares"
+)
+
+type Group struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Members []Member `json:"members"`
+}
+
+type Member struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Participant struct {
+	MemberID int     `json:"member_id"`
+	Value    float64 `json:"value,omitempty"`
+	Amount   float64 `json:"amount"`
+}
+
+type Split struct {
+	Type         string        `json:"type"`
+	Participants []Participant `json:"participants"`
+}
+
+type GroupExpense struct {
+	expense.Expense
+	PayerID int   `json:"payer_id"`
+	Split   Split `json:"split"`
+}
+
+type Settlement struct {
+	ID           int     `json:"id"`
+	FromMemberID int     `json:"from_member_id"`
+	ToMemberID   int     `json:"to_member_id"`
+	Amount       float64 `json:"amount"`
+}
+
+type Balance struct {
+	MemberID int     `json:"member_id"`
+	Name     string  `json:"name"`
+	Balance  float64 `json:"balance"`
+}
+
+type Transfer struct {
+	FromMemberID int     `json:"from_member_id"`
+	ToMemberID   int     `json:"to_member_id"`
+	Amount       float64 `json:"amount"`
+}
+
+type Balances struct {
+	Balances []Balance `json:"balances"`
+	// SettleUp is a set of transfers that clears every balance, at most one
+	// fewer than the members with a balance. It is not always the fewest.
+	SettleUp []Transfer `json:"settle_up"`
+}
@@ -0,0 +1,311 @@
+package group
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/ledger"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+func (h *handler) members(groupID int) ([]Member, error) {
+	rows, err := h.DB.Query("SELECT id, name FROM group_members WHERE group_id = $1 ORDER BY id", groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ID, &m.Name); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (h *handler) Create(c *gin.Context) {
+	var group Group
+	if err := c.BindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow("INSERT INTO expense_groups(name) VALUES ($1) RETURNING id", group.Name)
+	if err := row.Scan(&group.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range group.Members {
+		row := tx.QueryRow("INSERT INTO group_members(group_id, name) VALUES ($1, $2) RETURNING id", group.ID, group.Members[i].Name)
+		if err := row.Scan(&group.Members[i].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var group Group
+	row := h.DB.QueryRow("SELECT id, name FROM expense_groups WHERE id = $1", id)
+	if err := row.Scan(&group.ID, &group.Name); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	group.Members, err = h.members(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func (h *handler) CreateExpense(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var e GroupExpense
+	if err := c.BindJSON(&e); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// shares are split from the gross
+	if err := expense.ApplyTax(&e.Expense); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	members, err := h.members(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	inGroup := map[int]bool{}
+	for _, m := range members {
+		inGroup[m.ID] = true
+	}
+	if !inGroup[e.PayerID] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payer is not a member of the group"})
+		return
+	}
+	for _, p := range e.Split.Participants {
+		if !inGroup[p.MemberID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "participant is not a member of the group"})
+			return
+		}
+	}
+
+	e.Split.Participants, err = computeShares(e.Amount, e.Split)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := expense.Prepare(h.DB, &e.Expense); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := expense.Insert(tx, expense.KindExpense, &e.Expense); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ledger.SyncExpenses(tx, []int{e.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, p := range e.Split.Participants {
+		_, err := tx.Exec(`
+			INSERT INTO expense_shares(expense_id, group_id, payer_id, member_id, split_type, value, amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`,
+			e.ID, id, e.PayerID, p.MemberID, e.Split.Type, p.Value, p.Amount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, e)
+}
+
+func (h *handler) CreateSettlement(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var settlement Settlement
+	if err := c.BindJSON(&settlement); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if settlement.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be greater than zero"})
+		return
+	}
+	if settlement.FromMemberID == settlement.ToMemberID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot settle with yourself"})
+		return
+	}
+
+	members, err := h.members(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	inGroup := map[int]bool{}
+	for _, m := range members {
+		inGroup[m.ID] = true
+	}
+	if !inGroup[settlement.FromMemberID] || !inGroup[settlement.ToMemberID] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "member is not in the group"})
+		return
+	}
+
+	row := h.DB.QueryRow(`
+		INSERT INTO settlements(group_id, from_member_id, to_member_id, amount)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+		`,
+		id, settlement.FromMemberID, settlement.ToMemberID, settlement.Amount)
+	if err := row.Scan(&settlement.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, settlement)
+}
+
+// GetBalances returns what each member of a group is owed, negative when
+// they owe, and a suggested set of transfers that settles the group. The
+// transfers are worked out greedily, so they clear every balance but are
+// not guaranteed to be the fewest possible.
+func (h *handler) GetBalances(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var exists bool
+	if err := h.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM expense_groups WHERE id = $1)", id).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+		return
+	}
+
+	members, err := h.members(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := h.DB.Query("SELECT payer_id, member_id, amount FROM expense_shares WHERE group_id = $1", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var shares []share
+	for rows.Next() {
+		var s share
+		if err := rows.Scan(&s.PayerID, &s.MemberID, &s.Amount); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		shares = append(shares, s)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err = h.DB.Query("SELECT id, from_member_id, to_member_id, amount FROM settlements WHERE group_id = $1", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var settlements []Settlement
+	for rows.Next() {
+		var s Settlement
+		if err := rows.Scan(&s.ID, &s.FromMemberID, &s.ToMemberID, &s.Amount); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		settlements = append(settlements, s)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	balances := computeBalances(members, shares, settlements)
+	c.JSON(http.StatusOK, Balances{
+		Balances: balances,
+		SettleUp: settleUp(balances),
+	})
+}
@@ -0,0 +1,208 @@
+//go:build unit
+
+package group
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateGroupExpense(t *testing.T) {
+	t.Run("Create Group Expense With Payer Outside Group Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		body := `{"title": "lunch", "amount": 300, "payer_id": 9, "split": {"type": "equal", "participants": [{"member_id": 1}]}}`
+		req := httptest.NewRequest(http.MethodPost, "/groups/1/expenses", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT id, name FROM group_members").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "ann"))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/groups/:id/expenses", h.CreateExpense)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Create Group Expense With Invalid Tax Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		body := `{"title": "lunch", "amount": 300, "payer_id": 1, "tax": {"vat_mode": "zero"}, "split": {"type": "equal", "participants": [{"member_id": 1}]}}`
+		req := httptest.NewRequest(http.MethodPost, "/groups/1/expenses", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/groups/:id/expenses", h.CreateExpense)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Create Group Expense Should Return Created", func(t *testing.T) {
+		// Arrange
+		body := `{"title": "lunch", "amount": 300, "payer_id": 1, "split": {"type": "equal", "participants": [{"member_id": 1}, {"member_id": 2}]}}`
+		req := httptest.NewRequest(http.MethodPost, "/groups/1/expenses", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		spentAt := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT id, name FROM group_members").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "ann").AddRow(2, "bob"))
+		mock.ExpectQuery("SELECT (.+) FROM rules").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "enabled", "conditions", "actions"}))
+		mock.ExpectQuery("SELECT (.+) FROM merchants").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "aliases"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO expenses(.+)kind").
+			WithArgs(sqlmock.AnyArg(), 300.0, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), expense.KindExpense, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at"}).AddRow(7, spentAt))
+		mock.ExpectExec("DELETE FROM journal_entries").
+			WithArgs(pq.Array([]int{7})).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT (.+) FROM expenses e LEFT JOIN accounts a").
+			WithArgs(pq.Array([]int{7})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "kind", "tags", "category", "spent_at", "account_id", "name", "type"}).
+				AddRow(7, "lunch", 300.0, "expense", pq.Array([]string{}), "", spentAt, nil, nil, nil))
+		mock.ExpectQuery("INSERT INTO journal_entries").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(10, "Expenses:Uncategorized", nil, 300.0).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(10, "Assets:Unassigned", nil, -300.0).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec("INSERT INTO expense_shares").
+			WithArgs(7, 1, 1, 1, "equal", 0.0, 150.0).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO expense_shares").
+			WithArgs(7, 1, 1, 2, "equal", 0.0, 150.0).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/groups/:id/expenses", h.CreateExpense)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+}
+
+func TestGetBalances(t *testing.T) {
+	t.Run("Get Balances Of Unknown Group Should Return Not Found", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/groups/9/balances", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT EXISTS (.+) FROM expense_groups").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/groups/:id/balances", h.GetBalances)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Get Balances Should Return Settle Up", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/groups/1/balances", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT EXISTS (.+) FROM expense_groups").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("SELECT id, name FROM group_members").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "ann").AddRow(2, "bob"))
+		mock.ExpectQuery("SELECT (.+) FROM expense_shares").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"payer_id", "member_id", "amount"}).
+				AddRow(1, 1, 150.0).
+				AddRow(1, 2, 150.0))
+		mock.ExpectQuery("SELECT (.+) FROM settlements").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "from_member_id", "to_member_id", "amount"}).
+				AddRow(1, 2, 1, 50.0))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/groups/:id/balances", h.GetBalances)
+		expect := `{"balances":[{"member_id":1,"name":"ann","balance":100},{"member_id":2,"name":"bob","balance":-100}],"settle_up":[{"from_member_id":2,"to_member_id":1,"amount":100}]}`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
@@ -0,0 +1,212 @@
+package group
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+type share struct {
+	PayerID  int
+	MemberID int
+	Amount   float64
+}
+
+func toCents(v float64) int64 {
+	return int64(math.Round(v * 100))
+}
+
+func fromCents(c int64) float64 {
+	return float64(c) / 100
+}
+
+// allocate splits total cents by weight using the largest remainder method,
+// so the parts always add back up to exactly total.
+func allocate(total int64, weights []float64) []int64 {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+
+	type remainder struct {
+		index int
+		value float64
+	}
+	parts := make([]int64, len(weights))
+	remainders := make([]remainder, len(weights))
+	var given int64
+	for i, w := range weights {
+		exact := float64(total) * w / sum
+		parts[i] = int64(math.Floor(exact))
+		given += parts[i]
+		remainders[i] = remainder{index: i, value: exact - float64(parts[i])}
+	}
+
+	sort.SliceStable(remainders, func(a, b int) bool {
+		return remainders[a].value > remainders[b].value
+	})
+	for k := int64(0); k < total-given; k++ {
+		parts[remainders[k].index]++
+	}
+	return parts
+}
+
+// computeShares fills in the amount owed by each participant of split.
+func computeShares(amount float64, split Split) ([]Participant, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	if len(split.Participants) == 0 {
+		return nil, errors.New("split needs at least one participant")
+	}
+
+	seen := map[int]bool{}
+	for _, p := range split.Participants {
+		if seen[p.MemberID] {
+			return nil, fmt.Errorf("member %d listed more than once", p.MemberID)
+		}
+		seen[p.MemberID] = true
+	}
+
+	total := toCents(amount)
+	weights := make([]float64, len(split.Participants))
+	var parts []int64
+
+	switch split.Type {
+	case SplitEqual:
+		for i := range weights {
+			weights[i] = 1
+		}
+		parts = allocate(total, weights)
+	case SplitExact:
+		parts = make([]int64, len(split.Participants))
+		var sum int64
+		for i, p := range split.Participants {
+			if p.Value < 0 {
+				return nil, errors.New("exact amounts must not be negative")
+			}
+			parts[i] = toCents(p.Value)
+			sum += parts[i]
+		}
+		if sum != total {
+			return nil, fmt.Errorf("exact amounts add up to %.2f, expected %.2f", fromCents(sum), amount)
+		}
+	case SplitPercent:
+		var sum float64
+		for i, p := range split.Participants {
+			if p.Value < 0 {
+				return nil, errors.New("percentages must not be negative")
+			}
+			weights[i] = p.Value
+			sum += p.Value
+		}
+		if math.Abs(sum-100) > 0.0001 {
+			return nil, fmt.Errorf("percentages add up to %g, expected 100", sum)
+		}
+		parts = allocate(total, weights)
+	case SplitShares:
+		var sum float64
+		for i, p := range split.Participants {
+			if p.Value < 0 {
+				return nil, errors.New("shares must not be negative")
+			}
+			weights[i] = p.Value
+			sum += p.Value
+		}
+		if sum <= 0 {
+			return nil, errors.New("shares must add up to more than zero")
+		}
+		parts = allocate(total, weights)
+	default:
+		return nil, fmt.Errorf("unknown split type %q", split.Type)
+	}
+
+	participants := make([]Participant, len(split.Participants))
+	for i, p := range split.Participants {
+		participants[i] = Participant{MemberID: p.MemberID, Value: p.Value, Amount: fromCents(parts[i])}
+	}
+	return participants, nil
+}
+
+// computeBalances returns how much each member is owed (positive) or owes
+// (negative) after all shares and settlements in the group.
+func computeBalances(members []Member, shares []share, settlements []Settlement) []Balance {
+	cents := map[int]int64{}
+	for _, s := range shares {
+		if s.PayerID == s.MemberID {
+			continue
+		}
+		c := toCents(s.Amount)
+		cents[s.PayerID] += c
+		cents[s.MemberID] -= c
+	}
+	for _, s := range settlements {
+		c := toCents(s.Amount)
+		cents[s.FromMemberID] += c
+		cents[s.ToMemberID] -= c
+	}
+
+	balances := make([]Balance, len(members))
+	for i, m := range members {
+		balances[i] = Balance{MemberID: m.ID, Name: m.Name, Balance: fromCents(cents[m.ID])}
+	}
+	return balances
+}
+
+// settleUp pairs the largest debtor with the largest creditor until every
+// balance is cleared. Each step clears at least one member, so the plan never
+// needs more than n-1 transfers. It is a heuristic and can miss the fewest:
+// balances of -9, -8, -8, +9 and +16 settle in four transfers here, where
+// -9 paying +9 and both -8 paying +16 takes three. Finding the minimum
+// means searching for subsets that cancel out, which grows exponentially.
+func settleUp(balances []Balance) []Transfer {
+	type entry struct {
+		memberID int
+		cents    int64
+	}
+	var creditors, debtors []entry
+	for _, b := range balances {
+		c := toCents(b.Balance)
+		if c > 0 {
+			creditors = append(creditors, entry{b.MemberID, c})
+		} else if c < 0 {
+			debtors = append(debtors, entry{b.MemberID, -c})
+		}
+	}
+
+	byLargest := func(entries []entry) func(a, b int) bool {
+		return func(a, b int) bool {
+			if entries[a].cents != entries[b].cents {
+				return entries[a].cents > entries[b].cents
+			}
+			return entries[a].memberID < entries[b].memberID
+		}
+	}
+
+	transfers := []Transfer{}
+	for len(creditors) > 0 && len(debtors) > 0 {
+		sort.Slice(creditors, byLargest(creditors))
+		sort.Slice(debtors, byLargest(debtors))
+
+		amount := creditors[0].cents
+		if debtors[0].cents < amount {
+			amount = debtors[0].cents
+		}
+		transfers = append(transfers, Transfer{
+			FromMemberID: debtors[0].memberID,
+			ToMemberID:   creditors[0].memberID,
+			Amount:       fromCents(amount),
+		})
+
+		creditors[0].cents -= amount
+		debtors[0].cents -= amount
+		if creditors[0].cents == 0 {
+			creditors = creditors[1:]
+		}
+		if debtors[0].cents == 0 {
+			debtors = debtors[1:]
+		}
+	}
+	return transfers
+}
@@ -0,0 +1,114 @@
+//go:build unit
+
+package group
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeShares(t *testing.T) {
+	t.Run("Equal Split Should Give Remainder Cents To First Participants", func(t *testing.T) {
+		split := Split{Type: SplitEqual, Participants: []Participant{{MemberID: 1}, {MemberID: 2}, {MemberID: 3}}}
+
+		shares, err := computeShares(100, split)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, []float64{33.34, 33.33, 33.33}, amounts(shares))
+		}
+	})
+
+	t.Run("Exact Split Not Matching Amount Should Return Error", func(t *testing.T) {
+		split := Split{Type: SplitExact, Participants: []Participant{{MemberID: 1, Value: 40}, {MemberID: 2, Value: 50}}}
+
+		_, err := computeShares(100, split)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Percent Split Should Follow Percentages", func(t *testing.T) {
+		split := Split{Type: SplitPercent, Participants: []Participant{{MemberID: 1, Value: 70}, {MemberID: 2, Value: 30}}}
+
+		shares, err := computeShares(79, split)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, []float64{55.3, 23.7}, amounts(shares))
+		}
+	})
+
+	t.Run("Percent Split Not Adding To 100 Should Return Error", func(t *testing.T) {
+		split := Split{Type: SplitPercent, Participants: []Participant{{MemberID: 1, Value: 70}, {MemberID: 2, Value: 20}}}
+
+		_, err := computeShares(79, split)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Shares Split Should Follow Weights", func(t *testing.T) {
+		split := Split{Type: SplitShares, Participants: []Participant{{MemberID: 1, Value: 2}, {MemberID: 2, Value: 1}, {MemberID: 3, Value: 1}}}
+
+		shares, err := computeShares(300, split)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, []float64{150, 75, 75}, amounts(shares))
+		}
+	})
+
+	t.Run("Duplicate Participant Should Return Error", func(t *testing.T) {
+		split := Split{Type: SplitEqual, Participants: []Participant{{MemberID: 1}, {MemberID: 1}}}
+
+		_, err := computeShares(100, split)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestSettleUp(t *testing.T) {
+	t.Run("Settle Up Should Clear All Balances", func(t *testing.T) {
+		balances := []Balance{
+			{MemberID: 1, Balance: 60},
+			{MemberID: 2, Balance: -30},
+			{MemberID: 3, Balance: -20},
+			{MemberID: 4, Balance: -10},
+		}
+
+		transfers := settleUp(balances)
+
+		assert.Equal(t, []Transfer{
+			{FromMemberID: 2, ToMemberID: 1, Amount: 30},
+			{FromMemberID: 3, ToMemberID: 1, Amount: 20},
+			{FromMemberID: 4, ToMemberID: 1, Amount: 10},
+		}, transfers)
+	})
+
+	t.Run("Settled Group Should Need No Transfers", func(t *testing.T) {
+		balances := []Balance{{MemberID: 1}, {MemberID: 2}}
+
+		assert.Empty(t, settleUp(balances))
+	})
+}
+
+func TestComputeBalances(t *testing.T) {
+	members := []Member{{ID: 1, Name: "ann"}, {ID: 2, Name: "bob"}}
+	shares := []share{
+		{PayerID: 1, MemberID: 1, Amount: 50},
+		{PayerID: 1, MemberID: 2, Amount: 50},
+	}
+	settlements := []Settlement{{FromMemberID: 2, ToMemberID: 1, Amount: 20}}
+
+	balances := computeBalances(members, shares, settlements)
+
+	assert.Equal(t, []Balance{
+		{MemberID: 1, Name: "ann", Balance: 30},
+		{MemberID: 2, Name: "bob", Balance: -30},
+	}, balances)
+}
+
+func amounts(participants []Participant) []float64 {
+	var result []float64
+	for _, p := range participants {
+		result = append(result, p.Amount)
+	}
+	return result
+}
@@ -0,0 +1,189 @@
+package insight
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jsritawan/assessment/expense"
+)
+
+const (
+	KindPeriod  = "period"
+	KindExpense = "expense"
+)
+
+// AnomalyConfig controls how baselines are built and how far from them
+// spending has to be before it is reported.
+type AnomalyConfig struct {
+	Period Period `json:"period"`
+	// Periods is how many periods before the one examined make up its
+	// baseline.
+	Periods int `json:"periods"`
+	// Recent is how many periods, ending with the current one, are
+	// examined.
+	Recent int `json:"recent"`
+	// Threshold is the robust z-score beyond which spending is reported.
+	Threshold float64 `json:"threshold"`
+	// MinSamples is the fewest expenses a tag needs in its baseline before
+	// single expenses are judged against it.
+	MinSamples int `json:"min_samples"`
+}
+
+var DefaultAnomalyConfig = AnomalyConfig{
+	Period:     Month,
+	Periods:    6,
+	Recent:     1,
+	Threshold:  3.5,
+	MinSamples: 5,
+}
+
+type Baseline struct {
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Samples     int     `json:"samples"`
+	Median      float64 `json:"median"`
+	MAD         float64 `json:"mad"`
+	Explanation string  `json:"explanation"`
+}
+
+type Anomaly struct {
+	Kind      string           `json:"kind"`
+	Tag       string           `json:"tag"`
+	Period    string           `json:"period"`
+	Amount    float64          `json:"amount"`
+	Score     float64          `json:"score"`
+	Direction string           `json:"direction"`
+	Expense   *expense.Expense `json:"expense,omitempty"`
+	Baseline  Baseline         `json:"baseline"`
+}
+
+type Anomalies struct {
+	AnomalyConfig
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Anomalies []Anomaly `json:"anomalies"`
+}
+
+// window returns the start of the first baseline period and the end of
+// the last examined one for the period containing now.
+func (cfg AnomalyConfig) window(now time.Time) (time.Time, time.Time) {
+	current := cfg.Period.start(now)
+	first := cfg.Period.add(current, 1-cfg.Recent)
+	return cfg.Period.add(first, -cfg.Periods), cfg.Period.add(current, 1)
+}
+
+// tagged holds the expenses of one tag bucketed by period index, counted
+// from the start of the window.
+type tagged map[string]map[int][]expense.Expense
+
+func (cfg AnomalyConfig) bucket(expenses []expense.Expense, from time.Time) tagged {
+	buckets := tagged{}
+	for _, e := range expenses {
+		if e.SpentAt == nil {
+			continue
+		}
+		start := cfg.Period.start(*e.SpentAt)
+		i := 0
+		for p := from; p.Before(start); p = cfg.Period.add(p, 1) {
+			i++
+		}
+		for _, t := range e.Tags {
+			if buckets[t] == nil {
+				buckets[t] = map[int][]expense.Expense{}
+			}
+			buckets[t][i] = append(buckets[t][i], e)
+		}
+	}
+	return buckets
+}
+
+func total(expenses []expense.Expense) float64 {
+	var sum float64
+	for _, e := range expenses {
+		sum += e.Amount
+	}
+	return math.Round(sum*100) / 100
+}
+
+func (cfg AnomalyConfig) baseline(values []float64, from, to time.Time, what string) (Baseline, float64) {
+	med := median(values)
+	b := Baseline{
+		From:    cfg.Period.label(from),
+		To:      cfg.Period.label(to),
+		Samples: len(values),
+		Median:  math.Round(med*100) / 100,
+		MAD:     math.Round(mad(values, med)*100) / 100,
+	}
+	b.Explanation = fmt.Sprintf("median %s of %.2f with a median absolute deviation of %.2f over %d %ss from %s to %s",
+		what, b.Median, b.MAD, cfg.Periods, cfg.Period, b.From, b.To)
+	return b, meanAD(values, med)
+}
+
+func (cfg AnomalyConfig) judge(a *Anomaly, values []float64, meanAD float64) bool {
+	med := median(values)
+	score, ok := robustScore(a.Amount, med, mad(values, med), meanAD)
+	if !ok || math.Abs(score) <= cfg.Threshold {
+		return false
+	}
+	a.Score = math.Round(score*100) / 100
+	a.Direction = "above"
+	if score < 0 {
+		a.Direction = "below"
+	}
+	return true
+}
+
+// detect judges each examined period's per-tag total against the totals
+// of the periods before it, and each expense against the single expenses
+// of the same tag in those periods. Periods without spending count as
+// zero in the totals baseline.
+func (cfg AnomalyConfig) detect(expenses []expense.Expense, from time.Time) []Anomaly {
+	anomalies := []Anomaly{}
+	for t, periods := range cfg.bucket(expenses, from) {
+		for r := 0; r < cfg.Recent; r++ {
+			examined := cfg.Periods + r
+			start := cfg.Period.add(from, examined)
+			first, last := cfg.Period.add(from, r), cfg.Period.add(from, examined-1)
+
+			var totals, singles []float64
+			for i := r; i < examined; i++ {
+				totals = append(totals, total(periods[i]))
+				for _, e := range periods[i] {
+					singles = append(singles, e.Amount)
+				}
+			}
+
+			a := Anomaly{Kind: KindPeriod, Tag: t, Period: cfg.Period.label(start), Amount: total(periods[examined])}
+			var spread float64
+			a.Baseline, spread = cfg.baseline(totals, first, last, "total per "+string(cfg.Period))
+			if cfg.judge(&a, totals, spread) {
+				anomalies = append(anomalies, a)
+			}
+
+			if len(singles) < cfg.MinSamples {
+				continue
+			}
+			b, spread := cfg.baseline(singles, first, last, "expense")
+			for _, e := range periods[examined] {
+				e := e
+				a := Anomaly{Kind: KindExpense, Tag: t, Period: cfg.Period.label(start), Amount: e.Amount, Expense: &e, Baseline: b}
+				if cfg.judge(&a, singles, spread) {
+					anomalies = append(anomalies, a)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		if si, sj := math.Abs(anomalies[i].Score), math.Abs(anomalies[j].Score); si != sj {
+			return si > sj
+		}
+		if anomalies[i].Tag != anomalies[j].Tag {
+			return anomalies[i].Tag < anomalies[j].Tag
+		}
+		return anomalies[i].Kind > anomalies[j].Kind
+	})
+	return anomalies
+}
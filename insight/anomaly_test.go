@@ -0,0 +1,79 @@
+//go:build unit
+
+package insight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jsritawan/assessment/expense"
+	"github.com/stretchr/testify/assert"
+)
+
+func spent(year int, month time.Month, day int, title string, amount float64, tags ...string) expense.Expense {
+	t := time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	return expense.Expense{Title: title, Amount: amount, Tags: tags, SpentAt: &t}
+}
+
+func TestRobustScore(t *testing.T) {
+	values := []float64{100, 110, 90, 105, 95}
+	med := median(values)
+	assert.Equal(t, 100.0, med)
+	assert.Equal(t, 5.0, mad(values, med))
+
+	score, ok := robustScore(130, med, mad(values, med), meanAD(values, med))
+	assert.True(t, ok)
+	assert.InDelta(t, 4.047, score, 0.001)
+
+	_, ok = robustScore(130, 100, 0, 0)
+	assert.False(t, ok)
+}
+
+func TestPeriod(t *testing.T) {
+	day := time.Date(2024, 6, 13, 18, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Month.start(day))
+	assert.Equal(t, time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC), Week.start(day))
+	assert.Equal(t, "2024-06", Month.label(Month.start(day)))
+	assert.Equal(t, "2024-W24", Week.label(Week.start(day)))
+	assert.Equal(t, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), Month.add(Month.start(day), -6))
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	cfg := DefaultAnomalyConfig
+	from, _ := cfg.window(time.Date(2024, 7, 20, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), from)
+
+	var expenses []expense.Expense
+	for m, amount := range []float64{100, 110, 90, 105, 95, 100} {
+		expenses = append(expenses,
+			spent(2024, time.Month(m+1), 3, "coffee", amount, "food"),
+			spent(2024, time.Month(m+1), 9, "lunch", amount, "food"),
+			spent(2024, time.Month(m+1), 9, "bus", 30, "transport"))
+	}
+	expenses = append(expenses,
+		spent(2024, time.July, 2, "coffee", 100, "food"),
+		spent(2024, time.July, 4, "tasting menu", 900, "food"),
+		spent(2024, time.July, 5, "bus", 30, "transport"))
+
+	anomalies := cfg.detect(expenses, from)
+
+	if assert.Len(t, anomalies, 2) {
+		assert.Equal(t, KindExpense, anomalies[0].Kind)
+		assert.Equal(t, "tasting menu", anomalies[0].Expense.Title)
+		assert.Equal(t, "above", anomalies[0].Direction)
+		assert.Equal(t, "2024-07", anomalies[0].Period)
+
+		assert.Equal(t, KindPeriod, anomalies[1].Kind)
+		assert.Equal(t, "food", anomalies[1].Tag)
+		assert.Equal(t, 1000.0, anomalies[1].Amount)
+		assert.Equal(t, Baseline{
+			From:        "2024-01",
+			To:          "2024-06",
+			Samples:     6,
+			Median:      200,
+			MAD:         10,
+			Explanation: "median total per month of 200.00 with a median absolute deviation of 10.00 over 6 months from 2024-01 to 2024-06",
+		}, anomalies[1].Baseline)
+	}
+}
@@ -0,0 +1,367 @@
+package insight
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jsritawan/assessment/expense"
+)
+
+// madSigma turns a median absolute deviation into an estimate of the
+// standard deviation.
+const madSigma = 1.4826
+
+// forecastZ gives the bounds of a forecast roughly 90% coverage.
+const forecastZ = 1.645
+
+// recurringMonths is how many complete months in a row an expense must
+// have been seen, at a stable amount, to count as recurring.
+const recurringMonths = 3
+
+// recurringTolerance is how far, relatively, the amounts of a recurring
+// expense may drift from month to month.
+const recurringTolerance = 0.1
+
+type ForecastConfig struct {
+	// History is how many complete months the baseline of non-recurring
+	// spending is taken from.
+	History int `json:"history"`
+	// Years is how many prior years the seasonal factor is taken from.
+	Years int `json:"years"`
+}
+
+var DefaultForecastConfig = ForecastConfig{
+	History: 6,
+	Years:   2,
+}
+
+// Recurring is an expense seen every month lately, expected again.
+type Recurring struct {
+	Title  string   `json:"title"`
+	Amount float64  `json:"amount"`
+	Day    int      `json:"day"`
+	Tags   []string `json:"tags"`
+
+	key string
+}
+
+type Forecast struct {
+	Tag string `json:"tag,omitempty"`
+	// Actual is what has been spent in the month so far.
+	Actual float64 `json:"actual"`
+	// Recurring is what recurring expenses not yet seen this month are
+	// expected to add.
+	Recurring float64 `json:"recurring"`
+	// Installments is what installment plans still have due in the month.
+	Installments float64 `json:"installments"`
+	RunRate      float64 `json:"run_rate"`
+	Seasonality  float64 `json:"seasonality"`
+	Estimate     float64 `json:"estimate"`
+	Lower        float64 `json:"lower"`
+	Upper        float64 `json:"upper"`
+}
+
+type MonthForecast struct {
+	Month string     `json:"month"`
+	Total Forecast   `json:"total"`
+	Tags  []Forecast `json:"tags"`
+}
+
+// Commitment is what an installment plan still has due after the as of
+// date, through the month of its last installment.
+type Commitment struct {
+	PlanID    int     `json:"plan_id"`
+	Title     string  `json:"title"`
+	Remaining int     `json:"remaining"`
+	Amount    float64 `json:"amount"`
+	Until     string  `json:"until"`
+}
+
+type Forecasts struct {
+	ForecastConfig
+	AsOf      string          `json:"as_of"`
+	Recurring []Recurring     `json:"recurring"`
+	Months    []MonthForecast `json:"months"`
+	// Committed is the full amount installment plans still have due, well
+	// beyond the months forecast.
+	Committed   float64      `json:"committed"`
+	Commitments []Commitment `json:"commitments"`
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+func titleKey(title string) string {
+	return strings.Join(strings.Fields(strings.ToLower(title)), " ")
+}
+
+func daysIn(month time.Time) int {
+	return month.AddDate(0, 1, -1).Day()
+}
+
+// window returns the span of expenses a forecast made on now needs: the
+// prior years for seasonality, the trailing history, and the month so far.
+func (cfg ForecastConfig) window(now time.Time) (time.Time, time.Time) {
+	current := Month.start(now)
+	from := time.Date(current.Year()-cfg.Years, time.January, 1, 0, 0, 0, 0, time.UTC)
+	history := cfg.History
+	if history < recurringMonths {
+		history = recurringMonths
+	}
+	if h := Month.add(current, -history); h.Before(from) {
+		from = h
+	}
+	day := now.UTC()
+	return from, time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+// recurring finds expenses with the same title exactly once in each of
+// the last complete months whose amounts stay within the tolerance.
+func recurring(expenses []expense.Expense, current time.Time) []Recurring {
+	first := Month.add(current, -recurringMonths)
+	seen := map[string]map[time.Time][]expense.Expense{}
+	for _, e := range expenses {
+		if e.SpentAt.Before(first) || !e.SpentAt.Before(current) {
+			continue
+		}
+		key := titleKey(e.Title)
+		if seen[key] == nil {
+			seen[key] = map[time.Time][]expense.Expense{}
+		}
+		month := Month.start(*e.SpentAt)
+		seen[key][month] = append(seen[key][month], e)
+	}
+
+	found := []Recurring{}
+next:
+	for key, months := range seen {
+		if len(months) < recurringMonths {
+			continue
+		}
+		var amounts, days []float64
+		for _, m := range months {
+			if len(m) > 1 {
+				continue next
+			}
+			amounts = append(amounts, m[0].Amount)
+			days = append(days, float64(m[0].SpentAt.Day()))
+		}
+		sort.Float64s(amounts)
+		if amounts[0] <= 0 || amounts[len(amounts)-1] > amounts[0]*(1+recurringTolerance) {
+			continue
+		}
+		latest := months[Month.add(current, -1)][0]
+		found = append(found, Recurring{
+			Title:  latest.Title,
+			Amount: latest.Amount,
+			Day:    int(median(days)),
+			Tags:   latest.Tags,
+			key:    key,
+		})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].key < found[j].key })
+	return found
+}
+
+// series is the spending a single forecast is made from: all expenses for
+// the total, or those carrying one tag. Installments are known ahead, so
+// they are kept out of the spending projected from history and upcoming
+// ones are added as they fall due.
+type series struct {
+	expenses     []expense.Expense
+	recurring    map[string]float64
+	installments map[int]bool
+	upcoming     []expense.Expense
+}
+
+func (s series) monthly(nonRecurring bool) map[time.Time]float64 {
+	totals := map[time.Time]float64{}
+	for _, e := range s.expenses {
+		if _, ok := s.recurring[titleKey(e.Title)]; ok && nonRecurring {
+			continue
+		}
+		if s.installments[e.ID] && nonRecurring {
+			continue
+		}
+		totals[Month.start(*e.SpentAt)] += e.Amount
+	}
+	return totals
+}
+
+// seasonality compares a month with the average month of the same year,
+// averaged over the prior years that had any spending.
+func (s series) seasonality(cfg ForecastConfig, month time.Month, current time.Time) float64 {
+	totals := s.monthly(false)
+	var sum float64
+	var years int
+	for y := current.Year() - cfg.Years; y < current.Year(); y++ {
+		var year float64
+		for m := time.January; m <= time.December; m++ {
+			year += totals[time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)]
+		}
+		if year <= 0 {
+			continue
+		}
+		sum += totals[time.Date(y, month, 1, 0, 0, 0, 0, time.UTC)] / (year / 12)
+		years++
+	}
+	if years == 0 {
+		return 1
+	}
+	return sum / float64(years)
+}
+
+// forecast projects the month starting at month, as seen on now. Spending
+// so far is split into recurring expenses and the rest. The rest is
+// projected from its run rate, blended with the seasonally adjusted
+// median of recent months as the month progresses, and recurring expenses
+// not yet seen and installments not yet due are added on top.
+func (s series) forecast(cfg ForecastConfig, month, now time.Time) Forecast {
+	current := Month.start(now)
+	days := daysIn(month)
+	elapsed := 0
+	if !month.After(current) {
+		elapsed = now.UTC().Day()
+	}
+
+	var f Forecast
+	var discretionary float64
+	pending := map[string]float64{}
+	for k, amount := range s.recurring {
+		pending[k] = amount
+	}
+	for _, e := range s.expenses {
+		if !Month.start(*e.SpentAt).Equal(month) {
+			continue
+		}
+		f.Actual += e.Amount
+		if s.installments[e.ID] {
+			continue
+		}
+		k := titleKey(e.Title)
+		if _, ok := s.recurring[k]; ok {
+			delete(pending, k)
+			continue
+		}
+		discretionary += e.Amount
+	}
+	for _, amount := range pending {
+		f.Recurring += amount
+	}
+	for _, e := range s.upcoming {
+		if Month.start(*e.SpentAt).Equal(month) {
+			f.Installments += e.Amount
+		}
+	}
+
+	var history []float64
+	totals := s.monthly(true)
+	for i := 1; i <= cfg.History; i++ {
+		history = append(history, totals[Month.add(current, -i)])
+	}
+	baseline := median(history)
+	sigma := madSigma * mad(history, baseline)
+
+	f.Seasonality = s.seasonality(cfg, month.Month(), current)
+	full := baseline * f.Seasonality
+	w := float64(elapsed) / float64(days)
+	if elapsed > 0 {
+		f.RunRate = discretionary / float64(elapsed)
+		full = w*f.RunRate*float64(days) + (1-w)*full
+	}
+
+	committed := f.Actual + f.Recurring + f.Installments
+	f.Estimate = committed + math.Max(0, full-discretionary)
+	band := forecastZ * sigma * f.Seasonality * (1 - w)
+	f.Lower = math.Max(committed, f.Estimate-band)
+	f.Upper = f.Estimate + band
+
+	f.Actual, f.Recurring, f.Installments, f.RunRate = round(f.Actual), round(f.Recurring), round(f.Installments), round(f.RunRate)
+	f.Seasonality = round(f.Seasonality)
+	f.Estimate, f.Lower, f.Upper = round(f.Estimate), round(f.Lower), round(f.Upper)
+	return f
+}
+
+// forecast projects the current and next month in total and per tag.
+// installments are the expenses of installment plans from the start of
+// the window through the next month, including those not yet due.
+func (cfg ForecastConfig) forecast(expenses, installments []expense.Expense, now time.Time) Forecasts {
+	current := Month.start(now)
+	_, end := cfg.window(now)
+	dated := expenses[:0:0]
+	for _, e := range expenses {
+		if e.SpentAt != nil {
+			dated = append(dated, e)
+		}
+	}
+	planned := map[int]bool{}
+	var upcoming []expense.Expense
+	for _, e := range installments {
+		planned[e.ID] = true
+		if !e.SpentAt.Before(end) {
+			upcoming = append(upcoming, e)
+		}
+	}
+
+	var unplanned []expense.Expense
+	for _, e := range dated {
+		if !planned[e.ID] {
+			unplanned = append(unplanned, e)
+		}
+	}
+
+	found := recurring(unplanned, current)
+	all := series{recurring: map[string]float64{}, expenses: dated, installments: planned, upcoming: upcoming}
+	byTag := map[string]*series{}
+	tagged := func(t string) *series {
+		if byTag[t] == nil {
+			byTag[t] = &series{recurring: map[string]float64{}, installments: planned}
+		}
+		return byTag[t]
+	}
+	for _, e := range dated {
+		for _, t := range e.Tags {
+			tagged(t).expenses = append(tagged(t).expenses, e)
+		}
+	}
+	for _, e := range upcoming {
+		for _, t := range e.Tags {
+			tagged(t).upcoming = append(tagged(t).upcoming, e)
+		}
+	}
+	for _, r := range found {
+		all.recurring[r.key] = r.Amount
+		for _, t := range r.Tags {
+			byTag[t].recurring[r.key] = r.Amount
+		}
+	}
+
+	tags := make([]string, 0, len(byTag))
+	for t := range byTag {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+
+	result := Forecasts{
+		ForecastConfig: cfg,
+		AsOf:           now.Format("2006-01-02"),
+		Recurring:      found,
+		Commitments:    []Commitment{},
+	}
+	for _, month := range []time.Time{current, Month.add(current, 1)} {
+		m := MonthForecast{Month: Month.label(month), Total: all.forecast(cfg, month, now), Tags: []Forecast{}}
+		for _, t := range tags {
+			f := byTag[t].forecast(cfg, month, now)
+			if f.Estimate == 0 {
+				continue
+			}
+			f.Tag = t
+			m.Tags = append(m.Tags, f)
+		}
+		result.Months = append(result.Months, m)
+	}
+	return result
+}
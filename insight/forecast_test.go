@@ -0,0 +1,88 @@
+//go:build unit
+
+package insight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jsritawan/assessment/expense"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForecast(t *testing.T) {
+	now := time.Date(2024, 7, 16, 9, 0, 0, 0, time.UTC)
+	cfg := DefaultForecastConfig
+
+	from, to := cfg.window(now)
+	assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2024, 7, 17, 0, 0, 0, 0, time.UTC), to)
+
+	var expenses []expense.Expense
+	for m, food := range []float64{2800, 3000, 3200, 3000, 2900, 3100} {
+		month := time.Month(m + 1)
+		expenses = append(expenses,
+			spent(2024, month, 1, "Rent", 10000, "home"),
+			spent(2024, month, 5, "groceries", food/2, "food"),
+			spent(2024, month, 20, "groceries", food/2, "food"))
+	}
+	expenses = append(expenses,
+		spent(2024, time.July, 3, "groceries", 800, "food"),
+		spent(2024, time.July, 10, "groceries", 800, "food"))
+
+	f := cfg.forecast(expenses, nil, now)
+
+	assert.Equal(t, []Recurring{{Title: "Rent", Amount: 10000, Day: 1, Tags: []string{"home"}, key: "rent"}}, f.Recurring)
+	if assert.Len(t, f.Months, 2) {
+		july, august := f.Months[0], f.Months[1]
+		assert.Equal(t, "2024-07", july.Month)
+		assert.Equal(t, Forecast{Actual: 1600, Recurring: 10000, RunRate: 100, Seasonality: 1, Estimate: 13051.61, Lower: 12933.6, Upper: 13169.62}, july.Total)
+		assert.Equal(t, []Forecast{
+			{Tag: "food", Actual: 1600, RunRate: 100, Seasonality: 1, Estimate: 3051.61, Lower: 2933.6, Upper: 3169.62},
+			{Tag: "home", Recurring: 10000, Seasonality: 1, Estimate: 10000, Lower: 10000, Upper: 10000},
+		}, july.Tags)
+
+		assert.Equal(t, "2024-08", august.Month)
+		assert.Equal(t, Forecast{Tag: "food", Seasonality: 1, Estimate: 3000, Lower: 2756.11, Upper: 3243.89}, august.Tags[0])
+	}
+}
+
+func TestForecastSeasonality(t *testing.T) {
+	var expenses []expense.Expense
+	for m := time.January; m <= time.December; m++ {
+		amount := 1000.0
+		if m == time.December {
+			amount = 2200
+		}
+		expenses = append(expenses, spent(2023, m, 10, "gifts", amount, "gifts"))
+	}
+
+	s := series{expenses: expenses}
+
+	assert.InDelta(t, 2.0, s.seasonality(DefaultForecastConfig, time.December, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)), 0.001)
+	assert.InDelta(t, 1.0, s.seasonality(DefaultForecastConfig, time.March, time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)), 0.001)
+}
+
+func TestForecastInstallments(t *testing.T) {
+	now := time.Date(2024, 7, 16, 9, 0, 0, 0, time.UTC)
+	cfg := DefaultForecastConfig
+
+	var expenses, installments []expense.Expense
+	for m := time.February; m <= time.August; m++ {
+		e := spent(2024, m, 10, "TV", 1500, "gadget")
+		e.ID = int(m)
+		installments = append(installments, e)
+		if m <= time.July {
+			expenses = append(expenses, e)
+		}
+	}
+
+	f := cfg.forecast(expenses, installments, now)
+
+	if assert.Len(t, f.Months, 2) {
+		july, august := f.Months[0], f.Months[1]
+		assert.Equal(t, Forecast{Actual: 1500, Seasonality: 1, Estimate: 1500, Lower: 1500, Upper: 1500}, july.Total)
+		assert.Equal(t, Forecast{Installments: 1500, Seasonality: 1, Estimate: 1500, Lower: 1500, Upper: 1500}, august.Total)
+		assert.Equal(t, []Forecast{{Tag: "gadget", Installments: 1500, Seasonality: 1, Estimate: 1500, Lower: 1500, Upper: 1500}}, august.Tags)
+	}
+}
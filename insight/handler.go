@@ -0,0 +1,229 @@
+package insight
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/lib/pq"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+// asOf reads the as_of date insights are computed for, today by default.
+func asOf(c *gin.Context) (time.Time, error) {
+	v := c.Query("as_of")
+	if v == "" {
+		return time.Now().UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return t, fmt.Errorf("invalid as_of %q", v)
+	}
+	return t, nil
+}
+
+// Upper bounds of the forecast and anomaly parameters, which size what
+// they allocate and loop over. Two years of weeks is the most periods an
+// anomaly search looks back over.
+const (
+	MaxHistory = 36
+	MaxYears   = 10
+	MaxPeriods = 104
+)
+
+// positiveInt reads the query parameter name into n, which must be between
+// 1 and max.
+func positiveInt(c *gin.Context, name string, n *int, max int) error {
+	if v := c.Query(name); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 1 {
+			return fmt.Errorf("invalid %s %q", name, v)
+		}
+		if i > max {
+			return fmt.Errorf("%s must be at most %d", name, max)
+		}
+		*n = i
+	}
+	return nil
+}
+
+func anomalyConfig(c *gin.Context, cfg AnomalyConfig) (AnomalyConfig, error) {
+	if v := c.Query("period"); v != "" {
+		cfg.Period = Period(v)
+		if !cfg.Period.valid() {
+			return cfg, fmt.Errorf("invalid period %q", v)
+		}
+	}
+	for name, n := range map[string]*int{"periods": &cfg.Periods, "recent": &cfg.Recent, "min_samples": &cfg.MinSamples} {
+		if err := positiveInt(c, name, n, MaxPeriods); err != nil {
+			return cfg, err
+		}
+	}
+	if v := c.Query("threshold"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 {
+			return cfg, fmt.Errorf("invalid threshold %q", v)
+		}
+		cfg.Threshold = f
+	}
+	return cfg, nil
+}
+
+func (h *handler) expenses(from, to time.Time) ([]expense.Expense, error) {
+	rows, err := h.DB.Query("SELECT "+expense.Columns+" FROM expenses WHERE spent_at >= $1 AND spent_at < $2 AND kind = $3 ORDER BY spent_at, id",
+		from, to, expense.KindExpense)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expenses []expense.Expense
+	for rows.Next() {
+		var e expense.Expense
+		if err := rows.Scan(e.Fields()...); err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, e)
+	}
+	return expenses, rows.Err()
+}
+
+// installments loads the expenses of installment plans spent or due from
+// from to to.
+func (h *handler) installments(from, to time.Time) ([]expense.Expense, error) {
+	rows, err := h.DB.Query(`
+		SELECT e.id, e.amount, e.tags, e.spent_at
+		FROM installments i JOIN expenses e ON e.id = i.expense_id
+		WHERE e.spent_at >= $1 AND e.spent_at < $2
+		ORDER BY e.spent_at, e.id`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expenses []expense.Expense
+	for rows.Next() {
+		var e expense.Expense
+		if err := rows.Scan(&e.ID, &e.Amount, pq.Array(&e.Tags), &e.SpentAt); err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, e)
+	}
+	return expenses, rows.Err()
+}
+
+// commitments totals what each installment plan has due from from on.
+func (h *handler) commitments(from time.Time) ([]Commitment, error) {
+	rows, err := h.DB.Query(`
+		SELECT p.id, p.title, COUNT(*), SUM(e.amount), MAX(e.spent_at)
+		FROM installment_plans p
+		JOIN installments i ON i.plan_id = p.id
+		JOIN expenses e ON e.id = i.expense_id
+		WHERE e.spent_at >= $1
+		GROUP BY p.id, p.title
+		ORDER BY MAX(e.spent_at), p.id`, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commitments := []Commitment{}
+	for rows.Next() {
+		var c Commitment
+		var until time.Time
+		if err := rows.Scan(&c.PlanID, &c.Title, &c.Remaining, &c.Amount, &until); err != nil {
+			return nil, err
+		}
+		c.Amount = round(c.Amount)
+		c.Until = Month.label(until)
+		commitments = append(commitments, c)
+	}
+	return commitments, rows.Err()
+}
+
+func (h *handler) GetAnomalies(c *gin.Context) {
+	cfg, err := anomalyConfig(c, DefaultAnomalyConfig)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	now, err := asOf(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to := cfg.window(now)
+	expenses, err := h.expenses(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, Anomalies{
+		AnomalyConfig: cfg,
+		From:          cfg.Period.label(from),
+		To:            cfg.Period.label(cfg.Period.add(to, -1)),
+		Anomalies:     cfg.detect(expenses, from),
+	})
+}
+
+func (h *handler) GetForecast(c *gin.Context) {
+	cfg := DefaultForecastConfig
+	limits := map[string]struct {
+		n   *int
+		max int
+	}{
+		"history": {&cfg.History, MaxHistory},
+		"years":   {&cfg.Years, MaxYears},
+	}
+	for name, l := range limits {
+		if err := positiveInt(c, name, l.n, l.max); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	now, err := asOf(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to := cfg.window(now)
+	expenses, err := h.expenses(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	installments, err := h.installments(from, Month.add(Month.start(now), 2))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	commitments, err := h.commitments(to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	f := cfg.forecast(expenses, installments, now)
+	f.Commitments = commitments
+	for _, commitment := range commitments {
+		f.Committed += commitment.Amount
+	}
+	f.Committed = round(f.Committed)
+	c.JSON(http.StatusOK, f)
+}
@@ -0,0 +1,126 @@
+//go:build unit
+
+package insight
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var expenseColumns = []string{"id", "title", "amount", "note", "tags", "spent_at", "category", "merchant_id", "account_id", "status", "tax", "deduction_id"}
+
+func TestGetAnomalies(t *testing.T) {
+	t.Run("Get Anomalies With Invalid Period Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/insights/anomalies?period=year", nil)
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/insights/anomalies", h.GetAnomalies)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Get Anomalies With Too Many Periods Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/insights/anomalies?periods=1000000000", nil)
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/insights/anomalies", h.GetAnomalies)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Get Anomalies Should Return Anomalies In Window", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/insights/anomalies?as_of=2024-07-20&periods=3&threshold=3", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		rows := sqlmock.NewRows(expenseColumns)
+		for m, amount := range []float64{100, 120, 110, 600} {
+			rows.AddRow(m+1, "groceries", amount, "", pq.Array([]string{"food"}), time.Date(2024, time.Month(m+4), 5, 0, 0, 0, 0, time.UTC), "", nil, nil, "draft", nil, nil)
+		}
+		mock.ExpectQuery("SELECT (.+) FROM expenses WHERE spent_at >= \\$1 AND spent_at < \\$2").
+			WithArgs(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), "expense").
+			WillReturnRows(rows)
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/insights/anomalies", h.GetAnomalies)
+		expect := `{"period":"month","periods":3,"recent":1,"threshold":3,"min_samples":5,"from":"2024-04","to":"2024-07","anomalies":[{"kind":"period","tag":"food","period":"2024-07","amount":600,"score":33.05,"direction":"above","baseline":{"from":"2024-04","to":"2024-06","samples":3,"median":110,"mad":10,"explanation":"median total per month of 110.00 with a median absolute deviation of 10.00 over 3 months from 2024-04 to 2024-06"}}]}`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, expect, rec.Body.String())
+	})
+}
+
+func TestGetForecast(t *testing.T) {
+	t.Run("Get Forecast With Too Much History Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/insights/forecast?history=1000000000", nil)
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/insights/forecast", h.GetForecast)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"error":"history must be at most 36"}`, strings.TrimSpace(rec.Body.String()))
+	})
+}
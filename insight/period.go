@@ -0,0 +1,48 @@
+package insight
+
+import (
+	"fmt"
+	"time"
+)
+
+// Period is the length of the buckets spending is grouped into.
+type Period string
+
+const (
+	Month Period = "month"
+	Week  Period = "week"
+)
+
+func (p Period) valid() bool {
+	return p == Month || p == Week
+}
+
+// start returns the beginning of the period t falls in, in UTC. Weeks
+// start on Monday.
+func (p Period) start(t time.Time) time.Time {
+	t = t.UTC()
+	if p == Week {
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// add moves a period start n periods forward, or backward when n is
+// negative.
+func (p Period) add(start time.Time, n int) time.Time {
+	if p == Week {
+		return start.AddDate(0, 0, 7*n)
+	}
+	return start.AddDate(0, n, 0)
+}
+
+// label names the period starting at start, 2024-06 for a month and
+// 2024-W23 for an ISO week.
+func (p Period) label(start time.Time) string {
+	if p == Week {
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return start.Format("2006-01")
+}
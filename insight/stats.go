@@ -0,0 +1,61 @@
+package insight
+
+import (
+	"math"
+	"sort"
+)
+
+// madScale turns a median absolute deviation into a robust z-score, so a
+// score of 3.5 means about as unusual as 3.5 standard deviations would for
+// normally distributed data.
+const madScale = 0.6745
+
+// meanADScale is used instead when more than half the samples equal the
+// median and the MAD collapses to zero.
+const meanADScale = 1.253314
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// mad is the median absolute deviation of values around their median.
+func mad(values []float64, med float64) float64 {
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - med)
+	}
+	return median(deviations)
+}
+
+func meanAD(values []float64, med float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += math.Abs(v - med)
+	}
+	return sum / float64(len(values))
+}
+
+// robustScore returns how far x lies from the median in robust standard
+// deviations. ok is false when the samples do not vary at all and no
+// score can be given.
+func robustScore(x, med, mad, meanAD float64) (score float64, ok bool) {
+	switch {
+	case mad > 0:
+		return madScale * (x - med) / mad, true
+	case meanAD > 0:
+		return (x - med) / (meanADScale * meanAD), true
+	}
+	return 0, false
+}
@@ -0,0 +1,192 @@
+package installment
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/lib/pq"
+)
+
+const planColumns = "id, title, total, months, interest_rate, start_date, tags, category, account_id, created_at"
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+func scanPlan(row interface{ Scan(...interface{}) error }, p *Plan) error {
+	return row.Scan(&p.ID, &p.Title, &p.Total, &p.Months, &p.InterestRate, &p.StartDate, pq.Array(&p.Tags), &p.Category, &p.AccountID, &p.CreatedAt)
+}
+
+// installments loads the installments of the plans and tracks them as of
+// now. Amounts and due dates come from the expenses, which may have been
+// edited since the plan was made.
+func (h *handler) installments(plans []Plan, now time.Time) error {
+	ids := make([]int, len(plans))
+	byID := map[int]*Plan{}
+	for i := range plans {
+		ids[i] = plans[i].ID
+		byID[plans[i].ID] = &plans[i]
+		plans[i].Installments = []Installment{}
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT i.plan_id, i.number, e.id, e.amount, e.spent_at
+		FROM installments i JOIN expenses e ON e.id = i.expense_id
+		WHERE i.plan_id = ANY($1)
+		ORDER BY i.plan_id, i.number`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var planID int
+		var in Installment
+		if err := rows.Scan(&planID, &in.Number, &in.ExpenseID, &in.Amount, &in.DueAt); err != nil {
+			return err
+		}
+		if p := byID[planID]; p != nil {
+			p.Installments = append(p.Installments, in)
+		}
+	}
+	for i := range plans {
+		plans[i].track(now)
+	}
+	return rows.Err()
+}
+
+// Create makes a plan and an expense for each of its installments, due a
+// month apart from the start date. The expenses go through the rules like
+// any other, so they are tagged and matched to a merchant alike.
+func (h *handler) Create(c *gin.Context) {
+	var p Plan
+	if err := c.BindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now()
+	if err := validate(&p, now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.Installments = schedule(p)
+
+	e := expense.Expense{Title: p.Title, Amount: p.Installments[0].Amount, Tags: p.Tags, Category: p.Category, AccountID: p.AccountID}
+	if err := expense.Prepare(h.DB, &e); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow(`
+		INSERT INTO installment_plans(title, total, months, interest_rate, start_date, tags, category, account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+		`,
+		p.Title, p.Total, p.Months, p.InterestRate, p.StartDate, pq.Array(p.Tags), p.Category, p.AccountID)
+	if err := row.Scan(&p.ID, &p.CreatedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]int, 0, len(p.Installments))
+	for i := range p.Installments {
+		in := &p.Installments[i]
+		ie := e
+		ie.Title, ie.Amount, ie.SpentAt = fmt.Sprintf("%s (%d/%d)", e.Title, in.Number, p.Months), in.Amount, &in.DueAt
+		if err := expense.Insert(tx, expense.KindExpense, &ie); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		in.ExpenseID = ie.ID
+		if _, err := tx.Exec("INSERT INTO installments(plan_id, number, expense_id) VALUES ($1, $2, $3)", p.ID, in.Number, in.ExpenseID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ids = append(ids, in.ExpenseID)
+	}
+	if err := ledger.SyncExpenses(tx, ids); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	p.track(now)
+	c.JSON(http.StatusCreated, p)
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT " + planColumns + " FROM installment_plans ORDER BY start_date DESC, id DESC")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	plans := []Plan{}
+	for rows.Next() {
+		var p Plan
+		if err := scanPlan(rows, &p); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		plans = append(plans, p)
+	}
+	rows.Close()
+
+	if len(plans) > 0 {
+		if err := h.installments(plans, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, plans)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var p Plan
+	row := h.DB.QueryRow("SELECT "+planColumns+" FROM installment_plans WHERE id = $1", id)
+	if err := scanPlan(row, &p); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "installment plan not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	plans := []Plan{p}
+	if err := h.installments(plans, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, plans[0])
+}
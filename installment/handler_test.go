@@ -0,0 +1,165 @@
+//go:build unit
+
+package installment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var planColumnNames = []string{"id", "title", "total", "months", "interest_rate", "start_date", "tags", "category", "account_id", "created_at"}
+
+func TestCreatePlan(t *testing.T) {
+	t.Run("Create Plan Without Months Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/installment-plans", strings.NewReader(`{"title": "TV", "total": 15000}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/installment-plans", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Create Plan Should Create An Expense Per Installment", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/installment-plans", strings.NewReader(`{"title": "TV", "total": 15000, "months": 2, "start_date": "2024-01-31T00:00:00Z"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		start := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT (.+) FROM rules").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "enabled", "conditions", "actions"}))
+		mock.ExpectQuery("SELECT (.+) FROM merchants").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "aliases"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO installment_plans").
+			WithArgs("TV", 15000.0, 2, 0.0, &start, pq.Array([]string{}), "", nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, start))
+		mock.ExpectQuery("INSERT INTO expenses").
+			WithArgs("TV (1/2)", 7500.0, "", pq.Array([]string{}), start, "", nil, nil, expense.KindExpense, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at"}).AddRow(11, start))
+		mock.ExpectExec("INSERT INTO installments").
+			WithArgs(5, 1, 11).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO expenses").
+			WithArgs("TV (2/2)", 7500.0, "", pq.Array([]string{}), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), "", nil, nil, expense.KindExpense, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at"}).AddRow(12, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)))
+		mock.ExpectExec("INSERT INTO installments").
+			WithArgs(5, 2, 12).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM journal_entries WHERE expense_id").
+			WithArgs(pq.Array([]int{11, 12})).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT (.+) FROM expenses e LEFT JOIN accounts a").
+			WithArgs(pq.Array([]int{11, 12})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "kind", "tags", "category", "spent_at", "account_id", "name", "type"}))
+		mock.ExpectCommit()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/installment-plans", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"installments":[{"number":1,"expense_id":11,"amount":7500,"due_at":"2024-01-31T00:00:00Z","paid":true},{"number":2,"expense_id":12,"amount":7500,"due_at":"2024-02-29T00:00:00Z","paid":true}]`)
+	})
+}
+
+func TestGetPlan(t *testing.T) {
+	t.Run("Get Missing Plan Should Return Not Found", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/installment-plans/9", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM installment_plans WHERE id").
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows(planColumnNames))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/installment-plans/:id", h.Get)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Get Plan Should Track Installments From Expenses", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/installment-plans/5", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		start := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT (.+) FROM installment_plans WHERE id").
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows(planColumnNames).AddRow(5, "TV", 15000.0, 2, 0.0, start, pq.Array([]string{}), "", nil, start))
+		mock.ExpectQuery("SELECT (.+) FROM installments i JOIN expenses e").
+			WithArgs(pq.Array([]int{5})).
+			WillReturnRows(sqlmock.NewRows([]string{"plan_id", "number", "id", "amount", "spent_at"}).
+				AddRow(5, 1, 11, 7500.0, start).
+				AddRow(5, 2, 12, 7500.0, time.Now().AddDate(0, 1, 0)))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/installment-plans/:id", h.Get)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"interest":0,"payable":15000,"paid":1,"paid_amount":7500,"remaining":1,"remaining_amount":7500`)
+	})
+}
@@ -0,0 +1,129 @@
+package installment
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// MaxMonths is the longest plan accepted.
+const MaxMonths = 120
+
+// Plan pays for a purchase in equal monthly installments, each an expense
+// of its own. InterestRate is the flat monthly rate in percent of Total
+// that card issuers quote, so 0.8 over 10 months adds 8% of the total;
+// zero is the common 0% plan.
+type Plan struct {
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	Total        float64    `json:"total"`
+	Months       int        `json:"months"`
+	InterestRate float64    `json:"interest_rate"`
+	StartDate    *time.Time `json:"start_date"`
+	Tags         []string   `json:"tags"`
+	Category     string     `json:"category,omitempty"`
+	AccountID    *int       `json:"account_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	Interest        float64       `json:"interest"`
+	Payable         float64       `json:"payable"`
+	Paid            int           `json:"paid"`
+	PaidAmount      float64       `json:"paid_amount"`
+	Remaining       int           `json:"remaining"`
+	RemainingAmount float64       `json:"remaining_amount"`
+	Installments    []Installment `json:"installments"`
+}
+
+// Installment is one monthly payment of a plan. It counts as paid once
+// it has fallen due, as card installments are charged automatically.
+type Installment struct {
+	Number    int       `json:"number"`
+	ExpenseID int       `json:"expense_id"`
+	Amount    float64   `json:"amount"`
+	DueAt     time.Time `json:"due_at"`
+	Paid      bool      `json:"paid"`
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// addMonths moves t n months on, keeping the day of month where the month
+// has it and the last day otherwise, so a plan starting on 31 January is
+// due on 29 February rather than in March.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// validate trims the title of p, checks its terms and fills in the start
+// date, today unless given.
+func validate(p *Plan, now time.Time) error {
+	p.Title = strings.TrimSpace(p.Title)
+	if p.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if p.Total <= 0 {
+		return fmt.Errorf("total must be positive")
+	}
+	if p.Months < 1 || p.Months > MaxMonths {
+		return fmt.Errorf("months must be between 1 and %d", MaxMonths)
+	}
+	if p.InterestRate < 0 || p.InterestRate > 100 {
+		return fmt.Errorf("invalid interest_rate %v", p.InterestRate)
+	}
+	if p.StartDate == nil {
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		p.StartDate = &day
+	}
+	if p.Tags == nil {
+		p.Tags = []string{}
+	}
+	return nil
+}
+
+// schedule splits what p costs in all into its monthly installments, in
+// satang so they add up exactly; the first installments take the
+// remainder, one satang each.
+func schedule(p Plan) []Installment {
+	total := int64(math.Round(p.Total * 100))
+	interest := int64(math.Round(float64(total) * p.InterestRate / 100 * float64(p.Months)))
+	months := int64(p.Months)
+	each, extra := (total+interest)/months, (total+interest)%months
+
+	installments := make([]Installment, p.Months)
+	for i := range installments {
+		amount := each
+		if int64(i) < extra {
+			amount++
+		}
+		installments[i] = Installment{Number: i + 1, Amount: float64(amount) / 100, DueAt: addMonths(*p.StartDate, i)}
+	}
+	return installments
+}
+
+// track works out what p costs in all and how much of it has been paid as
+// of now from its installments.
+func (p *Plan) track(now time.Time) {
+	p.Payable, p.Paid, p.PaidAmount, p.Remaining, p.RemainingAmount = 0, 0, 0, 0, 0
+	for i := range p.Installments {
+		in := &p.Installments[i]
+		in.Paid = !in.DueAt.After(now)
+		p.Payable += in.Amount
+		if in.Paid {
+			p.Paid++
+			p.PaidAmount += in.Amount
+		} else {
+			p.Remaining++
+			p.RemainingAmount += in.Amount
+		}
+	}
+	p.Payable, p.PaidAmount, p.RemainingAmount = round(p.Payable), round(p.PaidAmount), round(p.RemainingAmount)
+	p.Interest = round(p.Payable - p.Total)
+}
@@ -0,0 +1,82 @@
+//go:build unit
+
+package installment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAddMonths(t *testing.T) {
+	start := date(2024, time.January, 31)
+
+	assert.Equal(t, date(2024, time.February, 29), addMonths(start, 1))
+	assert.Equal(t, date(2024, time.March, 31), addMonths(start, 2))
+	assert.Equal(t, date(2025, time.January, 31), addMonths(start, 12))
+}
+
+func TestSchedule(t *testing.T) {
+	t.Run("Zero Interest Should Split Total With Remainder First", func(t *testing.T) {
+		start := date(2024, time.January, 31)
+		p := Plan{Total: 10000, Months: 3, StartDate: &start}
+
+		assert.Equal(t, []Installment{
+			{Number: 1, Amount: 3333.34, DueAt: date(2024, time.January, 31)},
+			{Number: 2, Amount: 3333.33, DueAt: date(2024, time.February, 29)},
+			{Number: 3, Amount: 3333.33, DueAt: date(2024, time.March, 31)},
+		}, schedule(p))
+	})
+
+	t.Run("Flat Monthly Interest Should Be Added On Total", func(t *testing.T) {
+		start := date(2024, time.March, 1)
+		p := Plan{Total: 30000, Months: 10, InterestRate: 0.8, StartDate: &start}
+
+		installments := schedule(p)
+
+		assert.Len(t, installments, 10)
+		assert.Equal(t, 3240.0, installments[0].Amount)
+		assert.Equal(t, date(2024, time.December, 1), installments[9].DueAt)
+	})
+}
+
+func TestTrack(t *testing.T) {
+	start := date(2024, time.January, 31)
+	p := Plan{Total: 10000, Months: 3, StartDate: &start}
+	p.Installments = schedule(p)
+
+	p.track(date(2024, time.February, 29))
+
+	assert.Equal(t, 2, p.Paid)
+	assert.Equal(t, 6666.67, p.PaidAmount)
+	assert.Equal(t, 1, p.Remaining)
+	assert.Equal(t, 3333.33, p.RemainingAmount)
+	assert.Equal(t, 10000.0, p.Payable)
+	assert.Equal(t, 0.0, p.Interest)
+	assert.False(t, p.Installments[2].Paid)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2024, 5, 6, 15, 4, 0, 0, time.UTC)
+
+	p := Plan{Title: " iPhone ", Total: 39900, Months: 10}
+	assert.NoError(t, validate(&p, now))
+	assert.Equal(t, "iPhone", p.Title)
+	assert.Equal(t, date(2024, time.May, 6), *p.StartDate)
+
+	for _, p := range []Plan{
+		{Total: 100, Months: 10},
+		{Title: "TV", Total: 0, Months: 10},
+		{Title: "TV", Total: 100, Months: 0},
+		{Title: "TV", Total: 100, Months: MaxMonths + 1},
+		{Title: "TV", Total: 100, Months: 10, InterestRate: -1},
+	} {
+		p := p
+		assert.Error(t, validate(&p, now))
+	}
+}
@@ -0,0 +1,298 @@
+package ledger
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// entries loads the entries matching the conditions with their postings.
+func (h *handler) entries(where string, args ...interface{}) ([]Entry, error) {
+	rows, err := h.DB.Query(`
+		SELECT id, kind, expense_id, account_id, posted_at, description
+		FROM journal_entries j`+where+`
+		ORDER BY posted_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	index := map[int]int{}
+	var ids []int
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.Kind, &e.ExpenseID, &e.AccountID, &e.PostedAt, &e.Description); err != nil {
+			return nil, err
+		}
+		e.Postings = []Posting{}
+		index[e.ID] = len(entries)
+		ids = append(ids, e.ID)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	rows, err = h.DB.Query("SELECT entry_id, account, account_id, amount FROM postings WHERE entry_id = ANY($1) ORDER BY id", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var p Posting
+		if err := rows.Scan(&id, &p.Account, &p.AccountID, &p.Amount); err != nil {
+			return nil, err
+		}
+		e := &entries[index[id]]
+		e.Postings = append(e.Postings, p)
+	}
+	return entries, rows.Err()
+}
+
+// GetEntries lists journal entries, optionally only those of one expense
+// or touching one payment account.
+func (h *handler) GetEntries(c *gin.Context) {
+	var conds []string
+	var args []interface{}
+	for _, f := range []struct{ param, cond string }{
+		{"expense_id", "j.expense_id = $%d"},
+		{"account_id", "EXISTS (SELECT 1 FROM postings p WHERE p.entry_id = j.id AND p.account_id = $%d)"},
+	} {
+		param, cond := f.param, f.cond
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s %q", param, v)})
+			return
+		}
+		args = append(args, id)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if v := c.Query("kind"); v != "" {
+		args = append(args, v)
+		conds = append(conds, fmt.Sprintf("j.kind = $%d", len(args)))
+	}
+
+	var where string
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+	entries, err := h.entries(where, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+func (h *handler) CreateTransfer(c *gin.Context) {
+	var t Transfer
+	if err := c.BindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if cents(t.Amount) <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive"})
+		return
+	}
+	if t.FromAccountID == t.ToAccountID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot transfer to the same account"})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	names := map[int]string{}
+	currencies := map[int]string{}
+	rows, err := tx.Query("SELECT id, name, type, currency FROM accounts WHERE id = ANY($1)", pq.Array([]int{t.FromAccountID, t.ToAccountID}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for rows.Next() {
+		var id int
+		var name, accountType, currency string
+		if err := rows.Scan(&id, &name, &accountType, &currency); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		names[id] = PaymentAccount(name, accountType)
+		currencies[id] = currency
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(names) != 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account not found"})
+		return
+	}
+	// a transfer posts one amount to both sides, so it cannot convert
+	if from, to := currencies[t.FromAccountID], currencies[t.ToAccountID]; from != to {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot transfer between accounts in %s and %s", from, to)})
+		return
+	}
+
+	e := Entry{
+		Kind:        KindTransfer,
+		PostedAt:    time.Now(),
+		Description: t.Note,
+		Postings: []Posting{
+			{Account: names[t.ToAccountID], AccountID: &t.ToAccountID, Amount: t.Amount},
+			{Account: names[t.FromAccountID], AccountID: &t.FromAccountID, Amount: -t.Amount},
+		},
+	}
+	if t.Date != nil {
+		e.PostedAt = *t.Date
+	}
+	if e.Description == "" {
+		e.Description = "Transfer"
+	}
+	if err := Post(tx, &e); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, e)
+}
+
+func (h *handler) GetTransfers(c *gin.Context) {
+	entries, err := h.entries(" WHERE j.kind = $1", KindTransfer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetTrialBalance totals debits and credits per account up to and
+// including as_of, one trial balance per currency. An entry is in the
+// currency of the payment accounts it posts to, which a transfer requires
+// to match, and THB when it posts to none. Payment
+// accounts are listed under their current name.
+func (h *handler) GetTrialBalance(c *gin.Context) {
+	until := time.Now().UTC().AddDate(0, 0, 1)
+	if v := c.Query("as_of"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid as_of %q", v)})
+			return
+		}
+		until = t.AddDate(0, 0, 1)
+	}
+
+	rows, err := h.DB.Query(`
+		SELECT COALESCE(ec.currency, 'THB'), p.account, a.name, a.type,
+			COALESCE(SUM(p.amount) FILTER (WHERE p.amount > 0), 0),
+			COALESCE(-SUM(p.amount) FILTER (WHERE p.amount < 0), 0)
+		FROM postings p
+		JOIN journal_entries j ON j.id = p.entry_id
+		LEFT JOIN LATERAL (
+			SELECT pa.currency FROM postings q JOIN accounts pa ON pa.id = q.account_id
+			WHERE q.entry_id = j.id LIMIT 1
+		) ec ON true
+		LEFT JOIN accounts a ON a.id = p.account_id
+		WHERE j.posted_at < $1
+		GROUP BY 1, p.account, a.name, a.type`, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	type key struct{ currency, account string }
+	totals := map[key]*Balance{}
+	for rows.Next() {
+		var currency, account string
+		var name, accountType sql.NullString
+		var debit, credit float64
+		if err := rows.Scan(&currency, &account, &name, &accountType, &debit, &credit); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if name.Valid {
+			account = PaymentAccount(name.String, accountType.String)
+		}
+		k := key{currency, account}
+		b := totals[k]
+		if b == nil {
+			b = &Balance{Account: account}
+			totals[k] = b
+		}
+		b.Debit += debit
+		b.Credit += credit
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	byCurrency := map[string]*TrialBalance{}
+	for k, b := range totals {
+		tb := byCurrency[k.currency]
+		if tb == nil {
+			tb = &TrialBalance{Currency: k.currency, Accounts: []Balance{}}
+			byCurrency[k.currency] = tb
+		}
+		b.Debit, b.Credit = round(b.Debit), round(b.Credit)
+		b.Balance = round(b.Debit - b.Credit)
+		tb.Debit += b.Debit
+		tb.Credit += b.Credit
+		tb.Accounts = append(tb.Accounts, *b)
+	}
+
+	balances := []TrialBalance{}
+	for _, tb := range byCurrency {
+		sort.Slice(tb.Accounts, func(i, j int) bool { return tb.Accounts[i].Account < tb.Accounts[j].Account })
+		tb.Debit, tb.Credit = round(tb.Debit), round(tb.Credit)
+		tb.Balanced = cents(tb.Debit) == cents(tb.Credit)
+		balances = append(balances, *tb)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Currency < balances[j].Currency })
+
+	c.JSON(http.StatusOK, balances)
+}
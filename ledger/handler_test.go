@@ -0,0 +1,161 @@
+//go:build unit
+
+package ledger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	t.Run("Create Transfer To Same Account Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		body := `{"from_account_id": 1, "to_account_id": 1, "amount": 500}`
+		req := httptest.NewRequest(http.MethodPost, "/transfers", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/transfers", h.CreateTransfer)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Create Transfer Between Currencies Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		body := `{"from_account_id": 1, "to_account_id": 3, "amount": 500}`
+		req := httptest.NewRequest(http.MethodPost, "/transfers", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, name, type, currency FROM accounts").
+			WithArgs(pq.Array([]int{1, 3})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "currency"}).
+				AddRow(1, "savings", "cash", "THB").
+				AddRow(3, "travel", "bank", "USD"))
+		mock.ExpectRollback()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/transfers", h.CreateTransfer)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, `{"error":"cannot transfer between accounts in THB and USD"}`, strings.TrimSpace(rec.Body.String()))
+	})
+
+	t.Run("Create Transfer Should Return Created", func(t *testing.T) {
+		// Arrange
+		body := `{"from_account_id": 1, "to_account_id": 2, "amount": 500, "date": "2024-01-25T00:00:00Z", "note": "pay card"}`
+		req := httptest.NewRequest(http.MethodPost, "/transfers", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		date := time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, name, type, currency FROM accounts").
+			WithArgs(pq.Array([]int{1, 2})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "currency"}).
+				AddRow(1, "savings", "cash", "THB").
+				AddRow(2, "visa", "credit_card", "THB"))
+		mock.ExpectQuery("INSERT INTO journal_entries").
+			WithArgs(KindTransfer, nil, nil, date, "pay card").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(5, "Liabilities:Visa", 2, 500.0).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(5, "Assets:Savings", 1, -500.0).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/transfers", h.CreateTransfer)
+		expect := `{"id":5,"kind":"transfer","posted_at":"2024-01-25T00:00:00Z","description":"pay card","postings":[{"account":"Liabilities:Visa","account_id":2,"amount":500},{"account":"Assets:Savings","account_id":1,"amount":-500}]}`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestGetTrialBalance(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/ledger/trial-balance?as_of=2024-01-31", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM postings p").
+		WithArgs(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "account", "name", "type", "debit", "credit"}).
+			AddRow("THB", "Assets:Wallet", "cash", "cash", 1000.0, 120.0).
+			AddRow("THB", OpeningBalances, nil, nil, 0.0, 1000.0).
+			AddRow("THB", "Expenses:Food", nil, nil, 120.0, 0.0).
+			AddRow("USD", "Assets:Card", "visa", "credit_card", 0.0, 30.0).
+			AddRow("USD", "Expenses:Travel", nil, nil, 30.0, 0.0))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.GET("/ledger/trial-balance", h.GetTrialBalance)
+	expect := `[{"currency":"THB","accounts":[{"account":"Assets:Cash","debit":1000,"credit":120,"balance":880},{"account":"Equity:Opening-Balances","debit":0,"credit":1000,"balance":-1000},{"account":"Expenses:Food","debit":120,"credit":0,"balance":120}],"debit":1120,"credit":1120,"balanced":true},` +
+		`{"currency":"USD","accounts":[{"account":"Expenses:Travel","debit":30,"credit":0,"balance":30},{"account":"Liabilities:Visa","debit":0,"credit":30,"balance":-30}],"debit":30,"credit":30,"balanced":true}]`
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+}
@@ -0,0 +1,320 @@
+package ledger
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// Entry kinds. Expense and income entries mirror a row of the expenses
+// table, opening entries an account's opening balance, and transfers move
+// money between the user's own accounts without being spending.
+const (
+	KindOpening  = "opening"
+	KindExpense  = "expense"
+	KindIncome   = "income"
+	KindTransfer = "transfer"
+)
+
+const (
+	OpeningBalances = "Equity:Opening-Balances"
+	Unassigned      = "Assets:Unassigned"
+	Uncategorized   = "Uncategorized"
+)
+
+// Unreconciled is a condition on the expenses table leaving out expenses
+// matched to a line of a closed reconciliation session, whose books are
+// closed. Bulk rewrites add it so they skip what has been reconciled.
+const Unreconciled = `NOT EXISTS (
+	SELECT 1 FROM reconciliation_lines l
+	JOIN reconciliation_sessions s ON s.id = l.session_id
+	WHERE l.expense_id = expenses.id AND l.status = 'confirmed' AND s.status = 'closed')`
+
+// liabilities are the account types whose balance is owed rather than
+// held, matching the card types of package account.
+var liabilities = map[string]bool{
+	"credit_card":    true,
+	"corporate_card": true,
+}
+
+// Posting moves Amount into an account, positive for a debit and negative
+// for a credit. Postings to the user's payment accounts carry its ID so
+// balances survive the account being renamed.
+type Posting struct {
+	Account   string  `json:"account"`
+	AccountID *int    `json:"account_id,omitempty"`
+	Amount    float64 `json:"amount"`
+}
+
+type Entry struct {
+	ID          int       `json:"id"`
+	Kind        string    `json:"kind"`
+	ExpenseID   *int      `json:"expense_id,omitempty"`
+	AccountID   *int      `json:"account_id,omitempty"`
+	PostedAt    time.Time `json:"posted_at"`
+	Description string    `json:"description"`
+	Postings    []Posting `json:"postings"`
+}
+
+type DB interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func cents(f float64) int64 {
+	return int64(math.Round(f * 100))
+}
+
+// component turns a name into one level of an account name: words are
+// capitalized and joined by hyphens, anything but letters and digits
+// dropped, so "kbank visa" becomes "Kbank-Visa".
+func component(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, "-")
+}
+
+// Name joins root and the components of path, split on the tag separator.
+func Name(root, path string) string {
+	name := root
+	for _, part := range strings.Split(path, "/") {
+		if c := component(part); c != "" {
+			name += ":" + c
+		}
+	}
+	return name
+}
+
+// PaymentAccount names the ledger account of a payment account, under
+// Liabilities for cards and Assets for the rest.
+func PaymentAccount(name, accountType string) string {
+	if liabilities[accountType] {
+		return Name("Liabilities", name)
+	}
+	return Name("Assets", name)
+}
+
+// CategoryAccount names the Expenses or Income account of an expense row
+// after its category, or its first tag when it has none.
+func CategoryAccount(kind, category string, tags []string) string {
+	root := "Expenses"
+	if kind == KindIncome {
+		root = "Income"
+	}
+	label := category
+	if label == "" && len(tags) > 0 {
+		label = tags[0]
+	}
+	if name := Name(root, label); name != root {
+		return name
+	}
+	return root + ":" + Uncategorized
+}
+
+func (e Entry) validate() error {
+	if len(e.Postings) < 2 {
+		return fmt.Errorf("an entry needs at least two postings")
+	}
+	var sum int64
+	for _, p := range e.Postings {
+		sum += cents(p.Amount)
+	}
+	if sum != 0 {
+		return fmt.Errorf("postings of %q do not balance, off by %.2f", e.Description, float64(sum)/100)
+	}
+	return nil
+}
+
+// Post writes a balanced entry and its postings.
+func Post(db DB, e *Entry) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+	row := db.QueryRow(`
+		INSERT INTO journal_entries(kind, expense_id, account_id, posted_at, description)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+		`,
+		e.Kind, e.ExpenseID, e.AccountID, e.PostedAt, e.Description)
+	if err := row.Scan(&e.ID); err != nil {
+		return err
+	}
+	for _, p := range e.Postings {
+		_, err := db.Exec("INSERT INTO postings(entry_id, account, account_id, amount) VALUES ($1, $2, $3, $4)",
+			e.ID, p.Account, p.AccountID, p.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncExpenses rewrites the entries of the given expense rows from their
+// current state, so every write to the expenses table goes through here.
+func SyncExpenses(db DB, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := db.Exec("DELETE FROM journal_entries WHERE expense_id = ANY($1)", pq.Array(ids)); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT e.id, e.title, e.amount, e.kind, e.tags, e.category, e.spent_at, e.account_id, a.name, a.type
+		FROM expenses e LEFT JOIN accounts a ON a.id = e.account_id
+		WHERE e.id = ANY($1)
+		ORDER BY e.id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	var entries []Entry
+	for rows.Next() {
+		var id int
+		var amount float64
+		var kind, category string
+		var tags []string
+		var accountID *int
+		var accountName, accountType sql.NullString
+		e := Entry{}
+		if err := rows.Scan(&id, &e.Description, &amount, &kind, pq.Array(&tags), &category, &e.PostedAt,
+			&accountID, &accountName, &accountType); err != nil {
+			rows.Close()
+			return err
+		}
+		e.Kind, e.ExpenseID = kind, &id
+
+		payment := Posting{Account: Unassigned, AccountID: accountID, Amount: -amount}
+		if accountID != nil {
+			payment.Account = PaymentAccount(accountName.String, accountType.String)
+		}
+		counter := Posting{Account: CategoryAccount(kind, category, tags), Amount: amount}
+		if kind == KindIncome {
+			payment.Amount, counter.Amount = amount, -amount
+		}
+		e.Postings = []Posting{counter, payment}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		if err := Post(db, &entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncOpening rewrites the opening balance entry of a payment account,
+// balanced against Equity:Opening-Balances.
+func SyncOpening(db DB, accountID int) error {
+	if _, err := db.Exec("DELETE FROM journal_entries WHERE kind = $1 AND account_id = $2", KindOpening, accountID); err != nil {
+		return err
+	}
+
+	var name, accountType string
+	var opening float64
+	row := db.QueryRow("SELECT name, type, opening_balance FROM accounts WHERE id = $1", accountID)
+	if err := row.Scan(&name, &accountType, &opening); err != nil {
+		return err
+	}
+	if cents(opening) == 0 {
+		return nil
+	}
+
+	return Post(db, &Entry{
+		Kind:        KindOpening,
+		AccountID:   &accountID,
+		PostedAt:    time.Now(),
+		Description: "Opening balance",
+		Postings: []Posting{
+			{Account: PaymentAccount(name, accountType), AccountID: &accountID, Amount: opening},
+			{Account: OpeningBalances, Amount: -opening},
+		},
+	})
+}
+
+func ids(db DB, query string) ([]int, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Backfill writes the entries missing for expenses and opening balances
+// recorded before the ledger existed.
+func Backfill(db DB) error {
+	accounts, err := ids(db, `
+		SELECT id FROM accounts a
+		WHERE opening_balance <> 0
+		AND NOT EXISTS (SELECT 1 FROM journal_entries j WHERE j.kind = 'opening' AND j.account_id = a.id)`)
+	if err != nil {
+		return err
+	}
+	for _, id := range accounts {
+		if err := SyncOpening(db, id); err != nil {
+			return err
+		}
+	}
+
+	expenses, err := ids(db, `
+		SELECT id FROM expenses e
+		WHERE NOT EXISTS (SELECT 1 FROM journal_entries j WHERE j.expense_id = e.id)`)
+	if err != nil {
+		return err
+	}
+	return SyncExpenses(db, expenses)
+}
+
+// Transfer moves money between two of the user's own accounts, such as
+// paying off a card from a bank account.
+type Transfer struct {
+	FromAccountID int        `json:"from_account_id"`
+	ToAccountID   int        `json:"to_account_id"`
+	Amount        float64    `json:"amount"`
+	Date          *time.Time `json:"date,omitempty"`
+	Note          string     `json:"note"`
+}
+
+// Balance is one line of a trial balance.
+type Balance struct {
+	Account string  `json:"account"`
+	Debit   float64 `json:"debit"`
+	Credit  float64 `json:"credit"`
+	Balance float64 `json:"balance"`
+}
+
+// TrialBalance is the trial balance of the entries in one currency. Amounts
+// in different currencies are never added together.
+type TrialBalance struct {
+	Currency string    `json:"currency"`
+	Accounts []Balance `json:"accounts"`
+	Debit    float64   `json:"debit"`
+	Credit   float64   `json:"credit"`
+	Balanced bool      `json:"balanced"`
+}
@@ -0,0 +1,28 @@
+//go:build unit
+
+package ledger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountNames(t *testing.T) {
+	assert.Equal(t, "Expenses:Food:Dessert", CategoryAccount(KindExpense, "", []string{"food/dessert", "party"}))
+	assert.Equal(t, "Expenses:Eating-Out", CategoryAccount(KindExpense, "eating out", []string{"food"}))
+	assert.Equal(t, "Expenses:Uncategorized", CategoryAccount(KindExpense, "", nil))
+	assert.Equal(t, "Income:Salary", CategoryAccount(KindIncome, "salary", nil))
+	assert.Equal(t, "Liabilities:KBank-Visa", PaymentAccount("KBank Visa", "credit_card"))
+	assert.Equal(t, "Assets:Wallet", PaymentAccount("wallet", "cash"))
+}
+
+func TestValidate(t *testing.T) {
+	balanced := Entry{Postings: []Posting{{Account: "Expenses:Food", Amount: 0.1}, {Account: "Expenses:Food", Amount: 0.2}, {Account: Unassigned, Amount: -0.3}}}
+	unbalanced := Entry{Description: "lunch", Postings: []Posting{{Account: "Expenses:Food", Amount: 120}, {Account: Unassigned, Amount: -100}}}
+	single := Entry{Postings: []Posting{{Account: "Expenses:Food", Amount: 0}}}
+
+	assert.NoError(t, balanced.validate())
+	assert.EqualError(t, unbalanced.validate(), `postings of "lunch" do not balance, off by 20.00`)
+	assert.Error(t, single.validate())
+}
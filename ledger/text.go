@@ -0,0 +1,467 @@
+package ledger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Plain-text accounting formats read and written by Write and Parse.
+const (
+	FormatLedger    = "ledger"
+	FormatBeancount = "beancount"
+)
+
+// Metadata keys written on every transaction so a file reads back into the
+// same expenses. Tags are comma separated.
+const (
+	metaTags     = "tags"
+	metaCategory = "category"
+	metaTime     = "time"
+)
+
+var roots = map[string]bool{
+	"Assets":      true,
+	"Liabilities": true,
+	"Equity":      true,
+	"Income":      true,
+	"Expenses":    true,
+}
+
+// Transaction is an expense or income row as it appears in a plain-text
+// file: Account is its Expenses or Income account and Payment the account
+// it was paid from or into. Amount is positive for money spent or received.
+type Transaction struct {
+	Date     time.Time
+	Payee    string
+	Note     string
+	Tags     []string
+	Category string
+	Kind     string
+	Account  string
+	Payment  string
+	Amount   float64
+	Currency string
+	// Line is where the transaction starts in a parsed file.
+	Line int
+}
+
+// ValidAccount reports whether name is a colon separated account name
+// under one of the five roots, such as Expenses:Food:Dessert.
+func ValidAccount(name string) bool {
+	parts := strings.Split(name, ":")
+	if len(parts) < 2 || !roots[parts[0]] {
+		return false
+	}
+	for _, p := range parts[1:] {
+		if p == "" || component(p) != p {
+			return false
+		}
+	}
+	return true
+}
+
+func root(account string) string {
+	if i := strings.Index(account, ":"); i >= 0 {
+		return account[:i]
+	}
+	return account
+}
+
+func format(amount float64, currency string) string {
+	s := strconv.FormatFloat(float64(cents(amount))/100, 'f', 2, 64)
+	if currency != "" {
+		s += " " + currency
+	}
+	return s
+}
+
+// postings returns the two sides of a transaction in the order they are
+// written, the side that is debited first.
+func (t Transaction) postings() [2]Posting {
+	if t.Kind == KindIncome {
+		return [2]Posting{{Account: t.Payment, Amount: t.Amount}, {Account: t.Account, Amount: -t.Amount}}
+	}
+	return [2]Posting{{Account: t.Account, Amount: t.Amount}, {Account: t.Payment, Amount: -t.Amount}}
+}
+
+// metadata lists the key, value pairs written for a transaction.
+func (t Transaction) metadata() [][2]string {
+	var meta [][2]string
+	if len(t.Tags) > 0 {
+		meta = append(meta, [2]string{metaTags, strings.Join(t.Tags, ", ")})
+	}
+	if t.Category != "" {
+		meta = append(meta, [2]string{metaCategory, t.Category})
+	}
+	if d := t.Date.UTC(); d != d.Truncate(24*time.Hour) {
+		meta = append(meta, [2]string{metaTime, d.Format("15:04:05.999999999")})
+	}
+	return meta
+}
+
+func noteLines(note string) []string {
+	if note == "" {
+		return nil
+	}
+	return strings.Split(note, "\n")
+}
+
+// Write renders the transactions in the given format, oldest first.
+// Beancount files also get an open directive for every account used.
+func Write(w io.Writer, f string, txs []Transaction) error {
+	sorted := append([]Transaction(nil), txs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	b := bufio.NewWriter(w)
+	switch f {
+	case FormatLedger:
+		for i, t := range sorted {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(b, "%s %s\n", t.Date.UTC().Format("2006-01-02"), t.Payee)
+			for _, line := range noteLines(t.Note) {
+				fmt.Fprintf(b, "    ; %s\n", line)
+			}
+			for _, m := range t.metadata() {
+				fmt.Fprintf(b, "    ; %s: %s\n", m[0], m[1])
+			}
+			for _, p := range t.postings() {
+				fmt.Fprintf(b, "    %s  %s\n", p.Account, format(p.Amount, t.Currency))
+			}
+		}
+	case FormatBeancount:
+		opened := map[string]bool{}
+		var accounts []string
+		for _, t := range sorted {
+			for _, p := range t.postings() {
+				if !opened[p.Account] {
+					opened[p.Account] = true
+					accounts = append(accounts, p.Account)
+				}
+			}
+		}
+		sort.Strings(accounts)
+		for _, a := range accounts {
+			fmt.Fprintf(b, "%s open %s\n", sorted[0].Date.UTC().Format("2006-01-02"), a)
+		}
+		for _, t := range sorted {
+			fmt.Fprintf(b, "\n%s * %s \"\"\n", t.Date.UTC().Format("2006-01-02"), strconv.Quote(t.Payee))
+			for _, line := range noteLines(t.Note) {
+				fmt.Fprintf(b, "  ; %s\n", line)
+			}
+			for _, m := range t.metadata() {
+				fmt.Fprintf(b, "  %s: %s\n", m[0], strconv.Quote(m[1]))
+			}
+			for _, p := range t.postings() {
+				fmt.Fprintf(b, "  %s  %s\n", p.Account, format(p.Amount, t.Currency))
+			}
+		}
+	default:
+		return fmt.Errorf("unknown format %q, use %s or %s", f, FormatLedger, FormatBeancount)
+	}
+	return b.Flush()
+}
+
+// parser collects the lines of one transaction at a time.
+type parser struct {
+	format   string
+	txs      []Transaction
+	current  *Transaction
+	notes    []string
+	postings []Posting
+	// elided marks the postings written without an amount.
+	elided []bool
+}
+
+// Parse reads the transactions of a file written by Write, or by hand in
+// the same subset of the format: every transaction has exactly one
+// Expenses or Income posting and one payment posting, one of which may
+// leave its amount out. Directives other than transactions are skipped.
+func Parse(r io.Reader, f string) ([]Transaction, error) {
+	if f != FormatLedger && f != FormatBeancount {
+		return nil, fmt.Errorf("unknown format %q, use %s or %s", f, FormatLedger, FormatBeancount)
+	}
+	p := parser{format: f}
+	s := bufio.NewScanner(r)
+	n := 0
+	for s.Scan() {
+		n++
+		if err := p.line(n, strings.TrimRight(s.Text(), " \t\r")); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.flush(); err != nil {
+		return nil, err
+	}
+	return p.txs, nil
+}
+
+func (p *parser) line(n int, line string) error {
+	if line == "" {
+		return p.flush()
+	}
+	if line[0] == ' ' || line[0] == '\t' {
+		if p.current == nil {
+			// Indented lines of a directive we skip.
+			return nil
+		}
+		return lineError(n, p.detail(strings.TrimSpace(line)))
+	}
+	if err := p.flush(); err != nil {
+		return err
+	}
+	if line[0] < '0' || line[0] > '9' {
+		// Comments, options and directives such as account or include.
+		return nil
+	}
+	return lineError(n, p.header(n, line))
+}
+
+func lineError(n int, err error) error {
+	if err != nil {
+		return fmt.Errorf("line %d: %s", n, err)
+	}
+	return nil
+}
+
+func (p *parser) header(n int, line string) error {
+	fields := strings.SplitN(line, " ", 2)
+	date, err := parseDate(fields[0])
+	if err != nil {
+		return err
+	}
+	rest := ""
+	if len(fields) == 2 {
+		rest = strings.TrimSpace(fields[1])
+	}
+	t := Transaction{Date: date, Line: n}
+
+	if p.format == FormatBeancount {
+		flag, rest2 := rest, ""
+		if i := strings.IndexAny(rest, " \t"); i >= 0 {
+			flag, rest2 = rest[:i], strings.TrimSpace(rest[i:])
+		}
+		if flag != "*" && flag != "!" && flag != "txn" {
+			// open, close, balance, price and the other dated directives.
+			return nil
+		}
+		var strs []string
+		for strings.HasPrefix(rest2, `"`) {
+			s, err := strconv.QuotedPrefix(rest2)
+			if err != nil {
+				return fmt.Errorf("invalid string %s", rest2)
+			}
+			v, _ := strconv.Unquote(s)
+			strs = append(strs, v)
+			rest2 = strings.TrimSpace(rest2[len(s):])
+		}
+		for _, word := range strings.Fields(rest2) {
+			if strings.HasPrefix(word, "#") {
+				t.Tags = append(t.Tags, word[1:])
+			}
+		}
+		switch {
+		case len(strs) == 1:
+			t.Payee = strs[0]
+		case len(strs) >= 2 && strs[0] != "":
+			t.Payee = strs[0]
+		case len(strs) >= 2:
+			t.Payee = strs[1]
+		}
+	} else {
+		// A note after the payee starts with a tab or two spaces and ;.
+		for _, sep := range []string{"\t;", "  ;"} {
+			if i := strings.Index(rest, sep); i >= 0 {
+				p.notes = append(p.notes, strings.TrimSpace(rest[i+len(sep):]))
+				rest = strings.TrimSpace(rest[:i])
+			}
+		}
+		if strings.HasPrefix(rest, "* ") || strings.HasPrefix(rest, "! ") {
+			rest = strings.TrimSpace(rest[2:])
+		}
+		if strings.HasPrefix(rest, "(") {
+			if i := strings.Index(rest, ")"); i >= 0 {
+				rest = strings.TrimSpace(rest[i+1:])
+			}
+		}
+		t.Payee = rest
+	}
+	p.current = &t
+	return nil
+}
+
+// parseDate accepts ledger's slashes as well as dashes, and ignores a
+// ledger auxiliary date.
+func parseDate(s string) (time.Time, error) {
+	if i := strings.Index(s, "="); i >= 0 {
+		s = s[:i]
+	}
+	t, err := time.Parse("2006-01-02", strings.ReplaceAll(s, "/", "-"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+// meta splits a metadata line into its key and value, if it is one.
+func (p *parser) meta(line string) (string, string, bool) {
+	i := strings.Index(line, ":")
+	if i <= 0 {
+		return "", "", false
+	}
+	key, value := line[:i], strings.TrimSpace(line[i+1:])
+	if p.format == FormatBeancount {
+		if !unicode.IsLower(rune(key[0])) || strings.ContainsAny(key, " \t") {
+			return "", "", false
+		}
+		if v, err := strconv.Unquote(value); err == nil {
+			value = v
+		}
+		return key, value, true
+	}
+	switch key {
+	case metaTags, metaCategory, metaTime:
+		return key, value, true
+	}
+	return "", "", false
+}
+
+func (p *parser) detail(line string) error {
+	t := p.current
+	if strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+		comment := strings.TrimPrefix(line[1:], " ")
+		if p.format == FormatLedger {
+			if key, value, ok := p.meta(comment); ok {
+				return p.apply(t, key, value)
+			}
+		}
+		p.notes = append(p.notes, comment)
+		return nil
+	}
+	if p.format == FormatBeancount {
+		if key, value, ok := p.meta(line); ok {
+			return p.apply(t, key, value)
+		}
+	}
+
+	if i := strings.Index(line, ";"); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	account, amount := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		account, amount = line[:i], strings.TrimSpace(line[i:])
+	}
+	if !ValidAccount(account) {
+		return fmt.Errorf("invalid account %q", account)
+	}
+	posting := Posting{Account: account}
+	if amount != "" {
+		value, currency, err := parseAmount(amount)
+		if err != nil {
+			return err
+		}
+		if t.Currency == "" {
+			t.Currency = currency
+		}
+		posting.Amount = value
+	}
+	p.postings = append(p.postings, posting)
+	p.elided = append(p.elided, amount == "")
+	return nil
+}
+
+func (p *parser) apply(t *Transaction, key, value string) error {
+	switch key {
+	case metaTags:
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+	case metaCategory:
+		t.Category = value
+	case metaTime:
+		clock, err := time.Parse("15:04:05", value)
+		if err != nil {
+			return fmt.Errorf("invalid time %q", value)
+		}
+		t.Date = t.Date.Add(clock.Sub(clock.Truncate(24 * time.Hour)))
+	}
+	return nil
+}
+
+// parseAmount reads "1,234.50 THB", "THB 1234.50" or a bare number.
+func parseAmount(s string) (float64, string, error) {
+	var number, currency string
+	for _, f := range strings.Fields(s) {
+		if strings.ContainsAny(f[:1], "-+.0123456789") {
+			number = f
+		} else {
+			currency = f
+		}
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(number, ",", ""), 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid amount %q", s)
+	}
+	return value, currency, nil
+}
+
+// flush finishes the current transaction, filling in an elided amount and
+// working out which posting is the payment side.
+func (p *parser) flush() error {
+	t := p.current
+	postings, elided, notes := p.postings, p.elided, p.notes
+	p.current, p.postings, p.elided, p.notes = nil, nil, nil, nil
+	if t == nil {
+		return nil
+	}
+	fail := func(format string, args ...interface{}) error {
+		return lineError(t.Line, fmt.Errorf(format, args...))
+	}
+
+	if len(postings) != 2 {
+		return fail("transaction %q needs exactly two postings", t.Payee)
+	}
+	switch {
+	case elided[0] && elided[1]:
+		return fail("transaction %q has no amount", t.Payee)
+	case elided[0]:
+		postings[0].Amount = -postings[1].Amount
+	case elided[1]:
+		postings[1].Amount = -postings[0].Amount
+	}
+	if cents(postings[0].Amount+postings[1].Amount) != 0 {
+		return fail("transaction %q does not balance", t.Payee)
+	}
+
+	category, payment := postings[0], postings[1]
+	if r := root(payment.Account); r == "Expenses" || r == "Income" {
+		category, payment = payment, category
+	}
+	switch root(category.Account) {
+	case "Expenses":
+		t.Kind, t.Amount = KindExpense, category.Amount
+	case "Income":
+		t.Kind, t.Amount = KindIncome, -category.Amount
+	default:
+		return fail("transaction %q has no Expenses or Income posting", t.Payee)
+	}
+	if r := root(payment.Account); r == "Expenses" || r == "Income" {
+		return fail("transaction %q has no payment posting", t.Payee)
+	}
+	t.Account, t.Payment = category.Account, payment.Account
+	t.Note = strings.Join(notes, "\n")
+	p.txs = append(p.txs, *t)
+	return nil
+}
@@ -0,0 +1,164 @@
+//go:build unit
+
+package ledger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var transactions = []Transaction{
+	{
+		Date:     time.Date(2024, 1, 5, 12, 30, 0, 0, time.UTC),
+		Payee:    `lunch at "Somtam"`,
+		Note:     "with the team\nsplit later",
+		Tags:     []string{"food", "work/meals"},
+		Kind:     KindExpense,
+		Account:  "Expenses:Food",
+		Payment:  "Liabilities:KBank-Visa",
+		Amount:   120.5,
+		Currency: "THB",
+	},
+	{
+		Date:     time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC),
+		Payee:    "salary",
+		Category: "salary",
+		Kind:     KindIncome,
+		Account:  "Income:Salary",
+		Payment:  "Assets:Savings",
+		Amount:   50000,
+		Currency: "THB",
+	},
+}
+
+func TestWrite(t *testing.T) {
+	t.Run("Write Ledger", func(t *testing.T) {
+		var b bytes.Buffer
+		expect := `2024-01-05 lunch at "Somtam"
+    ; with the team
+    ; split later
+    ; tags: food, work/meals
+    ; time: 12:30:00
+    Expenses:Food  120.50 THB
+    Liabilities:KBank-Visa  -120.50 THB
+
+2024-01-25 salary
+    ; category: salary
+    Assets:Savings  50000.00 THB
+    Income:Salary  -50000.00 THB
+`
+
+		err := Write(&b, FormatLedger, transactions)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expect, b.String())
+	})
+
+	t.Run("Write Beancount", func(t *testing.T) {
+		var b bytes.Buffer
+		expect := `2024-01-05 open Assets:Savings
+2024-01-05 open Expenses:Food
+2024-01-05 open Income:Salary
+2024-01-05 open Liabilities:KBank-Visa
+
+2024-01-05 * "lunch at \"Somtam\"" ""
+  ; with the team
+  ; split later
+  tags: "food, work/meals"
+  time: "12:30:00"
+  Expenses:Food  120.50 THB
+  Liabilities:KBank-Visa  -120.50 THB
+
+2024-01-25 * "salary" ""
+  category: "salary"
+  Assets:Savings  50000.00 THB
+  Income:Salary  -50000.00 THB
+`
+
+		err := Write(&b, FormatBeancount, transactions)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expect, b.String())
+	})
+}
+
+func TestParse(t *testing.T) {
+	for _, f := range []string{FormatLedger, FormatBeancount} {
+		t.Run("Parse Written "+f+" Should Round Trip", func(t *testing.T) {
+			var b bytes.Buffer
+			assert.NoError(t, Write(&b, f, transactions))
+
+			txs, err := Parse(&b, f)
+
+			assert.NoError(t, err)
+			if assert.Len(t, txs, 2) {
+				for i := range txs {
+					txs[i].Line = 0
+				}
+				assert.Equal(t, transactions, txs)
+			}
+		})
+	}
+
+	t.Run("Parse Hand Written Ledger", func(t *testing.T) {
+		file := `; personal book
+account Expenses:Food
+
+2024/02/01 * (42) Coffee Shop  ; morning
+    Expenses:Food    65 THB
+    Assets:Cash
+`
+
+		txs, err := Parse(strings.NewReader(file), FormatLedger)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Transaction{{
+			Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Payee: "Coffee Shop", Note: "morning",
+			Kind: KindExpense, Account: "Expenses:Food", Payment: "Assets:Cash", Amount: 65, Currency: "THB", Line: 4,
+		}}, txs)
+	})
+
+	t.Run("Parse Hand Written Beancount", func(t *testing.T) {
+		file := `option "operating_currency" "THB"
+2024-01-01 open Assets:Cash THB
+
+2024-02-01 * "Coffee Shop" "latte" #coffee
+  Assets:Cash  -1,065.00 THB
+  Expenses:Food
+`
+
+		txs, err := Parse(strings.NewReader(file), FormatBeancount)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Transaction{{
+			Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Payee: "Coffee Shop", Tags: []string{"coffee"},
+			Kind: KindExpense, Account: "Expenses:Food", Payment: "Assets:Cash", Amount: 1065, Currency: "THB", Line: 4,
+		}}, txs)
+	})
+
+	t.Run("Parse Unbalanced Transaction Should Return Error", func(t *testing.T) {
+		file := `2024-02-01 coffee
+    Expenses:Food  65 THB
+    Assets:Cash  -60 THB
+`
+
+		_, err := Parse(strings.NewReader(file), FormatLedger)
+
+		assert.EqualError(t, err, `line 1: transaction "coffee" does not balance`)
+	})
+
+	t.Run("Parse Transfer Should Return Error", func(t *testing.T) {
+		file := `2024-02-01 pay card
+    Liabilities:Visa  500 THB
+    Assets:Cash
+`
+
+		_, err := Parse(strings.NewReader(file), FormatLedger)
+
+		assert.EqualError(t, err, `line 1: transaction "pay card" has no Expenses or Income posting`)
+	})
+}
@@ -0,0 +1,177 @@
+package merchant
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/lib/pq"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+func bindMerchant(c *gin.Context) (Merchant, bool) {
+	var m Merchant
+	if err := c.BindJSON(&m); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return m, false
+	}
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return m, false
+	}
+	aliases := []string{}
+	for _, a := range m.Aliases {
+		if a = strings.TrimSpace(a); a != "" {
+			aliases = append(aliases, a)
+		}
+	}
+	m.Aliases = aliases
+	return m, true
+}
+
+func (h *handler) find(c *gin.Context) (Merchant, bool) {
+	var m Merchant
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return m, false
+	}
+
+	row := h.DB.QueryRow("SELECT id, name, aliases FROM merchants WHERE id = $1", id)
+	if err := scanMerchant(row, &m); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "merchant not found"})
+		return m, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return m, false
+	}
+	return m, true
+}
+
+func (h *handler) Create(c *gin.Context) {
+	m, ok := bindMerchant(c)
+	if !ok {
+		return
+	}
+
+	row := h.DB.QueryRow("INSERT INTO merchants(name, aliases) VALUES ($1, $2) RETURNING id", m.Name, pq.Array(m.Aliases))
+	if err := row.Scan(&m.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, m)
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT id, name, aliases FROM merchants ORDER BY name")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	merchants := []Merchant{}
+	for rows.Next() {
+		var m Merchant
+		if err := scanMerchant(rows, &m); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		merchants = append(merchants, m)
+	}
+
+	c.JSON(http.StatusOK, merchants)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	if m, ok := h.find(c); ok {
+		c.JSON(http.StatusOK, m)
+	}
+}
+
+func (h *handler) Update(c *gin.Context) {
+	existing, ok := h.find(c)
+	if !ok {
+		return
+	}
+	m, ok := bindMerchant(c)
+	if !ok {
+		return
+	}
+	m.ID = existing.ID
+
+	if _, err := h.DB.Exec("UPDATE merchants SET name=$2, aliases=$3 WHERE id=$1", m.ID, m.Name, pq.Array(m.Aliases)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, m)
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	m, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM merchants WHERE id = $1", m.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Match backfills expenses without a merchant whose titles match this one,
+// leaving reconciled expenses as they are.
+func (h *handler) Match(c *gin.Context) {
+	m, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.DB.Query("SELECT id, title FROM expenses WHERE merchant_id IS NULL AND " + ledger.Unreconciled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	matcher := Matcher{m}
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if _, ok := matcher.Match(title); ok {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	if len(ids) > 0 {
+		if _, err := h.DB.Exec("UPDATE expenses SET merchant_id = $1 WHERE id = ANY($2) AND "+ledger.Unreconciled, m.ID, pq.Array(ids)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"matched": ids})
+}
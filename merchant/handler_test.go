@@ -0,0 +1,108 @@
+//go:build unit
+
+package merchant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateMerchant(t *testing.T) {
+	t.Run("Create Merchant Without Name Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/merchants", strings.NewReader(`{"name": "  "}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/merchants", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Create Merchant Should Return Created", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/merchants", strings.NewReader(`{"name": "Starbucks", "aliases": ["SBUX", " "]}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("INSERT INTO merchants").
+			WithArgs("Starbucks", pq.Array([]string{"SBUX"})).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/merchants", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `{"id":1,"name":"Starbucks","aliases":["SBUX"]}`, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestMatchMerchant(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodPost, "/merchants/1/match", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT id, name, aliases FROM merchants WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "aliases"}).AddRow(1, "Starbucks", pq.Array([]string{"SBUX"})))
+	mock.ExpectQuery("SELECT id, title FROM expenses WHERE merchant_id IS NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).
+			AddRow(3, "SBUX latte").
+			AddRow(4, "lunch").
+			AddRow(5, "Starbucks cold brew"))
+	mock.ExpectExec("UPDATE expenses SET merchant_id").
+		WithArgs(1, pq.Array([]int{3, 5})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.POST("/merchants/:id/match", h.Match)
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"matched":[3,5]}`, strings.TrimSpace(rec.Body.String()))
+}
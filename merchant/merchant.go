@@ -0,0 +1,88 @@
+package merchant
+
+import (
+	"database/sql"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+type Merchant struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func scanMerchant(row interface{ Scan(...interface{}) error }, m *Merchant) error {
+	return row.Scan(&m.ID, &m.Name, pq.Array(&m.Aliases))
+}
+
+// normalize lowercases s and turns everything but letters, digits and
+// combining marks into single spaces, so "STARBUCKS #123" and
+// "Starbucks-123" compare alike. Marks are kept for scripts such as Thai.
+func normalize(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// Matcher finds the merchant an expense title refers to.
+type Matcher []Merchant
+
+// Match returns the merchant whose name or one of whose aliases appears
+// in title as whole words. When several do, the longest name wins so
+// "starbucks reserve" beats "starbucks".
+func (m Matcher) Match(title string) (Merchant, bool) {
+	padded := " " + normalize(title) + " "
+	var best Merchant
+	var bestLen int
+	for _, merchant := range m {
+		for _, name := range append([]string{merchant.Name}, merchant.Aliases...) {
+			n := normalize(name)
+			if n == "" || len(n) <= bestLen {
+				continue
+			}
+			if strings.Contains(padded, " "+n+" ") {
+				best, bestLen = merchant, len(n)
+			}
+		}
+	}
+	return best, bestLen > 0
+}
+
+func Load(db Querier) (Matcher, error) {
+	rows, err := db.Query("SELECT id, name, aliases FROM merchants ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var m Matcher
+	for rows.Next() {
+		var merchant Merchant
+		if err := scanMerchant(rows, &merchant); err != nil {
+			return nil, err
+		}
+		m = append(m, merchant)
+	}
+	return m, rows.Err()
+}
+
+// Find returns the ID of the merchant matching title, or nil when none
+// does.
+func Find(db Querier, title string) (*int, error) {
+	m, err := Load(db)
+	if err != nil {
+		return nil, err
+	}
+	if merchant, ok := m.Match(title); ok {
+		return &merchant.ID, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,35 @@
+//go:build unit
+
+package merchant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	m := Matcher{
+		{ID: 1, Name: "Starbucks", Aliases: []string{"SBUX"}},
+		{ID: 2, Name: "Starbucks Reserve"},
+		{ID: 3, Name: "7-Eleven", Aliases: []string{"เซเว่น"}},
+	}
+
+	cases := []struct {
+		title string
+		id    int
+	}{
+		{"STARBUCKS #1234 BANGKOK", 1},
+		{"sbux latte", 1},
+		{"Starbucks Reserve - Iconsiam", 2},
+		{"7 ELEVEN สาขา 123", 3},
+		{"ซื้อของ เซเว่น", 3},
+		{"starbucksy", 0},
+		{"lunch", 0},
+	}
+	for _, tc := range cases {
+		merchant, ok := m.Match(tc.title)
+		assert.Equal(t, tc.id != 0, ok, tc.title)
+		assert.Equal(t, tc.id, merchant.ID, tc.title)
+	}
+}
@@ -0,0 +1,51 @@
+package query
+
+import "fmt"
+
+// Node is a parsed filter expression.
+type Node interface {
+	node()
+}
+
+type And struct {
+	Left, Right Node
+}
+
+type Or struct {
+	Left, Right Node
+}
+
+type Not struct {
+	Expr Node
+}
+
+// Comparison is a single field condition such as amount>=100 or tag:food.
+type Comparison struct {
+	Pos   int
+	Field string
+	Op    string
+	Value string
+
+	preds []predicate
+}
+
+func (*And) node()        {}
+func (*Or) node()         {}
+func (*Not) node()        {}
+func (*Comparison) node() {}
+
+// predicate is a SQL fragment written by this package, never by the user;
+// each %s is replaced by a numbered placeholder bound to the matching arg.
+type predicate struct {
+	sql  string
+	args []interface{}
+}
+
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
@@ -0,0 +1,47 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+type compiler struct {
+	offset int
+	args   []interface{}
+}
+
+// Compile turns a parsed expression into a SQL condition over the expenses
+// table. Every value is bound as an argument; placeholders start at
+// offset+1 so the condition can be appended to a query that already has
+// offset arguments.
+func Compile(n Node, offset int) (string, []interface{}) {
+	c := &compiler{offset: offset}
+	return c.compile(n), c.args
+}
+
+func (c *compiler) bind(p predicate) string {
+	sql := p.sql
+	for _, arg := range p.args {
+		c.args = append(c.args, arg)
+		sql = strings.Replace(sql, "%s", fmt.Sprintf("$%d", c.offset+len(c.args)), 1)
+	}
+	return sql
+}
+
+func (c *compiler) compile(n Node) string {
+	switch n := n.(type) {
+	case *And:
+		return "(" + c.compile(n.Left) + " AND " + c.compile(n.Right) + ")"
+	case *Or:
+		return "(" + c.compile(n.Left) + " OR " + c.compile(n.Right) + ")"
+	case *Not:
+		return "NOT " + c.compile(n.Expr)
+	case *Comparison:
+		parts := make([]string, len(n.preds))
+		for i, p := range n.preds {
+			parts[i] = c.bind(p)
+		}
+		return "(" + strings.Join(parts, " AND ") + ")"
+	}
+	panic(fmt.Sprintf("query: unexpected node %T", n))
+}
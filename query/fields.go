@@ -0,0 +1,205 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jsritawan/assessment/tag"
+)
+
+type fieldFunc func(op, value string) ([]predicate, error)
+
+// opError is returned by a fieldFunc for an operator the field does not
+// support, so the parser can point at the operator rather than the value.
+type opError struct {
+	op, field string
+}
+
+func (e *opError) Error() string {
+	return fmt.Sprintf("operator %q is not supported for %s", e.op, e.field)
+}
+
+var fields = map[string]fieldFunc{
+	"id":        numberField("id"),
+	"amount":    numberField("amount"),
+	"title":     textField("title"),
+	"note":      textField("note"),
+	"category":  textField("category"),
+	"tag":       tagField,
+	"spent":     dateField("spent_at"),
+	"merchant":  numberField("merchant_id"),
+	"account":   numberField("account_id"),
+	"status":    textField("status"),
+	"deduction": numberField("deduction_id"),
+}
+
+func fieldNames() string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func splitRange(value string) (string, string, bool) {
+	i := strings.Index(value, "..")
+	if i < 0 {
+		return "", "", false
+	}
+	return value[:i], value[i+2:], true
+}
+
+func parseNumber(value string) (float64, error) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	return n, nil
+}
+
+func numberField(column string) fieldFunc {
+	return func(op, value string) ([]predicate, error) {
+		switch op {
+		case ":", "=":
+			if lo, hi, ok := splitRange(value); ok {
+				var preds []predicate
+				if lo != "" {
+					n, err := parseNumber(lo)
+					if err != nil {
+						return nil, err
+					}
+					preds = append(preds, predicate{column + " >= %s", []interface{}{n}})
+				}
+				if hi != "" {
+					n, err := parseNumber(hi)
+					if err != nil {
+						return nil, err
+					}
+					preds = append(preds, predicate{column + " <= %s", []interface{}{n}})
+				}
+				if len(preds) == 0 {
+					return nil, fmt.Errorf("range %q has no bounds", value)
+				}
+				return preds, nil
+			}
+			n, err := parseNumber(value)
+			if err != nil {
+				return nil, err
+			}
+			return []predicate{{column + " = %s", []interface{}{n}}}, nil
+		case "!=", ">", ">=", "<", "<=":
+			n, err := parseNumber(value)
+			if err != nil {
+				return nil, err
+			}
+			if op == "!=" {
+				op = "<>"
+			}
+			return []predicate{{column + " " + op + " %s", []interface{}{n}}}, nil
+		}
+		return nil, &opError{op, column}
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func textField(column string) fieldFunc {
+	col := "coalesce(" + column + ", '')"
+	return func(op, value string) ([]predicate, error) {
+		switch op {
+		case ":", "=":
+			return []predicate{{"lower(" + col + ") = lower(%s)", []interface{}{value}}}, nil
+		case "!=":
+			return []predicate{{"lower(" + col + ") <> lower(%s)", []interface{}{value}}}, nil
+		case "~":
+			return []predicate{{col + " ILIKE %s", []interface{}{"%" + escapeLike(value) + "%"}}}, nil
+		}
+		return nil, &opError{op, column}
+	}
+}
+
+// tagField matches a tag together with its descendants, so tag:food also
+// finds expenses tagged food/dessert.
+func tagField(op, value string) ([]predicate, error) {
+	name := tag.Clean(value)
+	within := "EXISTS (SELECT 1 FROM unnest(tags) t WHERE t = %s OR t LIKE %s)"
+	switch op {
+	case ":", "=":
+		return []predicate{{within, []interface{}{name, tag.DescendantPattern(name)}}}, nil
+	case "!=":
+		return []predicate{{"NOT " + within, []interface{}{name, tag.DescendantPattern(name)}}}, nil
+	case "~":
+		return []predicate{{"EXISTS (SELECT 1 FROM unnest(tags) t WHERE t ILIKE %s)", []interface{}{"%" + escapeLike(value) + "%"}}}, nil
+	}
+	return nil, &opError{op, "tag"}
+}
+
+// parseDate accepts a day, a month or a year and returns the half-open
+// interval [start, end) that it covers.
+func parseDate(value string) (time.Time, time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, t.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.Parse("2006-01", value); err == nil {
+		return t, t.AddDate(0, 1, 0), nil
+	}
+	if t, err := time.Parse("2006", value); err == nil {
+		return t, t.AddDate(1, 0, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%q is not a date, use YYYY, YYYY-MM or YYYY-MM-DD", value)
+}
+
+func dateField(column string) fieldFunc {
+	return func(op, value string) ([]predicate, error) {
+		switch op {
+		case ":", "=":
+			lo, hi, ok := splitRange(value)
+			if !ok {
+				lo, hi = value, value
+			}
+			var preds []predicate
+			if lo != "" {
+				start, _, err := parseDate(lo)
+				if err != nil {
+					return nil, err
+				}
+				preds = append(preds, predicate{column + " >= %s", []interface{}{start}})
+			}
+			if hi != "" {
+				_, end, err := parseDate(hi)
+				if err != nil {
+					return nil, err
+				}
+				preds = append(preds, predicate{column + " < %s", []interface{}{end}})
+			}
+			if len(preds) == 0 {
+				return nil, fmt.Errorf("range %q has no bounds", value)
+			}
+			return preds, nil
+		}
+
+		start, end, err := parseDate(value)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "!=":
+			return []predicate{{"(" + column + " < %s OR " + column + " >= %s)", []interface{}{start, end}}}, nil
+		case ">":
+			return []predicate{{column + " >= %s", []interface{}{end}}}, nil
+		case ">=":
+			return []predicate{{column + " >= %s", []interface{}{start}}}, nil
+		case "<":
+			return []predicate{{column + " < %s", []interface{}{start}}}, nil
+		case "<=":
+			return []predicate{{column + " < %s", []interface{}{end}}}, nil
+		}
+		return nil, &opError{op, "spent"}
+	}
+}
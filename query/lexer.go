@@ -0,0 +1,84 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`()"=!<>:~`, r)
+}
+
+// lex splits s into tokens. Positions are 1-based and counted in runes so
+// they line up with what the user typed, Thai text included.
+func lex(s string) ([]token, error) {
+	src := []rune(s)
+	var toks []token
+
+	i := 0
+	for i < len(src) {
+		r := src[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", pos})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", pos})
+			i++
+		case r == '"':
+			var b strings.Builder
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				b.WriteRune(src[j])
+				j++
+			}
+			if j >= len(src) {
+				return nil, &Error{Pos: pos, Msg: "unterminated string"}
+			}
+			toks = append(toks, token{tokString, b.String(), pos})
+			i = j + 1
+		case strings.ContainsRune("=!<>:~", r):
+			op := string(r)
+			if (r == '>' || r == '<' || r == '!') && i+1 < len(src) && src[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &Error{Pos: pos, Msg: `unexpected "!", did you mean "!="`}
+			}
+			toks = append(toks, token{tokOp, op, pos})
+			i += len(op)
+		default:
+			j := i
+			for j < len(src) && !isDelimiter(src[j]) {
+				j++
+			}
+			toks = append(toks, token{tokWord, string(src[i:j]), pos})
+			i = j
+		}
+	}
+
+	return append(toks, token{tokEOF, "", len(src) + 1}), nil
+}
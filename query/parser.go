@@ -0,0 +1,167 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func isKeyword(t token, keyword string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, keyword)
+}
+
+func unexpected(t token, want string) *Error {
+	if t.kind == tokEOF {
+		return &Error{Pos: t.pos, Msg: "unexpected end of query, expected " + want}
+	}
+	return &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q, expected %s", t.text, want)}
+}
+
+// Parse reads a filter expression such as
+//
+//	amount>=100 and tag:food and not tag:beverage and spent:2024-01..2024-03
+//
+// Conditions next to each other without a keyword are joined with and.
+func Parse(s string) (Node, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, &Error{Pos: 1, Msg: "empty query"}
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, unexpected(t, `"and", "or" or end of query`)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) startsCondition() bool {
+	t := p.peek()
+	if t.kind == tokLParen {
+		return true
+	}
+	return t.kind == tokWord && !isKeyword(t, "and") && !isKeyword(t, "or")
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if isKeyword(p.peek(), "and") {
+			p.next()
+		} else if !p.startsCondition() {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	t := p.peek()
+	switch {
+	case isKeyword(t, "not"):
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	case t.kind == tokLParen:
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t.kind != tokRParen {
+			return nil, unexpected(t, `")"`)
+		}
+		p.next()
+		return expr, nil
+	case t.kind == tokWord && !isKeyword(t, "and") && !isKeyword(t, "or"):
+		return p.parseComparison()
+	}
+	return nil, unexpected(t, "a condition")
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	field := p.next()
+	name := strings.ToLower(field.text)
+	build, ok := fields[name]
+	if !ok {
+		return nil, &Error{Pos: field.pos, Msg: fmt.Sprintf("unknown field %q, expected one of %s", field.text, fieldNames())}
+	}
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, unexpected(op, "an operator after "+name)
+	}
+
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, unexpected(value, "a value")
+	}
+
+	preds, err := build(op.text, value.text)
+	if err != nil {
+		pos := value.pos
+		var oe *opError
+		if errors.As(err, &oe) {
+			pos = op.pos
+		}
+		return nil, &Error{Pos: pos, Msg: err.Error()}
+	}
+
+	return &Comparison{
+		Pos:   field.pos,
+		Field: name,
+		Op:    op.text,
+		Value: value.text,
+		preds: preds,
+	}, nil
+}
@@ -0,0 +1,109 @@
+//go:build unit
+
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	t.Run("Example Query Should Compile To Parameterized SQL", func(t *testing.T) {
+		n, err := Parse(`amount>=100 and tag:food and not tag:beverage and spent:2024-01..2024-03 and title~"smoothie"`)
+
+		if assert.NoError(t, err) {
+			sql, args := Compile(n, 0)
+			assert.Equal(t, `(((((amount >= $1) AND (EXISTS (SELECT 1 FROM unnest(tags) t WHERE t = $2 OR t LIKE $3))) AND NOT (EXISTS (SELECT 1 FROM unnest(tags) t WHERE t = $4 OR t LIKE $5))) AND (spent_at >= $6 AND spent_at < $7)) AND (coalesce(title, '') ILIKE $8))`, sql)
+			assert.Equal(t, []interface{}{
+				100.0,
+				"food",
+				"food/%",
+				"beverage",
+				"beverage/%",
+				time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+				"%smoothie%",
+			}, args)
+		}
+	})
+
+	t.Run("Placeholders Should Start After Offset", func(t *testing.T) {
+		n, err := Parse("amount<5 or amount>10")
+
+		if assert.NoError(t, err) {
+			sql, _ := Compile(n, 2)
+			assert.Equal(t, `((amount < $3) OR (amount > $4))`, sql)
+		}
+	})
+
+	t.Run("And Should Bind Tighter Than Or", func(t *testing.T) {
+		n, err := Parse("tag:a or tag:b and (amount<5)")
+
+		if assert.NoError(t, err) {
+			or, ok := n.(*Or)
+			if assert.True(t, ok) {
+				_, ok = or.Right.(*And)
+				assert.True(t, ok)
+			}
+		}
+	})
+
+	t.Run("Tag Should Match Its Descendants Case Insensitively", func(t *testing.T) {
+		n, err := Parse("tag:Food")
+
+		if assert.NoError(t, err) {
+			_, args := Compile(n, 0)
+			assert.Equal(t, []interface{}{"food", "food/%"}, args)
+		}
+	})
+
+	t.Run("Like Wildcards In Value Should Be Escaped", func(t *testing.T) {
+		n, err := Parse(`note~"100%_off"`)
+
+		if assert.NoError(t, err) {
+			_, args := Compile(n, 0)
+			assert.Equal(t, []interface{}{`%100\%\_off%`}, args)
+		}
+	})
+
+	t.Run("Open Ended Amount Range Should Compile One Bound", func(t *testing.T) {
+		n, err := Parse("amount:..50")
+
+		if assert.NoError(t, err) {
+			sql, args := Compile(n, 0)
+			assert.Equal(t, "(amount <= $1)", sql)
+			assert.Equal(t, []interface{}{50.0}, args)
+		}
+	})
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		pos   int
+	}{
+		{"Unknown Field", "amount>1 and colour:red", 14},
+		{"Invalid Number", "amount>=abc", 9},
+		{"Invalid Date", "spent:2024-13", 7},
+		{"Unsupported Operator", "tag>food", 4},
+		{"Missing Value", "amount>", 8},
+		{"Unclosed Parenthesis", "(tag:food", 10},
+		{"Unterminated String", `title~"smoothie`, 7},
+		{"Dangling And", "tag:food and", 13},
+		{"Empty Query", "  ", 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name+" Should Return Error Position", func(t *testing.T) {
+			_, err := Parse(tc.input)
+
+			qe, ok := err.(*Error)
+			if assert.True(t, ok, "expected *Error, got %v", err) {
+				assert.Equal(t, tc.pos, qe.Pos, qe.Error())
+			}
+		})
+	}
+}
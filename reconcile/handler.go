@@ -0,0 +1,455 @@
+package reconcile
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/jsritawan/assessment/statement"
+)
+
+type handler struct {
+	DB    *sql.DB
+	Match MatchConfig
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB:    db,
+		Match: DefaultMatchConfig,
+	}
+}
+
+const selectSession = `
+	SELECT s.id, s.account_id, s.source, s.status, s.created_at, s.closed_at,
+		COUNT(l.id) FILTER (WHERE l.status = 'unmatched'),
+		COUNT(l.id) FILTER (WHERE l.status = 'matched'),
+		COUNT(l.id) FILTER (WHERE l.status = 'confirmed')
+	FROM reconciliation_sessions s
+	LEFT JOIN reconciliation_lines l ON l.session_id = s.id`
+
+func scanSession(row interface{ Scan(...interface{}) error }, s *Session) error {
+	return row.Scan(&s.ID, &s.AccountID, &s.Source, &s.Status, &s.CreatedAt, &s.ClosedAt,
+		&s.Summary.Unmatched, &s.Summary.Matched, &s.Summary.Confirmed)
+}
+
+const selectLines = `
+	SELECT id, transaction_id, posted_at, amount, payee, memo, status, expense_id, score
+	FROM reconciliation_lines
+	WHERE session_id = $1
+	ORDER BY posted_at, id`
+
+func (l *Line) fields() []interface{} {
+	return []interface{}{&l.ID, &l.TransactionID, &l.Date, &l.Amount, &l.Payee, &l.Memo, &l.Status, &l.ExpenseID, &l.Score}
+}
+
+// find loads the session of the request with its lines.
+func (h *handler) find(c *gin.Context) (Session, bool) {
+	var s Session
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return s, false
+	}
+
+	row := h.DB.QueryRow(selectSession+" WHERE s.id = $1 GROUP BY s.id", id)
+	if err := scanSession(row, &s); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reconciliation session not found"})
+		return s, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return s, false
+	}
+
+	rows, err := h.DB.Query(selectLines, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return s, false
+	}
+	defer rows.Close()
+
+	s.Lines = []Line{}
+	for rows.Next() {
+		var l Line
+		if err := rows.Scan(l.fields()...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return s, false
+		}
+		s.Lines = append(s.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return s, false
+	}
+	return s, true
+}
+
+// findLine loads the line of the request, which must belong to an open
+// session.
+func (h *handler) findLine(c *gin.Context) (Line, bool) {
+	s, ok := h.find(c)
+	if !ok {
+		return Line{}, false
+	}
+	if s.Status != StatusOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": "reconciliation session is closed, reopen it first"})
+		return Line{}, false
+	}
+	id, err := strconv.Atoi(c.Param("lineId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid line id"})
+		return Line{}, false
+	}
+	for _, l := range s.Lines {
+		if l.ID == id {
+			return l, true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "line not found"})
+	return Line{}, false
+}
+
+// Create starts a session from a bank statement in any format of package
+// statement and matches its lines to the expenses not reconciled yet.
+func (h *handler) Create(c *gin.Context) {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := c.Query("format")
+	if format == "" {
+		if format = statement.Detect(data); format == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unrecognized statement format, pass format as one of " + strings.Join(statement.Formats(), ", ")})
+			return
+		}
+	}
+
+	var accountID *int
+	if v := c.Query("account_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid account_id %q", v)})
+			return
+		}
+		var exists bool
+		if err := h.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM accounts WHERE id = $1)", id).Scan(&exists); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		accountID = &id
+	}
+
+	st, err := statement.Parse(bytes.NewReader(data), format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(st.Transactions) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "statement has no transactions"})
+		return
+	}
+
+	s := Session{AccountID: accountID, Source: st.Account, Status: StatusOpen, Lines: make([]Line, len(st.Transactions))}
+	from, to := st.Transactions[0].Date, st.Transactions[0].Date
+	for i, t := range st.Transactions {
+		s.Lines[i] = Line{TransactionID: t.ID, Date: t.Date, Amount: t.Amount, Payee: t.Payee, Memo: t.Memo, Status: LineUnmatched}
+		if t.Date.Before(from) {
+			from = t.Date
+		}
+		if t.Date.After(to) {
+			to = t.Date
+		}
+	}
+
+	slack := time.Duration(h.Match.Days+1) * 24 * time.Hour
+	candidates, err := h.candidates(accountID, from.Add(-slack), to.Add(slack))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.Match.match(s.Lines, candidates)
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow("INSERT INTO reconciliation_sessions(account_id, source, status) VALUES ($1, $2, $3) RETURNING id, created_at", s.AccountID, s.Source, s.Status)
+	if err := row.Scan(&s.ID, &s.CreatedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range s.Lines {
+		l := &s.Lines[i]
+		row := tx.QueryRow(`
+			INSERT INTO reconciliation_lines(session_id, transaction_id, posted_at, amount, payee, memo, status, expense_id, score)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+			`,
+			s.ID, l.TransactionID, l.Date, l.Amount, l.Payee, l.Memo, l.Status, l.ExpenseID, l.Score)
+		if err := row.Scan(&l.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.count()
+
+	c.JSON(http.StatusCreated, s)
+}
+
+// candidates loads the expenses and income between from and to that no
+// line of any session points at. When the statement is for an account,
+// only that account's rows and rows without an account are candidates.
+func (h *handler) candidates(accountID *int, from, to time.Time) ([]candidate, error) {
+	rows, err := h.DB.Query(`
+		SELECT e.id, e.kind, e.title, e.amount, e.spent_at
+		FROM expenses e
+		WHERE e.spent_at >= $1 AND e.spent_at < $2
+			AND ($3::INT IS NULL OR e.account_id = $3 OR e.account_id IS NULL)
+			AND NOT EXISTS (SELECT 1 FROM reconciliation_lines l WHERE l.expense_id = e.id)
+		ORDER BY e.id
+		`, from, to, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.ID, &c.Kind, &c.Title, &c.Amount, &c.SpentAt); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	rows, err := h.DB.Query(selectSession + " GROUP BY s.id ORDER BY s.created_at DESC, s.id DESC")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var s Session
+		if err := scanSession(rows, &s); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		sessions = append(sessions, s)
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	s, ok := h.find(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// updateLine writes the match of a line and responds with it.
+func (h *handler) updateLine(c *gin.Context, l Line) {
+	_, err := h.DB.Exec("UPDATE reconciliation_lines SET status = $2, expense_id = $3, score = $4 WHERE id = $1", l.ID, l.Status, l.ExpenseID, l.Score)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, l)
+}
+
+// Confirm accepts the expense suggested for a line, or matches the line to
+// the expense_id given in the body instead.
+func (h *handler) Confirm(c *gin.Context) {
+	l, ok := h.findLine(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		ExpenseID *int `json:"expense_id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if body.ExpenseID == nil {
+		if l.ExpenseID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "line has no match to confirm, pass expense_id or create an expense from it"})
+			return
+		}
+		l.Status = LineConfirmed
+		h.updateLine(c, l)
+		return
+	}
+
+	var kind string
+	var amount float64
+	err := h.DB.QueryRow("SELECT kind, amount FROM expenses WHERE id = $1", *body.ExpenseID).Scan(&kind, &amount)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "expense not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if kind != l.Kind() || cents(amount) != cents(math.Abs(l.Amount)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s %d does not match the line's amount %.2f", kind, *body.ExpenseID, l.Amount)})
+		return
+	}
+	var taken bool
+	err = h.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM reconciliation_lines WHERE expense_id = $1 AND id <> $2)", *body.ExpenseID, l.ID).Scan(&taken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "expense is matched to another line"})
+		return
+	}
+
+	l.Status, l.ExpenseID, l.Score = LineConfirmed, body.ExpenseID, nil
+	h.updateLine(c, l)
+}
+
+// Unmatch removes the expense matched to a line, confirmed or not.
+func (h *handler) Unmatch(c *gin.Context) {
+	l, ok := h.findLine(c)
+	if !ok {
+		return
+	}
+	l.Status, l.ExpenseID, l.Score = LineUnmatched, nil, nil
+	h.updateLine(c, l)
+}
+
+// CreateExpense records an unmatched line as a new expense, or income for
+// money coming in, and confirms the line with it.
+func (h *handler) CreateExpense(c *gin.Context) {
+	l, ok := h.findLine(c)
+	if !ok {
+		return
+	}
+	if l.ExpenseID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "line is matched already, unmatch it first"})
+		return
+	}
+	var accountID *int
+	if err := h.DB.QueryRow("SELECT account_id FROM reconciliation_sessions WHERE id = $1", c.Param("id")).Scan(&accountID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	spentAt := l.Date
+	e := expense.Expense{Title: l.Payee, Amount: math.Abs(l.Amount), Note: l.Memo, Tags: []string{}, SpentAt: &spentAt, AccountID: accountID}
+	if e.Title == "" {
+		e.Title, e.Note = l.Memo, ""
+	}
+	if e.Title == "" {
+		e.Title = "bank transaction " + l.TransactionID
+	}
+	if err := expense.Prepare(h.DB, &e); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := expense.Insert(tx, l.Kind(), &e); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ledger.SyncExpenses(tx, []int{e.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("UPDATE reconciliation_lines SET status = $2, expense_id = $3, score = NULL WHERE id = $1", l.ID, LineConfirmed, e.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, e)
+}
+
+// Close marks a session reconciled, which locks the expenses of its
+// confirmed lines. Suggested matches must be confirmed or unmatched first;
+// lines left unmatched, such as transfers between own accounts, do not
+// hold it open.
+func (h *handler) Close(c *gin.Context) {
+	s, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if s.Status == StatusClosed {
+		c.JSON(http.StatusConflict, gin.H{"error": "reconciliation session is closed already"})
+		return
+	}
+	if s.Summary.Matched > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%d lines have unconfirmed matches, confirm or unmatch them first", s.Summary.Matched)})
+		return
+	}
+	h.setStatus(c, s, StatusClosed)
+}
+
+// Reopen unlocks a closed session and its expenses.
+func (h *handler) Reopen(c *gin.Context) {
+	s, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if s.Status == StatusOpen {
+		c.JSON(http.StatusConflict, gin.H{"error": "reconciliation session is open already"})
+		return
+	}
+	h.setStatus(c, s, StatusOpen)
+}
+
+func (h *handler) setStatus(c *gin.Context, s Session, status string) {
+	row := h.DB.QueryRow(`
+		UPDATE reconciliation_sessions
+		SET status = $2, closed_at = CASE WHEN $2 = 'closed' THEN now() END
+		WHERE id = $1
+		RETURNING closed_at
+		`, s.ID, status)
+	if err := row.Scan(&s.ClosedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.Status = status
+
+	c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,243 @@
+//go:build unit
+
+package reconcile
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+const ofxStatement = `OFXHEADER:100
+<OFX>
+<BANKACCTFROM><ACCTID>123-4-56789-0</BANKACCTFROM>
+<STMTTRN><DTPOSTED>20240104<TRNAMT>-89.00<FITID>T1<NAME>Cafe</STMTTRN>
+<STMTTRN><DTPOSTED>20240105<TRNAMT>-120.00<FITID>T2<NAME>Somtam Shop<MEMO>lunch</STMTTRN>
+</OFX>
+`
+
+var (
+	createdAt      = time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)
+	sessionColumns = []string{"id", "account_id", "source", "status", "created_at", "closed_at", "unmatched", "matched", "confirmed"}
+	lineColumns    = []string{"id", "transaction_id", "posted_at", "amount", "payee", "memo", "status", "expense_id", "score"}
+)
+
+// expectSession expects session 1 to be loaded with a matched line 7 and
+// an unmatched line 8.
+func expectSession(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectQuery("SELECT (.+) FROM reconciliation_sessions s").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).AddRow(1, nil, "123-4-56789-0", status, createdAt, nil, 1, 1, 0))
+	mock.ExpectQuery("SELECT (.+) FROM reconciliation_lines").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(lineColumns).
+			AddRow(7, "T1", date(4), -89.0, "Cafe", "", LineMatched, 3, 0.745).
+			AddRow(8, "T2", date(5), -120.0, "Somtam Shop", "lunch", LineUnmatched, nil, nil))
+}
+
+func serve(h *handler, method, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.POST("/reconciliations", h.Create)
+	r.POST("/reconciliations/:id/close", h.Close)
+	r.POST("/reconciliations/:id/lines/:lineId/confirm", h.Confirm)
+	r.POST("/reconciliations/:id/lines/:lineId/expense", h.CreateExpense)
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateSession(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM expenses e").
+		WithArgs(date(4).AddDate(0, 0, -4), date(5).AddDate(0, 0, 4), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "title", "amount", "spent_at"}).
+			AddRow(3, expense.KindExpense, "somtam shop", 120.0, date(5)))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO reconciliation_sessions").
+		WithArgs(nil, "123-4-56789-0", StatusOpen).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, createdAt))
+	mock.ExpectQuery("INSERT INTO reconciliation_lines").
+		WithArgs(1, "T1", date(4), -89.0, "Cafe", "", LineUnmatched, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("INSERT INTO reconciliation_lines").
+		WithArgs(1, "T2", date(5), -120.0, "Somtam Shop", "lunch", LineMatched, 3, 1.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectCommit()
+
+	// Act
+	rec := serve(NewHandler(db), http.MethodPost, "/reconciliations", ofxStatement)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"summary":{"unmatched":1,"matched":1,"confirmed":0}`)
+}
+
+func TestConfirmLine(t *testing.T) {
+	t.Run("Confirm Suggested Match Should Return OK", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		expectSession(mock, StatusOpen)
+		mock.ExpectExec("UPDATE reconciliation_lines").
+			WithArgs(7, LineConfirmed, 3, 0.745).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// Act
+		rec := serve(NewHandler(db), http.MethodPost, "/reconciliations/1/lines/7/confirm", "")
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Confirm Expense Of Another Amount Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		expectSession(mock, StatusOpen)
+		mock.ExpectQuery("SELECT kind, amount FROM expenses").
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"kind", "amount"}).AddRow(expense.KindExpense, 125.0))
+
+		// Act
+		rec := serve(NewHandler(db), http.MethodPost, "/reconciliations/1/lines/8/confirm", `{"expense_id": 5}`)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Confirm In Closed Session Should Return Conflict", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		expectSession(mock, StatusClosed)
+
+		// Act
+		rec := serve(NewHandler(db), http.MethodPost, "/reconciliations/1/lines/7/confirm", "")
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestCreateExpenseFromLine(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	spentAt := date(5)
+	expectSession(mock, StatusOpen)
+	mock.ExpectQuery("SELECT account_id FROM reconciliation_sessions").
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"account_id"}).AddRow(nil))
+	mock.ExpectQuery("SELECT (.+) FROM rules").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position", "enabled", "conditions", "actions"}))
+	mock.ExpectQuery("SELECT (.+) FROM merchants").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "aliases"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO expenses").
+		WithArgs("Somtam Shop", 120.0, "lunch", pq.Array(&[]string{}), &spentAt, "", nil, nil, expense.KindExpense, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "spent_at"}).AddRow(4, spentAt))
+	mock.ExpectExec("DELETE FROM journal_entries WHERE expense_id").
+		WithArgs(pq.Array([]int{4})).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT (.+) FROM expenses e LEFT JOIN accounts a").
+		WithArgs(pq.Array([]int{4})).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "kind", "tags", "category", "spent_at", "account_id", "name", "type"}))
+	mock.ExpectExec("UPDATE reconciliation_lines").
+		WithArgs(8, LineConfirmed, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Act
+	rec := serve(NewHandler(db), http.MethodPost, "/reconciliations/1/lines/8/expense", "")
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{"id":4,"title":"Somtam Shop","amount":120,"note":"lunch","tags":[],"spent_at":"2024-01-05T00:00:00Z"}`, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestCloseSession(t *testing.T) {
+	t.Run("Close With Unconfirmed Matches Should Return Conflict", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		expectSession(mock, StatusOpen)
+
+		// Act
+		rec := serve(NewHandler(db), http.MethodPost, "/reconciliations/1/close", "")
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("Close Should Return OK", func(t *testing.T) {
+		// Arrange
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		closedAt := createdAt.Add(time.Hour)
+		mock.ExpectQuery("SELECT (.+) FROM reconciliation_sessions s").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(sessionColumns).AddRow(1, nil, "123-4-56789-0", StatusOpen, createdAt, nil, 1, 0, 1))
+		mock.ExpectQuery("SELECT (.+) FROM reconciliation_lines").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(lineColumns))
+		mock.ExpectQuery("UPDATE reconciliation_sessions").
+			WithArgs(1, StatusClosed).
+			WillReturnRows(sqlmock.NewRows([]string{"closed_at"}).AddRow(closedAt))
+
+		// Act
+		rec := serve(NewHandler(db), http.MethodPost, "/reconciliations/1/close", "")
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"status":"closed","created_at":"2024-02-01T09:00:00Z","closed_at":"2024-02-01T10:00:00Z"`)
+	})
+}
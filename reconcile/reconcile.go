@@ -0,0 +1,169 @@
+package reconcile
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jsritawan/assessment/expense"
+)
+
+// Session statuses. A closed session is reconciled: the expenses of its
+// confirmed lines cannot be edited until it is reopened.
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+// Line statuses. A matched line has an expense suggested by the automatic
+// matching that the user has not confirmed yet.
+const (
+	LineUnmatched = "unmatched"
+	LineMatched   = "matched"
+	LineConfirmed = "confirmed"
+)
+
+type Session struct {
+	ID        int        `json:"id"`
+	AccountID *int       `json:"account_id,omitempty"`
+	Source    string     `json:"source"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	Summary   Summary    `json:"summary"`
+	Lines     []Line     `json:"lines,omitempty"`
+}
+
+// Summary counts the lines of a session by status.
+type Summary struct {
+	Unmatched int `json:"unmatched"`
+	Matched   int `json:"matched"`
+	Confirmed int `json:"confirmed"`
+}
+
+// Line is a bank statement line. Amount is negative for money leaving the
+// account, which is matched against expenses; money coming in is matched
+// against income.
+type Line struct {
+	ID            int       `json:"id"`
+	TransactionID string    `json:"transaction_id"`
+	Date          time.Time `json:"date"`
+	Amount        float64   `json:"amount"`
+	Payee         string    `json:"payee"`
+	Memo          string    `json:"memo"`
+	Status        string    `json:"status"`
+	ExpenseID     *int      `json:"expense_id,omitempty"`
+	Score         *float64  `json:"score,omitempty"`
+}
+
+// Kind is the kind of expenses row the line is matched against.
+func (l Line) Kind() string {
+	if l.Amount < 0 {
+		return expense.KindExpense
+	}
+	return expense.KindIncome
+}
+
+func (s *Session) count() {
+	s.Summary = Summary{}
+	for _, l := range s.Lines {
+		switch l.Status {
+		case LineUnmatched:
+			s.Summary.Unmatched++
+		case LineMatched:
+			s.Summary.Matched++
+		case LineConfirmed:
+			s.Summary.Confirmed++
+		}
+	}
+}
+
+// MatchConfig decides which expense a bank line is matched to. Amounts
+// must agree to the cent; the date and the title decide between the
+// candidates and whether one is close enough at all.
+type MatchConfig struct {
+	// Days is how far apart the line and the expense may be dated.
+	Days int
+	// DateWeight is the share of the score given to the dates being close,
+	// the rest going to the title similarity.
+	DateWeight float64
+	// MinScore is the lowest score that is suggested as a match.
+	MinScore float64
+}
+
+var DefaultMatchConfig = MatchConfig{
+	Days:       3,
+	DateWeight: 0.6,
+	MinScore:   0.5,
+}
+
+// candidate is an expense that is not matched to any line yet.
+type candidate struct {
+	ID      int
+	Kind    string
+	Title   string
+	Amount  float64
+	SpentAt time.Time
+}
+
+func cents(f float64) int64 {
+	return int64(math.Round(f * 100))
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// score rates how well an expense matches a line, from 0 to 1, or returns
+// false when it cannot match at all.
+func (cfg MatchConfig) score(l Line, c candidate) (float64, bool) {
+	if c.Kind != l.Kind() || cents(c.Amount) != cents(math.Abs(l.Amount)) {
+		return 0, false
+	}
+	days := math.Abs(day(l.Date).Sub(day(c.SpentAt)).Hours() / 24)
+	if days > float64(cfg.Days) {
+		return 0, false
+	}
+	title := math.Max(expense.TitleSimilarity(l.Payee, c.Title), expense.TitleSimilarity(l.Memo, c.Title))
+	s := cfg.DateWeight*(1-days/float64(cfg.Days+1)) + (1-cfg.DateWeight)*title
+	return math.Round(s*1000) / 1000, true
+}
+
+// match pairs lines with candidates one to one, taking the best scoring
+// pairs first, and sets the lines it matched. Lines that already have an
+// expense are left alone.
+func (cfg MatchConfig) match(lines []Line, candidates []candidate) {
+	type pair struct {
+		line, candidate int
+		score           float64
+	}
+	var pairs []pair
+	for i, l := range lines {
+		if l.ExpenseID != nil {
+			continue
+		}
+		for j, c := range candidates {
+			if s, ok := cfg.score(l, c); ok && s >= cfg.MinScore {
+				pairs = append(pairs, pair{i, j, s})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		if pairs[a].score != pairs[b].score {
+			return pairs[a].score > pairs[b].score
+		}
+		return candidates[pairs[a].candidate].ID < candidates[pairs[b].candidate].ID
+	})
+
+	taken := map[int]bool{}
+	for _, p := range pairs {
+		l := &lines[p.line]
+		if l.ExpenseID != nil || taken[p.candidate] {
+			continue
+		}
+		taken[p.candidate] = true
+		id, score := candidates[p.candidate].ID, p.score
+		l.ExpenseID, l.Score, l.Status = &id, &score, LineMatched
+	}
+}
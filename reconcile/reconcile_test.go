@@ -0,0 +1,81 @@
+//go:build unit
+
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jsritawan/assessment/expense"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(day int) time.Time {
+	return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestScore(t *testing.T) {
+	cfg := DefaultMatchConfig
+	line := Line{Date: date(5), Amount: -120, Payee: "Somtam Shop"}
+
+	t.Run("Same Day And Title Should Score One", func(t *testing.T) {
+		s, ok := cfg.score(line, candidate{Kind: expense.KindExpense, Title: "somtam shop", Amount: 120, SpentAt: date(5).Add(13 * time.Hour)})
+
+		assert.True(t, ok)
+		assert.Equal(t, 1.0, s)
+	})
+
+	t.Run("Dates Apart Should Score Lower", func(t *testing.T) {
+		s, ok := cfg.score(line, candidate{Kind: expense.KindExpense, Title: "Somtam Shop", Amount: 120, SpentAt: date(7)})
+
+		assert.True(t, ok)
+		assert.Equal(t, 0.7, s)
+	})
+
+	t.Run("Different Amount, Kind Or Too Far Apart Should Not Match", func(t *testing.T) {
+		for _, c := range []candidate{
+			{Kind: expense.KindExpense, Title: "Somtam Shop", Amount: 120.01, SpentAt: date(5)},
+			{Kind: expense.KindIncome, Title: "Somtam Shop", Amount: 120, SpentAt: date(5)},
+			{Kind: expense.KindExpense, Title: "Somtam Shop", Amount: 120, SpentAt: date(9)},
+		} {
+			_, ok := cfg.score(line, c)
+			assert.False(t, ok)
+		}
+	})
+}
+
+func TestMatch(t *testing.T) {
+	t.Run("Best Pairs Should Win One To One", func(t *testing.T) {
+		lines := []Line{
+			{Date: date(4), Amount: -89, Payee: "Cafe", Status: LineUnmatched},
+			{Date: date(4), Amount: -89, Payee: "Cafe", Status: LineUnmatched},
+			{Date: date(5), Amount: -120, Payee: "Somtam Shop", Status: LineUnmatched},
+			{Date: date(5), Amount: 500, Payee: "transfer in", Status: LineUnmatched},
+		}
+		candidates := []candidate{
+			{ID: 1, Kind: expense.KindExpense, Title: "cafe", Amount: 89, SpentAt: date(4)},
+			{ID: 2, Kind: expense.KindExpense, Title: "somtam", Amount: 120, SpentAt: date(8)},
+			{ID: 3, Kind: expense.KindExpense, Title: "Somtam Shop", Amount: 120, SpentAt: date(5)},
+			{ID: 4, Kind: expense.KindIncome, Title: "salary", Amount: 500, SpentAt: date(25)},
+		}
+
+		DefaultMatchConfig.match(lines, candidates)
+
+		assert.Equal(t, 1, *lines[0].ExpenseID)
+		assert.Equal(t, LineMatched, lines[0].Status)
+		assert.Nil(t, lines[1].ExpenseID)
+		assert.Equal(t, 3, *lines[2].ExpenseID)
+		assert.Nil(t, lines[3].ExpenseID)
+		assert.Equal(t, LineUnmatched, lines[3].Status)
+	})
+
+	t.Run("Weak Matches Should Not Be Suggested", func(t *testing.T) {
+		lines := []Line{{Date: date(5), Amount: -120, Payee: "Somtam Shop", Status: LineUnmatched}}
+		candidates := []candidate{{ID: 1, Kind: expense.KindExpense, Title: "electricity bill", Amount: 120, SpentAt: date(8)}}
+
+		DefaultMatchConfig.match(lines, candidates)
+
+		assert.Nil(t, lines[0].ExpenseID)
+		assert.Equal(t, LineUnmatched, lines[0].Status)
+	})
+}
@@ -0,0 +1,270 @@
+package report
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/deduction"
+	"github.com/jsritawan/assessment/expense"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+// bangkok is Thai time, in which report dates are given and tax years
+// run. A fixed zone avoids depending on tzdata; Thailand keeps no
+// daylight saving.
+var bangkok = time.FixedZone("ICT", 7*3600)
+
+func parseDate(name, v string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", v, bangkok)
+	if err != nil {
+		return t, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return t, nil
+}
+
+// dateRange turns the optional from and to query parameters, both
+// inclusive dates in Bangkok, into conditions on spent_at numbered after
+// offset.
+func dateRange(c *gin.Context, offset int) ([]string, []interface{}, error) {
+	var conds []string
+	var args []interface{}
+	if v := c.Query("from"); v != "" {
+		t, err := parseDate("from", v)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, t)
+		conds = append(conds, fmt.Sprintf("spent_at >= $%d", offset+len(args)))
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseDate("to", v)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, t.AddDate(0, 0, 1))
+		conds = append(conds, fmt.Sprintf("spent_at < $%d", offset+len(args)))
+	}
+	return conds, args, nil
+}
+
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// ByMerchant totals expenses per merchant, largest first. Expenses
+// without a merchant are totalled together under a null merchant_id.
+func (h *handler) ByMerchant(c *gin.Context) {
+	conds, args, err := dateRange(c, 1)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	conds = append([]string{"e.kind = $1"}, conds...)
+	args = append([]interface{}{expense.KindExpense}, args...)
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	stmt := `
+		SELECT m.id, COALESCE(m.name, ''), COUNT(*), COALESCE(SUM(e.amount), 0)
+		FROM expenses e LEFT JOIN merchants m ON m.id = e.merchant_id` + where(conds) + `
+		GROUP BY m.id, m.name
+		ORDER BY SUM(e.amount) DESC, m.name`
+	if limit > 0 {
+		args = append(args, limit)
+		stmt += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := h.DB.Query(stmt, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	totals := []MerchantTotal{}
+	for rows.Next() {
+		var t MerchantTotal
+		if err := rows.Scan(&t.MerchantID, &t.Name, &t.Count, &t.Total); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		totals = append(totals, t)
+	}
+
+	c.JSON(http.StatusOK, totals)
+}
+
+var periodLabels = map[string]func(time.Time) string{
+	"month": func(t time.Time) string { return t.Format("2006-01") },
+	"year":  func(t time.Time) string { return t.Format("2006") },
+	"week": func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	},
+}
+
+// CashFlow totals income and expenses per month, week or year, with the
+// net savings and savings rate of each and of the whole range.
+func (h *handler) CashFlow(c *gin.Context) {
+	period := c.DefaultQuery("period", "month")
+	label, ok := periodLabels[period]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid period %q", period)})
+		return
+	}
+	conds, args, err := dateRange(c, 3)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	args = append([]interface{}{period, expense.KindIncome, expense.KindExpense}, args...)
+
+	rows, err := h.DB.Query(`
+		SELECT date_trunc($1, spent_at AT TIME ZONE 'Asia/Bangkok') AS period,
+			COALESCE(SUM(amount) FILTER (WHERE kind = $2), 0),
+			COALESCE(SUM(amount) FILTER (WHERE kind = $3), 0)
+		FROM expenses`+where(conds)+`
+		GROUP BY 1
+		ORDER BY 1`, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	report := CashFlowReport{Periods: []CashFlow{}}
+	for rows.Next() {
+		var start time.Time
+		var f CashFlow
+		if err := rows.Scan(&start, &f.Income, &f.Expense); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		f.Period = label(start)
+		report.Total.Income += f.Income
+		report.Total.Expense += f.Expense
+		f.settle()
+		report.Periods = append(report.Periods, f)
+	}
+	report.Total.settle()
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Tax totals input VAT and withholding tax per month over expenses with a
+// tax breakdown.
+func (h *handler) Tax(c *gin.Context) {
+	conds, args, err := dateRange(c, 1)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	conds = append([]string{"kind = $1", "tax IS NOT NULL"}, conds...)
+	args = append([]interface{}{expense.KindExpense}, args...)
+
+	rows, err := h.DB.Query(`
+		SELECT date_trunc('month', spent_at AT TIME ZONE 'Asia/Bangkok') AS period, COUNT(*),
+			COALESCE(SUM((tax->>'net')::numeric), 0),
+			COALESCE(SUM((tax->>'vat')::numeric), 0),
+			COALESCE(SUM((tax->>'vat')::numeric) FILTER (WHERE COALESCE(tax->>'vendor_tax_id', '') <> ''), 0),
+			COALESCE(SUM(amount), 0),
+			COALESCE(SUM((tax->>'withholding')::numeric), 0)
+		FROM expenses`+where(conds)+`
+		GROUP BY 1
+		ORDER BY 1`, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	report := TaxReport{Periods: []TaxPeriod{}}
+	for rows.Next() {
+		var start time.Time
+		var p TaxPeriod
+		if err := rows.Scan(&start, &p.Count, &p.Net, &p.VAT, &p.ClaimableVAT, &p.Gross, &p.Withholding); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		p.Period = periodLabels["month"](start)
+		report.Total.add(p)
+		report.Periods = append(report.Periods, p)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Deductions compares what was spent in each deduction category over a
+// tax year, the current one unless year is given, with its cap.
+func (h *handler) Deductions(c *gin.Context) {
+	year := time.Now().In(bangkok).Year()
+	if v := c.Query("year"); v != "" {
+		var err error
+		if year, err = strconv.Atoi(v); err != nil || year < 1900 || year > 9999 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid year %q", v)})
+			return
+		}
+	}
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, bangkok)
+
+	rows, err := h.DB.Query(`
+		SELECT d.id, d.name, d.caps, COUNT(e.id), COALESCE(SUM(e.amount), 0)
+		FROM deduction_categories d
+		LEFT JOIN expenses e ON e.deduction_id = d.id AND e.kind = $1 AND e.spent_at >= $2 AND e.spent_at < $3
+		GROUP BY d.id, d.name, d.caps
+		ORDER BY d.name`, expense.KindExpense, start, start.AddDate(1, 0, 0))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	report := DeductionReport{Year: year, Categories: []deduction.Claim{}}
+	for rows.Next() {
+		var claim deduction.Claim
+		var caps deduction.Caps
+		if err := rows.Scan(&claim.CategoryID, &claim.Name, &caps, &claim.Count, &claim.Claimed); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		claim.Settle(caps.For(year))
+		report.Claimed += claim.Claimed
+		if claim.Deductible != nil {
+			report.Deductible += *claim.Deductible
+		}
+		report.Categories = append(report.Categories, claim)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	report.Claimed, report.Deductible = round(report.Claimed), round(report.Deductible)
+
+	c.JSON(http.StatusOK, report)
+}
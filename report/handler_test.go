@@ -0,0 +1,223 @@
+//go:build unit
+
+package report
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByMerchant(t *testing.T) {
+	t.Run("Merchant Report With Invalid Date Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/reports/merchants?from=yesterday", nil)
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/reports/merchants", h.ByMerchant)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Merchant Report Should Return Totals Per Merchant", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/reports/merchants?from=2024-01-01&to=2024-01-31&limit=5", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM expenses e LEFT JOIN merchants m ON m.id = e.merchant_id WHERE e.kind = \\$1 AND spent_at >= \\$2 AND spent_at < \\$3 (.+) LIMIT \\$4").
+			WithArgs("expense", time.Date(2024, 1, 1, 0, 0, 0, 0, bangkok), time.Date(2024, 2, 1, 0, 0, 0, 0, bangkok), 5).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "count", "total"}).
+				AddRow(1, "Starbucks", 3, 450.0).
+				AddRow(nil, "", 2, 120.0))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/reports/merchants", h.ByMerchant)
+		expect := `[{"merchant_id":1,"name":"Starbucks","count":3,"total":450},{"merchant_id":null,"name":"","count":2,"total":120}]`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestCashFlow(t *testing.T) {
+	t.Run("Cash Flow With Invalid Period Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/reports/cashflow?period=decade", nil)
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/reports/cashflow", h.CashFlow)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Cash Flow Should Return Income And Expense Per Period", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/reports/cashflow?from=2024-01-01", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT date_trunc(.+) FROM expenses WHERE spent_at >= \\$4 GROUP BY 1").
+			WithArgs("month", "income", "expense", time.Date(2024, 1, 1, 0, 0, 0, 0, bangkok)).
+			WillReturnRows(sqlmock.NewRows([]string{"period", "income", "expense"}).
+				AddRow(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 50000.0, 40000.0).
+				AddRow(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 0.0, 1200.5))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/reports/cashflow", h.CashFlow)
+		expect := `{"periods":[{"period":"2024-01","income":50000,"expense":40000,"net":10000,"savings_rate":0.2},{"period":"2024-02","income":0,"expense":1200.5,"net":-1200.5,"savings_rate":null}],"total":{"income":50000,"expense":41200.5,"net":8799.5,"savings_rate":0.176}}`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestTax(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/reports/tax?to=2024-02-29", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT date_trunc\\('month', spent_at AT TIME ZONE 'Asia/Bangkok'\\)(.+) FROM expenses WHERE kind = \\$1 AND tax IS NOT NULL AND spent_at < \\$2 GROUP BY 1").
+		WithArgs("expense", time.Date(2024, 3, 1, 0, 0, 0, 0, bangkok)).
+		WillReturnRows(sqlmock.NewRows([]string{"period", "count", "net", "vat", "claimable_vat", "gross", "withholding"}).
+			AddRow(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 2, 1000.0, 70.0, 70.0, 1070.0, 30.0).
+			AddRow(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 1, 99.07, 6.93, 0.0, 106.0, 0.0))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.GET("/reports/tax", h.Tax)
+	expect := `{"periods":[{"period":"2024-01","count":2,"net":1000,"vat":70,"claimable_vat":70,"gross":1070,"withholding":30},{"period":"2024-02","count":1,"net":99.07,"vat":6.93,"claimable_vat":0,"gross":106,"withholding":0}],"total":{"count":3,"net":1099.07,"vat":76.93,"claimable_vat":70,"gross":1176,"withholding":30}}`
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestDeductions(t *testing.T) {
+	t.Run("Deductions With Invalid Year Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/reports/deductions?year=last", nil)
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/reports/deductions", h.Deductions)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Deductions Should Compare Claims With Caps", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/reports/deductions?year=2024", nil)
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT d.id, d.name, d.caps, COUNT(.+) FROM deduction_categories d LEFT JOIN expenses e").
+			WithArgs("expense", time.Date(2024, 1, 1, 0, 0, 0, 0, bangkok), time.Date(2025, 1, 1, 0, 0, 0, 0, bangkok)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "caps", "count", "claimed"}).
+				AddRow(2, "Donation", []byte(`[]`), 1, 500.0).
+				AddRow(3, "Easy E-Receipt", []byte(`[{"year":2024,"amount":50000}]`), 3, 62000.0).
+				AddRow(1, "Life insurance", []byte(`[{"year":2023,"amount":100000},{"year":2024,"amount":100000}]`), 1, 25000.0))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.GET("/reports/deductions", h.Deductions)
+		expect := `{"year":2024,"categories":[` +
+			`{"category_id":2,"name":"Donation","count":1,"claimed":500,"cap":null,"deductible":null,"remaining":null,"over_cap":false},` +
+			`{"category_id":3,"name":"Easy E-Receipt","count":3,"claimed":62000,"cap":50000,"deductible":50000,"remaining":0,"over_cap":true},` +
+			`{"category_id":1,"name":"Life insurance","count":1,"claimed":25000,"cap":100000,"deductible":25000,"remaining":75000,"over_cap":false}` +
+			`],"claimed":87500,"deductible":75000}`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
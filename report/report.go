@@ -0,0 +1,79 @@
+package report
+
+import (
+	"math"
+
+	"github.com/jsritawan/assessment/deduction"
+)
+
+type MerchantTotal struct {
+	MerchantID *int    `json:"merchant_id"`
+	Name       string  `json:"name"`
+	Count      int     `json:"count"`
+	Total      float64 `json:"total"`
+}
+
+// CashFlow compares income with spending over one period. SavingsRate is
+// the share of income left after spending, null when there was no income.
+type CashFlow struct {
+	Period      string   `json:"period,omitempty"`
+	Income      float64  `json:"income"`
+	Expense     float64  `json:"expense"`
+	Net         float64  `json:"net"`
+	SavingsRate *float64 `json:"savings_rate"`
+}
+
+type CashFlowReport struct {
+	Periods []CashFlow `json:"periods"`
+	Total   CashFlow   `json:"total"`
+}
+
+// TaxPeriod totals the tax breakdown of expenses over one month. Input VAT
+// can only be claimed against a tax invoice, so ClaimableVAT counts the VAT
+// of expenses with a vendor tax ID.
+type TaxPeriod struct {
+	Period       string  `json:"period,omitempty"`
+	Count        int     `json:"count"`
+	Net          float64 `json:"net"`
+	VAT          float64 `json:"vat"`
+	ClaimableVAT float64 `json:"claimable_vat"`
+	Gross        float64 `json:"gross"`
+	Withholding  float64 `json:"withholding"`
+}
+
+type TaxReport struct {
+	Periods []TaxPeriod `json:"periods"`
+	Total   TaxPeriod   `json:"total"`
+}
+
+func (p *TaxPeriod) add(o TaxPeriod) {
+	p.Count += o.Count
+	p.Net = round(p.Net + o.Net)
+	p.VAT = round(p.VAT + o.VAT)
+	p.ClaimableVAT = round(p.ClaimableVAT + o.ClaimableVAT)
+	p.Gross = round(p.Gross + o.Gross)
+	p.Withholding = round(p.Withholding + o.Withholding)
+}
+
+// DeductionReport lists the claims of a tax year per deduction category.
+type DeductionReport struct {
+	Year       int               `json:"year"`
+	Categories []deduction.Claim `json:"categories"`
+	Claimed    float64           `json:"claimed"`
+	Deductible float64           `json:"deductible"`
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// settle fills in the net and savings rate from income and expense.
+func (f *CashFlow) settle() {
+	f.Income, f.Expense = round(f.Income), round(f.Expense)
+	f.Net = round(f.Income - f.Expense)
+	f.SavingsRate = nil
+	if f.Income > 0 {
+		rate := math.Round(f.Net/f.Income*10000) / 10000
+		f.SavingsRate = &rate
+	}
+}
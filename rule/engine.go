@@ -0,0 +1,267 @@
+package rule
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jsritawan/assessment/tag"
+)
+
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+type compiled struct {
+	rule       Rule
+	conditions []func(s *Subject) bool
+}
+
+// Engine runs rules in order; each rule sees the changes made by the ones
+// before it.
+type Engine []compiled
+
+// parsePattern accepts either a bare regular expression or the /pattern/i
+// form, where the only supported flag is i.
+func parsePattern(v string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(v, "/") {
+		if end := strings.LastIndex(v, "/"); end > 0 {
+			flags := v[end+1:]
+			pattern := v[1:end]
+			if strings.Trim(flags, "i") != "" {
+				return nil, fmt.Errorf("unsupported regular expression flags %q", flags)
+			}
+			if flags != "" {
+				pattern = "(?i)" + pattern
+			}
+			return regexp.Compile(pattern)
+		}
+	}
+	return regexp.Compile(v)
+}
+
+func textOf(field string) (func(s *Subject) string, bool) {
+	switch field {
+	case "title":
+		return func(s *Subject) string { return s.Title }, true
+	case "note":
+		return func(s *Subject) string { return s.Note }, true
+	case "category":
+		return func(s *Subject) string { return s.Category }, true
+	}
+	return nil, false
+}
+
+func compileCondition(c Condition) (func(s *Subject) bool, error) {
+	value := string(c.Value)
+
+	if text, ok := textOf(c.Field); ok {
+		switch c.Op {
+		case OpMatches:
+			re, err := parsePattern(value)
+			if err != nil {
+				return nil, err
+			}
+			return func(s *Subject) bool { return re.MatchString(text(s)) }, nil
+		case OpContains:
+			needle := strings.ToLower(value)
+			return func(s *Subject) bool { return strings.Contains(strings.ToLower(text(s)), needle) }, nil
+		case OpEquals:
+			return func(s *Subject) bool { return strings.EqualFold(text(s), value) }, nil
+		}
+		return nil, fmt.Errorf("operator %q is not supported for %s", c.Op, c.Field)
+	}
+
+	switch c.Field {
+	case "amount":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		switch c.Op {
+		case OpGreater:
+			return func(s *Subject) bool { return s.Amount > n }, nil
+		case OpGreaterEq:
+			return func(s *Subject) bool { return s.Amount >= n }, nil
+		case OpLess:
+			return func(s *Subject) bool { return s.Amount < n }, nil
+		case OpLessEq:
+			return func(s *Subject) bool { return s.Amount <= n }, nil
+		case OpEquals:
+			return func(s *Subject) bool { return s.Amount == n }, nil
+		}
+		return nil, fmt.Errorf("operator %q is not supported for amount", c.Op)
+	case "tag":
+		name := tag.Clean(value)
+		has := func(s *Subject) bool {
+			for _, t := range s.Tags {
+				if tag.IsWithin(t, name) {
+					return true
+				}
+			}
+			return false
+		}
+		switch c.Op {
+		case OpHas:
+			return has, nil
+		case OpLacks:
+			return func(s *Subject) bool { return !has(s) }, nil
+		}
+		return nil, fmt.Errorf("operator %q is not supported for tag", c.Op)
+	}
+	return nil, fmt.Errorf("unknown field %q", c.Field)
+}
+
+func validateAction(a Action) error {
+	switch a.Type {
+	case ActionAddTag, ActionRemoveTag:
+		if tag.Clean(string(a.Value)) == "" {
+			return fmt.Errorf("%s needs a tag", a.Type)
+		}
+	case ActionSetNote, ActionSetCategory:
+	default:
+		return fmt.Errorf("unknown action %q", a.Type)
+	}
+	return nil
+}
+
+func compile(r Rule) (compiled, error) {
+	if len(r.Actions) == 0 {
+		return compiled{}, fmt.Errorf("rule needs at least one action")
+	}
+	c := compiled{rule: r}
+	for i, cond := range r.Conditions {
+		match, err := compileCondition(cond)
+		if err != nil {
+			return compiled{}, fmt.Errorf("condition %d: %v", i+1, err)
+		}
+		c.conditions = append(c.conditions, match)
+	}
+	for i, a := range r.Actions {
+		if err := validateAction(a); err != nil {
+			return compiled{}, fmt.Errorf("action %d: %v", i+1, err)
+		}
+	}
+	return c, nil
+}
+
+// Validate reports the first problem with a rule's conditions or actions.
+func Validate(r Rule) error {
+	_, err := compile(r)
+	return err
+}
+
+func NewEngine(rules []Rule) (Engine, error) {
+	engine := make(Engine, 0, len(rules))
+	for _, r := range rules {
+		c, err := compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", r.ID, err)
+		}
+		engine = append(engine, c)
+	}
+	return engine, nil
+}
+
+func (c compiled) matches(s *Subject) bool {
+	for _, match := range c.conditions {
+		if !match(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c compiled) apply(s *Subject) {
+	for _, a := range c.rule.Actions {
+		value := string(a.Value)
+		switch a.Type {
+		case ActionAddTag:
+			name := tag.Clean(value)
+			found := false
+			for _, t := range s.Tags {
+				found = found || t == name
+			}
+			if !found {
+				s.Tags = append(s.Tags, name)
+			}
+		case ActionRemoveTag:
+			name := tag.Clean(value)
+			kept := []string{}
+			for _, t := range s.Tags {
+				if t != name {
+					kept = append(kept, t)
+				}
+			}
+			s.Tags = kept
+		case ActionSetNote:
+			s.Note = value
+		case ActionSetCategory:
+			s.Category = value
+		}
+	}
+}
+
+// Apply runs every matching rule against s and returns the IDs of the rules
+// that matched.
+func (e Engine) Apply(s *Subject) []int {
+	var matched []int
+	for _, c := range e {
+		if c.matches(s) {
+			c.apply(s)
+			matched = append(matched, c.rule.ID)
+		}
+	}
+	return matched
+}
+
+func diff(before, after Subject) []FieldChange {
+	var changes []FieldChange
+	if before.Note != after.Note {
+		changes = append(changes, FieldChange{"note", before.Note, after.Note})
+	}
+	if !reflect.DeepEqual(before.Tags, after.Tags) && !(len(before.Tags) == 0 && len(after.Tags) == 0) {
+		changes = append(changes, FieldChange{"tags", before.Tags, after.Tags})
+	}
+	if before.Category != after.Category {
+		changes = append(changes, FieldChange{"category", before.Category, after.Category})
+	}
+	return changes
+}
+
+func scanRule(row interface{ Scan(...interface{}) error }, r *Rule) error {
+	var conditions, actions []byte
+	if err := row.Scan(&r.ID, &r.Name, &r.Position, &r.Enabled, &conditions, &actions); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(conditions, &r.Conditions); err != nil {
+		return err
+	}
+	return json.Unmarshal(actions, &r.Actions)
+}
+
+// Load builds an engine from the enabled rules in their configured order.
+func Load(db Querier) (Engine, error) {
+	rows, err := db.Query("SELECT id, name, position, enabled, conditions, actions FROM rules WHERE enabled ORDER BY position, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		var r Rule
+		if err := scanRule(rows, &r); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return NewEngine(rules)
+}
@@ -0,0 +1,81 @@
+//go:build unit
+
+package rule
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineApply(t *testing.T) {
+	rules := []Rule{
+		{
+			ID:         1,
+			Conditions: []Condition{{Field: "title", Op: OpMatches, Value: "/grab|bolt/i"}},
+			Actions:    []Action{{Type: ActionAddTag, Value: "transport"}, {Type: ActionSetCategory, Value: "travel"}},
+		},
+		{
+			ID: 2,
+			Conditions: []Condition{
+				{Field: "amount", Op: OpGreater, Value: "5000"},
+				{Field: "tag", Op: OpHas, Value: "electronics"},
+			},
+			Actions: []Action{{Type: ActionAddTag, Value: "big-ticket"}},
+		},
+		{
+			ID:         3,
+			Conditions: []Condition{{Field: "tag", Op: OpHas, Value: "transport"}},
+			Actions:    []Action{{Type: ActionSetNote, Value: "commute"}},
+		},
+	}
+	engine, err := NewEngine(rules)
+	assert.NoError(t, err)
+
+	t.Run("Regex Rule Should Tag And Feed Later Rules", func(t *testing.T) {
+		s := Subject{Title: "GRAB to office", Amount: 120}
+
+		matched := engine.Apply(&s)
+
+		assert.Equal(t, []int{1, 3}, matched)
+		assert.Equal(t, []string{"transport"}, s.Tags)
+		assert.Equal(t, "travel", s.Category)
+		assert.Equal(t, "commute", s.Note)
+	})
+
+	t.Run("All Conditions Should Hold", func(t *testing.T) {
+		cheap := Subject{Title: "cable", Amount: 300, Tags: []string{"electronics"}}
+		phone := Subject{Title: "phone", Amount: 39000, Tags: []string{"electronics/phone"}}
+
+		engine.Apply(&cheap)
+		engine.Apply(&phone)
+
+		assert.Equal(t, []string{"electronics"}, cheap.Tags)
+		assert.Equal(t, []string{"electronics/phone", "big-ticket"}, phone.Tags)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Invalid Regex Should Return Error", func(t *testing.T) {
+		err := Validate(Rule{
+			Conditions: []Condition{{Field: "title", Op: OpMatches, Value: "/(grab/i"}},
+			Actions:    []Action{{Type: ActionAddTag, Value: "transport"}},
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Unknown Action Should Return Error", func(t *testing.T) {
+		err := Validate(Rule{Actions: []Action{{Type: "delete", Value: "x"}}})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Numeric Value Should Unmarshal Without Quotes", func(t *testing.T) {
+		var c Condition
+		assert.NoError(t, json.Unmarshal([]byte(`{"field": "amount", "op": ">", "value": 5000}`), &c))
+
+		assert.Equal(t, Value("5000"), c.Value)
+	})
+}
@@ -0,0 +1,228 @@
+package rule
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/lib/pq"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+func bindRule(c *gin.Context) (Rule, bool) {
+	r := Rule{Enabled: true}
+	if err := c.BindJSON(&r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return r, false
+	}
+	if err := Validate(r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return r, false
+	}
+	return r, true
+}
+
+func (h *handler) find(c *gin.Context) (Rule, bool) {
+	var r Rule
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return r, false
+	}
+
+	row := h.DB.QueryRow("SELECT id, name, position, enabled, conditions, actions FROM rules WHERE id = $1", id)
+	if err := scanRule(row, &r); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+		return r, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return r, false
+	}
+	return r, true
+}
+
+func (h *handler) Create(c *gin.Context) {
+	r, ok := bindRule(c)
+	if !ok {
+		return
+	}
+	conditions, _ := json.Marshal(r.Conditions)
+	actions, _ := json.Marshal(r.Actions)
+
+	row := h.DB.QueryRow(`
+		INSERT INTO rules(name, position, enabled, conditions, actions)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+		`,
+		r.Name, r.Position, r.Enabled, conditions, actions)
+	if err := row.Scan(&r.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, r)
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT id, name, position, enabled, conditions, actions FROM rules ORDER BY position, id")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		var r Rule
+		if err := scanRule(rows, &r); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		rules = append(rules, r)
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	if r, ok := h.find(c); ok {
+		c.JSON(http.StatusOK, r)
+	}
+}
+
+func (h *handler) Update(c *gin.Context) {
+	existing, ok := h.find(c)
+	if !ok {
+		return
+	}
+	r, ok := bindRule(c)
+	if !ok {
+		return
+	}
+	r.ID = existing.ID
+	conditions, _ := json.Marshal(r.Conditions)
+	actions, _ := json.Marshal(r.Actions)
+
+	_, err := h.DB.Exec("UPDATE rules SET name=$2, position=$3, enabled=$4, conditions=$5, actions=$6 WHERE id=$1",
+		r.ID, r.Name, r.Position, r.Enabled, conditions, actions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	r, ok := h.find(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM rules WHERE id = $1", r.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Apply runs one rule over every expense not yet reconciled. With
+// dry_run=true it only reports what would change.
+func (h *handler) Apply(c *gin.Context) {
+	r, ok := h.find(c)
+	if !ok {
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	engine, err := NewEngine([]Rule{r})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	stmt := "SELECT id, title, amount, note, tags, category FROM expenses WHERE " + ledger.Unreconciled + " ORDER BY id"
+	if !dryRun {
+		stmt += " FOR UPDATE"
+	}
+	rows, err := tx.Query(stmt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := ApplyResult{RuleID: r.ID, DryRun: dryRun, Changes: []ExpenseChange{}}
+	updates := map[int]Subject{}
+	for rows.Next() {
+		var id int
+		var before Subject
+		if err := rows.Scan(&id, &before.Title, &before.Amount, &before.Note, pq.Array(&before.Tags), &before.Category); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		after := before
+		after.Tags = append([]string(nil), before.Tags...)
+		if len(engine.Apply(&after)) == 0 {
+			continue
+		}
+		result.Matched++
+		if changes := diff(before, after); len(changes) > 0 {
+			result.Changes = append(result.Changes, ExpenseChange{ExpenseID: id, Changes: changes})
+			updates[id] = after
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	var ids []int
+	for _, change := range result.Changes {
+		s := updates[change.ExpenseID]
+		_, err := tx.Exec("UPDATE expenses SET note=$2, tags=$3, category=$4 WHERE id=$1 AND "+ledger.Unreconciled,
+			change.ExpenseID, s.Note, pq.Array(s.Tags), s.Category)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ids = append(ids, change.ExpenseID)
+	}
+	if err := ledger.SyncExpenses(tx, ids); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,116 @@
+//go:build unit
+
+package rule
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var ruleColumns = []string{"id", "name", "position", "enabled", "conditions", "actions"}
+
+func TestCreateRule(t *testing.T) {
+	t.Run("Create Rule With Unknown Field Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		body := `{"name": "colour", "conditions": [{"field": "colour", "op": "equals", "value": "red"}], "actions": [{"type": "add_tag", "value": "red"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/rules", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/rules", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Create Rule Should Return Created", func(t *testing.T) {
+		// Arrange
+		body := `{"name": "rides", "conditions": [{"field": "title", "op": "matches", "value": "/grab|bolt/i"}], "actions": [{"type": "add_tag", "value": "transport"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/rules", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("INSERT INTO rules").
+			WithArgs("rides", 0, true,
+				[]byte(`[{"field":"title","op":"matches","value":"/grab|bolt/i"}]`),
+				[]byte(`[{"type":"add_tag","value":"transport"}]`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/rules", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+}
+
+func TestApplyRule(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodPost, "/rules/1/apply?dry_run=true", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM rules WHERE id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(ruleColumns).
+			AddRow(1, "rides", 0, true,
+				[]byte(`[{"field":"title","op":"matches","value":"/grab|bolt/i"}]`),
+				[]byte(`[{"type":"add_tag","value":"transport"}]`)))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, title, amount, note, tags, category FROM expenses WHERE NOT EXISTS \\((.+)reconciliation_sessions(.+)\\) ORDER BY id$").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "category"}).
+			AddRow(1, "Grab home", 150.0, "", pq.Array([]string{"late"}), "").
+			AddRow(2, "smoothie", 79.0, "", pq.Array([]string{"food"}), "").
+			AddRow(3, "bolt", 90.0, "", pq.Array([]string{"transport"}), ""))
+	mock.ExpectRollback()
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.POST("/rules/:id/apply", h.Apply)
+	expect := `{"rule_id":1,"dry_run":true,"matched":2,"changes":[{"expense_id":1,"changes":[{"field":"tags","before":["late"],"after":["late","transport"]}]}]}`
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+}
@@ -0,0 +1,84 @@
+package rule
+
+import "encoding/json"
+
+const (
+	OpMatches   = "matches"
+	OpContains  = "contains"
+	OpEquals    = "equals"
+	OpHas       = "has"
+	OpLacks     = "lacks"
+	OpGreater   = ">"
+	OpGreaterEq = ">="
+	OpLess      = "<"
+	OpLessEq    = "<="
+
+	ActionAddTag      = "add_tag"
+	ActionRemoveTag   = "remove_tag"
+	ActionSetNote     = "set_note"
+	ActionSetCategory = "set_category"
+)
+
+// Value is a condition or action operand. Numbers may be given unquoted.
+type Value string
+
+func (v *Value) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = Value(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*v = Value(n.String())
+	return nil
+}
+
+type Condition struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value Value  `json:"value"`
+}
+
+type Action struct {
+	Type  string `json:"type"`
+	Value Value  `json:"value"`
+}
+
+type Rule struct {
+	ID         int         `json:"id"`
+	Name       string      `json:"name"`
+	Position   int         `json:"position"`
+	Enabled    bool        `json:"enabled"`
+	Conditions []Condition `json:"conditions"`
+	Actions    []Action    `json:"actions"`
+}
+
+// Subject is the part of an expense that rules can look at and change.
+type Subject struct {
+	Title    string
+	Amount   float64
+	Note     string
+	Tags     []string
+	Category string
+}
+
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type ExpenseChange struct {
+	ExpenseID int           `json:"expense_id"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+type ApplyResult struct {
+	RuleID  int             `json:"rule_id"`
+	DryRun  bool            `json:"dry_run"`
+	Matched int             `json:"matched"`
+	Changes []ExpenseChange `json:"changes"`
+}
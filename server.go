@@ -8,11 +8,28 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/account"
+	"github.com/jsritawan/assessment/attachment"
+	"github.com/jsritawan/assessment/deduction"
 	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/expensereport"
+	"github.com/jsritawan/assessment/goal"
+	"github.com/jsritawan/assessment/group"
+	"github.com/jsritawan/assessment/insight"
+	"github.com/jsritawan/assessment/installment"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/jsritawan/assessment/merchant"
+	"github.com/jsritawan/assessment/reconcile"
+	"github.com/jsritawan/assessment/report"
+	"github.com/jsritawan/assessment/rule"
+	"github.com/jsritawan/assessment/tag"
+	"github.com/jsritawan/assessment/user"
+	"github.com/jsritawan/assessment/view"
 	_ "github.com/lib/pq"
 )
 
@@ -45,9 +62,381 @@ func main() {
 		log.Fatal("create expenses table failed: ", err)
 	}
 
+	_, err = db.Exec(`
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS spent_at TIMESTAMPTZ NOT NULL DEFAULT now();
+		CREATE INDEX IF NOT EXISTS expenses_spent_at_idx ON expenses (spent_at);
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+	`)
+	if err != nil {
+		log.Fatal("add expenses columns failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS search tsvector
+			GENERATED ALWAYS AS (to_tsvector('english', coalesce(title, '') || ' ' || coalesce(note, ''))) STORED;
+		CREATE INDEX IF NOT EXISTS expenses_search_idx ON expenses USING GIN (search);
+		DROP INDEX IF EXISTS expenses_title_trgm_idx;
+		DROP INDEX IF EXISTS expenses_note_trgm_idx;
+		CREATE INDEX IF NOT EXISTS expenses_title_coalesce_trgm_idx ON expenses USING GIN ((coalesce(title, '')) gin_trgm_ops);
+		CREATE INDEX IF NOT EXISTS expenses_note_coalesce_trgm_idx ON expenses USING GIN ((coalesce(note, '')) gin_trgm_ops);
+	`)
+	if err != nil {
+		log.Fatal("create expenses search index failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS expense_groups (
+			id SERIAL PRIMARY KEY,
+			name TEXT
+		);
+		CREATE TABLE IF NOT EXISTS group_members (
+			id SERIAL PRIMARY KEY,
+			group_id INT REFERENCES expense_groups(id),
+			name TEXT
+		);
+		CREATE TABLE IF NOT EXISTS expense_shares (
+			expense_id INT REFERENCES expenses(id),
+			group_id INT REFERENCES expense_groups(id),
+			payer_id INT REFERENCES group_members(id),
+			member_id INT REFERENCES group_members(id),
+			split_type TEXT,
+			value FLOAT,
+			amount FLOAT
+		);
+		CREATE TABLE IF NOT EXISTS settlements (
+			id SERIAL PRIMARY KEY,
+			group_id INT REFERENCES expense_groups(id),
+			from_member_id INT REFERENCES group_members(id),
+			to_member_id INT REFERENCES group_members(id),
+			amount FLOAT
+		);
+	`)
+	if err != nil {
+		log.Fatal("create group tables failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS attachments (
+			id SERIAL PRIMARY KEY,
+			expense_id INT REFERENCES expenses(id),
+			filename TEXT,
+			content_type TEXT,
+			size BIGINT,
+			blob_hash TEXT,
+			thumbnail_hash TEXT
+		);
+	`)
+	if err != nil {
+		log.Fatal("create attachments table failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS saved_views (
+			id SERIAL PRIMARY KEY,
+			owner TEXT NOT NULL,
+			name TEXT NOT NULL,
+			filter TEXT NOT NULL DEFAULT '',
+			sort TEXT NOT NULL DEFAULT 'id',
+			columns TEXT[]
+		);
+		CREATE TABLE IF NOT EXISTS view_shares (
+			view_id INT REFERENCES saved_views(id) ON DELETE CASCADE,
+			user_name TEXT,
+			PRIMARY KEY (view_id, user_name)
+		);
+	`)
+	if err != nil {
+		log.Fatal("create saved views tables failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS tags (
+			name TEXT PRIMARY KEY
+		);
+		CREATE TABLE IF NOT EXISTS tag_synonyms (
+			alias TEXT PRIMARY KEY,
+			tag TEXT NOT NULL
+		);
+	`)
+	if err != nil {
+		log.Fatal("create tags tables failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS rules (
+			id SERIAL PRIMARY KEY,
+			name TEXT,
+			position INT NOT NULL DEFAULT 0,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			conditions JSONB NOT NULL DEFAULT '[]',
+			actions JSONB NOT NULL DEFAULT '[]'
+		);
+	`)
+	if err != nil {
+		log.Fatal("create rules table failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS merchants (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			aliases TEXT[] NOT NULL DEFAULT '{}'
+		);
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS merchant_id INT REFERENCES merchants(id) ON DELETE SET NULL;
+		CREATE INDEX IF NOT EXISTS expenses_merchant_id_idx ON expenses (merchant_id);
+	`)
+	if err != nil {
+		log.Fatal("create merchants table failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS accounts (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			type TEXT NOT NULL,
+			currency TEXT NOT NULL DEFAULT 'THB',
+			last4 TEXT NOT NULL DEFAULT '',
+			statement_day INT,
+			due_day INT,
+			opening_balance FLOAT NOT NULL DEFAULT 0
+		);
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+		CREATE INDEX IF NOT EXISTS expenses_account_id_idx ON expenses (account_id, spent_at);
+	`)
+	if err != nil {
+		log.Fatal("create accounts table failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'expense';
+		CREATE INDEX IF NOT EXISTS expenses_kind_spent_at_idx ON expenses (kind, spent_at);
+	`)
+	if err != nil {
+		log.Fatal("add expenses kind failed: ", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS journal_entries (
+			id SERIAL PRIMARY KEY,
+			kind TEXT NOT NULL,
+			expense_id INT REFERENCES expenses(id) ON DELETE CASCADE,
+			account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+			posted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			description TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS journal_entries_expense_id_idx ON journal_entries (expense_id);
+		CREATE TABLE IF NOT EXISTS postings (
+			id SERIAL PRIMARY KEY,
+			entry_id INT NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+			account TEXT NOT NULL,
+			account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+			amount FLOAT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS postings_entry_id_idx ON postings (entry_id);
+		CREATE INDEX IF NOT EXISTS postings_account_id_idx ON postings (account_id);
+	`)
+	if err != nil {
+		log.Fatal("create ledger tables failed: ", err)
+	}
+	// deleting an account used to take its opening and transfer entries with
+	// it, leaving the other side of each transfer unbalanced
+	_, err = db.Exec(`
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'journal_entries_account_id_fkey' AND confdeltype = 'c') THEN
+				ALTER TABLE journal_entries DROP CONSTRAINT journal_entries_account_id_fkey;
+				ALTER TABLE journal_entries ADD CONSTRAINT journal_entries_account_id_fkey
+					FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE SET NULL;
+			END IF;
+		END $$;
+	`)
+	if err != nil {
+		log.Fatal("migrate journal entries account constraint failed: ", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS export_mappings (
+			tag TEXT PRIMARY KEY,
+			account TEXT NOT NULL
+		);
+	`)
+	if err != nil {
+		log.Fatal("create export_mappings table failed: ", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS bank_transactions (
+			source TEXT NOT NULL,
+			transaction_id TEXT NOT NULL,
+			expense_id INT REFERENCES expenses(id) ON DELETE SET NULL,
+			imported_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (source, transaction_id)
+		);
+	`)
+	if err != nil {
+		log.Fatal("create bank_transactions table failed: ", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS reconciliation_sessions (
+			id SERIAL PRIMARY KEY,
+			account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+			source TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'open',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			closed_at TIMESTAMPTZ
+		);
+		CREATE TABLE IF NOT EXISTS reconciliation_lines (
+			id SERIAL PRIMARY KEY,
+			session_id INT NOT NULL REFERENCES reconciliation_sessions(id) ON DELETE CASCADE,
+			transaction_id TEXT NOT NULL,
+			posted_at TIMESTAMPTZ NOT NULL,
+			amount FLOAT NOT NULL,
+			payee TEXT NOT NULL DEFAULT '',
+			memo TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'unmatched',
+			expense_id INT REFERENCES expenses(id) ON DELETE SET NULL,
+			score FLOAT
+		);
+		CREATE INDEX IF NOT EXISTS reconciliation_lines_session_id_idx ON reconciliation_lines (session_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS reconciliation_lines_expense_id_idx ON reconciliation_lines (expense_id) WHERE expense_id IS NOT NULL;
+	`)
+	if err != nil {
+		log.Fatal("create reconciliation tables failed: ", err)
+	}
+	_, err = db.Exec(`
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'draft';
+		CREATE TABLE IF NOT EXISTS expense_transitions (
+			id SERIAL PRIMARY KEY,
+			expense_id INT NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+			from_status TEXT NOT NULL,
+			to_status TEXT NOT NULL,
+			actor TEXT NOT NULL DEFAULT '',
+			role TEXT NOT NULL,
+			comment TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS expense_transitions_expense_id_idx ON expense_transitions (expense_id);
+		CREATE TABLE IF NOT EXISTS user_roles (
+			user_name TEXT PRIMARY KEY,
+			role TEXT NOT NULL
+		);
+	`)
+	if err != nil {
+		log.Fatal("create expense approval tables failed: ", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS expense_reports (
+			id SERIAL PRIMARY KEY,
+			title TEXT NOT NULL,
+			owner TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'draft',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE TABLE IF NOT EXISTS expense_report_items (
+			report_id INT NOT NULL REFERENCES expense_reports(id) ON DELETE CASCADE,
+			expense_id INT NOT NULL UNIQUE REFERENCES expenses(id) ON DELETE CASCADE,
+			PRIMARY KEY (report_id, expense_id)
+		);
+		CREATE TABLE IF NOT EXISTS expense_report_transitions (
+			id SERIAL PRIMARY KEY,
+			report_id INT NOT NULL REFERENCES expense_reports(id) ON DELETE CASCADE,
+			from_status TEXT NOT NULL,
+			to_status TEXT NOT NULL,
+			actor TEXT NOT NULL DEFAULT '',
+			role TEXT NOT NULL,
+			comment TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS expense_report_transitions_report_id_idx ON expense_report_transitions (report_id);
+	`)
+	if err != nil {
+		log.Fatal("create expense report tables failed: ", err)
+	}
+	_, err = db.Exec(`
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS tax JSONB;
+	`)
+	if err != nil {
+		log.Fatal("create expense tax column failed: ", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS deduction_categories (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			description TEXT NOT NULL DEFAULT '',
+			caps JSONB NOT NULL DEFAULT '[]'
+		);
+		ALTER TABLE expenses ADD COLUMN IF NOT EXISTS deduction_id INT REFERENCES deduction_categories(id) ON DELETE SET NULL;
+		CREATE INDEX IF NOT EXISTS expenses_deduction_id_idx ON expenses (deduction_id);
+	`)
+	if err != nil {
+		log.Fatal("create deduction categories table failed: ", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS installment_plans (
+			id SERIAL PRIMARY KEY,
+			title TEXT NOT NULL,
+			total FLOAT NOT NULL,
+			months INT NOT NULL,
+			interest_rate FLOAT NOT NULL DEFAULT 0,
+			start_date TIMESTAMPTZ NOT NULL,
+			tags TEXT[] NOT NULL DEFAULT '{}',
+			category TEXT NOT NULL DEFAULT '',
+			account_id INT REFERENCES accounts(id) ON DELETE SET NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE TABLE IF NOT EXISTS installments (
+			plan_id INT NOT NULL REFERENCES installment_plans(id) ON DELETE CASCADE,
+			number INT NOT NULL,
+			expense_id INT NOT NULL UNIQUE REFERENCES expenses(id) ON DELETE CASCADE,
+			PRIMARY KEY (plan_id, number)
+		);
+	`)
+	if err != nil {
+		log.Fatal("create installment tables failed: ", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS goals (
+			id SERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			target FLOAT NOT NULL,
+			deadline TIMESTAMPTZ NOT NULL,
+			start_date TIMESTAMPTZ NOT NULL,
+			monthly_budget FLOAT,
+			budget_tag TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE TABLE IF NOT EXISTS goal_contributions (
+			id SERIAL PRIMARY KEY,
+			goal_id INT NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+			amount FLOAT NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			contributed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX IF NOT EXISTS goal_contributions_goal_id_idx ON goal_contributions (goal_id);
+	`)
+	if err != nil {
+		log.Fatal("create goal tables failed: ", err)
+	}
+	if err := tag.Backfill(db); err != nil {
+		log.Fatal("backfill tags failed: ", err)
+	}
+	if err := ledger.Backfill(db); err != nil {
+		log.Fatal("backfill ledger failed: ", err)
+	}
+
+	attachmentDir := os.Getenv("ATTACHMENT_DIR")
+	if attachmentDir == "" {
+		attachmentDir = "attachments"
+	}
+	store, err := attachment.NewLocalStore(attachmentDir)
+	if err != nil {
+		log.Fatal("open attachment store failed: ", err)
+	}
+	maxSize, _ := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_SIZE"), 10, 64)
+
 	r := gin.Default()
 	// Middlewares
 	r.Use(auth)
+	r.Use(user.Identify)
 	r.SetTrustedProxies([]string{"127.0.0.1"})
 	// Handlers
 	r.GET("/health", func(c *gin.Context) {
@@ -58,9 +447,145 @@ func main() {
 
 	h := expense.NewHandler(db)
 	r.POST("/expenses", h.Create)
+	r.GET("/expenses/search", h.Search)
+	r.GET("/expenses/duplicates", h.GetDuplicates)
+	r.POST("/expenses/duplicates/merge", h.MergeDuplicates)
+	r.GET("/expenses/export", h.Export)
+	r.GET("/expenses/export/mappings", h.GetMapping)
+	r.PUT("/expenses/export/mappings", h.PutMapping)
+	r.POST("/expenses/import", h.Import)
+	r.POST("/expenses/import/statement", h.ImportStatement)
 	r.GET("/expenses/:id", h.Get)
 	r.GET("/expenses", h.GetAll)
 	r.PUT("/expenses/:id", h.Update)
+	r.POST("/expenses/:id/submit", h.Submit)
+	r.POST("/expenses/:id/approve", h.Approve)
+	r.POST("/expenses/:id/reject", h.Reject)
+	r.POST("/expenses/:id/reimburse", h.Reimburse)
+	r.GET("/expenses/:id/transitions", h.Transitions)
+
+	erh := expensereport.NewHandler(db)
+	r.POST("/expense-reports", erh.Create)
+	r.GET("/expense-reports", erh.GetAll)
+	r.GET("/expense-reports/:id", erh.Get)
+	r.GET("/expense-reports/:id/export", erh.Export)
+	r.POST("/expense-reports/:id/expenses", erh.AddExpenses)
+	r.DELETE("/expense-reports/:id/expenses/:expenseId", erh.RemoveExpense)
+	r.POST("/expense-reports/:id/submit", erh.Submit)
+	r.POST("/expense-reports/:id/approve", erh.Approve)
+	r.POST("/expense-reports/:id/reject", erh.Reject)
+	r.POST("/expense-reports/:id/reimburse", erh.Reimburse)
+	r.GET("/expense-reports/:id/transitions", erh.Transitions)
+
+	inh := expense.NewIncomeHandler(db)
+	r.POST("/income", inh.Create)
+	r.GET("/income/:id", inh.Get)
+	r.GET("/income", inh.GetAll)
+	r.PUT("/income/:id", inh.Update)
+
+	ah := attachment.NewHandler(db, store, maxSize)
+	r.POST("/expenses/:id/attachments", ah.Create)
+	r.GET("/expenses/:id/attachments", ah.GetAll)
+	r.GET("/expenses/:id/attachments/:attachmentId", ah.Download)
+	r.GET("/expenses/:id/attachments/:attachmentId/thumbnail", ah.Thumbnail)
+	r.DELETE("/expenses/:id/attachments/:attachmentId", ah.Delete)
+
+	th := tag.NewHandler(db)
+	r.GET("/tags", th.GetAll)
+	r.POST("/tags", th.Create)
+	r.POST("/tags/rename", th.Rename)
+	r.POST("/tags/merge", th.Merge)
+	r.GET("/tags/synonyms", th.GetSynonyms)
+	r.POST("/tags/synonyms", th.CreateSynonym)
+	r.DELETE("/tags/synonyms/*alias", th.DeleteSynonym)
+
+	rh := rule.NewHandler(db)
+	r.POST("/rules", rh.Create)
+	r.GET("/rules", rh.GetAll)
+	r.GET("/rules/:id", rh.Get)
+	r.PUT("/rules/:id", rh.Update)
+	r.DELETE("/rules/:id", rh.Delete)
+	r.POST("/rules/:id/apply", rh.Apply)
+
+	mh := merchant.NewHandler(db)
+	r.POST("/merchants", mh.Create)
+	r.GET("/merchants", mh.GetAll)
+	r.GET("/merchants/:id", mh.Get)
+	r.PUT("/merchants/:id", mh.Update)
+	r.DELETE("/merchants/:id", mh.Delete)
+	r.POST("/merchants/:id/match", mh.Match)
+
+	iph := installment.NewHandler(db)
+	r.POST("/installment-plans", iph.Create)
+	r.GET("/installment-plans", iph.GetAll)
+	r.GET("/installment-plans/:id", iph.Get)
+
+	glh := goal.NewHandler(db)
+	r.POST("/goals", glh.Create)
+	r.GET("/goals", glh.GetAll)
+	r.GET("/goals/:id", glh.Get)
+	r.DELETE("/goals/:id", glh.Delete)
+	r.POST("/goals/:id/contributions", glh.Contribute)
+
+	dh := deduction.NewHandler(db)
+	r.POST("/deductions", dh.Create)
+	r.GET("/deductions", dh.GetAll)
+	r.GET("/deductions/:id", dh.Get)
+	r.PUT("/deductions/:id", dh.Update)
+	r.DELETE("/deductions/:id", dh.Delete)
+
+	ach := account.NewHandler(db)
+	r.POST("/accounts", ach.Create)
+	r.GET("/accounts", ach.GetAll)
+	r.GET("/accounts/:id", ach.Get)
+	r.PUT("/accounts/:id", ach.Update)
+	r.DELETE("/accounts/:id", ach.Delete)
+	r.GET("/accounts/:id/transactions", ach.Transactions)
+	r.GET("/accounts/:id/periods", ach.Periods)
+	r.GET("/accounts/:id/statements", ach.Statements)
+
+	lh := ledger.NewHandler(db)
+	r.POST("/transfers", lh.CreateTransfer)
+	r.GET("/transfers", lh.GetTransfers)
+	r.GET("/ledger/entries", lh.GetEntries)
+	r.GET("/ledger/trial-balance", lh.GetTrialBalance)
+
+	rch := reconcile.NewHandler(db)
+	r.POST("/reconciliations", rch.Create)
+	r.GET("/reconciliations", rch.GetAll)
+	r.GET("/reconciliations/:id", rch.Get)
+	r.POST("/reconciliations/:id/close", rch.Close)
+	r.POST("/reconciliations/:id/reopen", rch.Reopen)
+	r.POST("/reconciliations/:id/lines/:lineId/confirm", rch.Confirm)
+	r.POST("/reconciliations/:id/lines/:lineId/unmatch", rch.Unmatch)
+	r.POST("/reconciliations/:id/lines/:lineId/expense", rch.CreateExpense)
+
+	vh := view.NewHandler(db)
+	r.POST("/views", vh.Create)
+	r.GET("/views", vh.GetAll)
+	r.GET("/views/:id", vh.Get)
+	r.PUT("/views/:id", vh.Update)
+	r.DELETE("/views/:id", vh.Delete)
+	r.GET("/views/:id/expenses", vh.Expenses)
+	r.POST("/views/:id/shares", vh.Share)
+	r.DELETE("/views/:id/shares/:user", vh.Unshare)
+
+	gh := group.NewHandler(db)
+	r.POST("/groups", gh.Create)
+	r.GET("/groups/:id", gh.Get)
+	r.POST("/groups/:id/expenses", gh.CreateExpense)
+	r.POST("/groups/:id/settlements", gh.CreateSettlement)
+	r.GET("/groups/:id/balances", gh.GetBalances)
+
+	ih := insight.NewHandler(db)
+	r.GET("/insights/anomalies", ih.GetAnomalies)
+	r.GET("/insights/forecast", ih.GetForecast)
+
+	reh := report.NewHandler(db)
+	r.GET("/reports/merchants", reh.ByMerchant)
+	r.GET("/reports/cashflow", reh.CashFlow)
+	r.GET("/reports/tax", reh.Tax)
+	r.GET("/reports/deductions", reh.Deductions)
 
 	srv := &http.Server{
 		Addr:    ":" + os.Getenv("PORT"),
@@ -0,0 +1,150 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// camtDocument is the part of an ISO 20022 camt.053 bank to customer
+// statement that expenses are made from. Elements are matched by local
+// name, so any camt.053 version is read.
+type camtDocument struct {
+	Statements []struct {
+		Account struct {
+			IBAN     string `xml:"Id>IBAN"`
+			Other    string `xml:"Id>Othr>Id"`
+			Currency string `xml:"Ccy"`
+		} `xml:"Acct"`
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Reference   string     `xml:"NtryRef"`
+	ServicerRef string     `xml:"AcctSvcrRef"`
+	Amount      camtAmount `xml:"Amt"`
+	Indicator   string     `xml:"CdtDbtInd"`
+	Reversal    bool       `xml:"RvslInd"`
+	Booking     camtDate   `xml:"BookgDt"`
+	Value       camtDate   `xml:"ValDt"`
+	Details     []struct {
+		Refs struct {
+			ServicerRef string `xml:"AcctSvcrRef"`
+			EndToEnd    string `xml:"EndToEndId"`
+		} `xml:"Refs"`
+		Creditor     string   `xml:"RltdPties>Cdtr>Nm"`
+		CreditorPty  string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+		Debtor       string   `xml:"RltdPties>Dbtr>Nm"`
+		DebtorPty    string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+		Unstructured []string `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+	Info string `xml:"AddtlNtryInf"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) parse() (time.Time, bool) {
+	if d.Date != "" {
+		t, err := time.Parse("2006-01-02", strings.TrimSpace(d.Date))
+		return t, err == nil
+	}
+	if d.DateTime != "" {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+			if t, err := time.Parse(layout, strings.TrimSpace(d.DateTime)); err == nil {
+				return t.UTC(), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func detectCAMT053(head []byte) bool {
+	return bytes.Contains(head, []byte("camt.053"))
+}
+
+// parseCAMT053 reads the entries of every statement in the document. An
+// entry's ID is the bank's servicer reference, falling back to the entry
+// reference and then the end to end ID of its first transaction.
+func parseCAMT053(r io.Reader) (*Statement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid camt.053 document: %s", err)
+	}
+
+	s := &Statement{Transactions: []Transaction{}}
+	for _, stmt := range doc.Statements {
+		if s.Account == "" {
+			s.Account = firstOf(stmt.Account.IBAN, stmt.Account.Other)
+		}
+		for _, e := range stmt.Entries {
+			var t Transaction
+			amount, err := parseAmount(e.Amount.Value)
+			if err != nil {
+				return nil, err
+			}
+			debit := e.Indicator == "DBIT"
+			if e.Reversal {
+				// A reversal undoes an earlier entry of the other direction.
+				debit = !debit
+			}
+			if debit {
+				amount = -amount
+			}
+			t.Amount = amount
+			if s.Currency == "" {
+				s.Currency = firstOf(e.Amount.Currency, stmt.Account.Currency)
+			}
+
+			date, ok := e.Booking.parse()
+			if !ok {
+				if date, ok = e.Value.parse(); !ok {
+					return nil, fmt.Errorf("entry %q has no booking date", firstOf(e.ServicerRef, e.Reference))
+				}
+			}
+			t.Date = date
+
+			var memo []string
+			t.ID = firstOf(e.ServicerRef, e.Reference)
+			if len(e.Details) > 0 {
+				d := e.Details[0]
+				t.ID = firstOf(t.ID, d.Refs.ServicerRef, d.Refs.EndToEnd)
+				if debit {
+					t.Payee = firstOf(d.Creditor, d.CreditorPty)
+				} else {
+					t.Payee = firstOf(d.Debtor, d.DebtorPty)
+				}
+				memo = append(memo, d.Unstructured...)
+			}
+			if t.ID == "" {
+				return nil, fmt.Errorf("entry on %s has no reference", t.Date.Format("2006-01-02"))
+			}
+			if info := strings.TrimSpace(e.Info); info != "" {
+				memo = append(memo, info)
+			}
+			t.Memo = strings.Join(memo, " ")
+			s.Transactions = append(s.Transactions, t)
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,55 @@
+//go:build unit
+
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCAMT053(t *testing.T) {
+	file := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Acct><Id><IBAN>TH0000000000001234567890</IBAN></Id><Ccy>THB</Ccy></Acct>
+      <Ntry>
+        <Amt Ccy="THB">1250.50</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><Dt>2024-01-05</Dt></BookgDt>
+        <AcctSvcrRef>REF-1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <RltdPties><Cdtr><Nm>Tops Market</Nm></Cdtr></RltdPties>
+          <RmtInf><Ustrd>groceries</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="THB">500</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <BookgDt><DtTm>2024-01-06T09:30:00+07:00</DtTm></BookgDt>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>E2E-2</EndToEndId></Refs>
+          <RltdPties><Dbtr><Nm>Ann</Nm></Dbtr></RltdPties>
+        </TxDtls></NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+	s, err := Parse(strings.NewReader(file), FormatCAMT053)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Statement{
+		Account:  "TH0000000000001234567890",
+		Currency: "THB",
+		Transactions: []Transaction{
+			{ID: "REF-1", Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Amount: -1250.5, Payee: "Tops Market", Memo: "groceries"},
+			{ID: "E2E-2", Date: time.Date(2024, 1, 6, 2, 30, 0, 0, time.UTC), Amount: 500, Payee: "Ann"},
+		},
+	}, s)
+	assert.Equal(t, FormatCAMT053, Detect([]byte(file)))
+}
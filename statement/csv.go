@@ -0,0 +1,215 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// csvProfile describes the CSV statement export of one bank: which header
+// cells identify it and which columns hold what. Column names are matched
+// after trimming spaces.
+type csvProfile struct {
+	Format string
+	Bank   string
+	// Signature lists header cells that only this bank's export has
+	// together. The header is the first row holding all of them; rows
+	// above it are a preamble that may name the account.
+	Signature []string
+	// Date holds the date, or the date and time when Time is empty.
+	Date string
+	Time string
+	// Withdrawal and Deposit hold unsigned amounts, or Amount holds a
+	// signed one.
+	Withdrawal string
+	Deposit    string
+	Amount     string
+	// Reference holds the bank's transaction reference, if the export has
+	// one. Lines without one get an ID from their content.
+	Reference string
+	// Payee lists the columns tried in turn for the payee, Memo the
+	// columns joined into the memo.
+	Payee []string
+	Memo  []string
+}
+
+// thaiBanks are the CSV exports of the Thai banks' internet banking.
+var thaiBanks = []*csvProfile{
+	{
+		Format:     FormatKBank,
+		Bank:       "KBank",
+		Signature:  []string{"วันที่ทำรายการ", "ถอนเงิน (บาท)", "ฝากเงิน (บาท)", "ทำรายการผ่าน"},
+		Date:       "วันที่ทำรายการ",
+		Time:       "เวลา",
+		Withdrawal: "ถอนเงิน (บาท)",
+		Deposit:    "ฝากเงิน (บาท)",
+		Payee:      []string{"รายละเอียด", "รายการ"},
+		Memo:       []string{"รายการ", "ทำรายการผ่าน"},
+	},
+	{
+		Format:     FormatSCB,
+		Bank:       "SCB",
+		Signature:  []string{"รหัสรายการ", "ถอนเงิน/หักบัญชี", "ฝากเงิน/เข้าบัญชี"},
+		Date:       "วันที่",
+		Time:       "เวลา",
+		Withdrawal: "ถอนเงิน/หักบัญชี",
+		Deposit:    "ฝากเงิน/เข้าบัญชี",
+		Payee:      []string{"รายละเอียด", "รหัสรายการ"},
+		Memo:       []string{"รหัสรายการ", "ช่องทาง"},
+	},
+	{
+		Format:    FormatKrungthai,
+		Bank:      "Krungthai",
+		Signature: []string{"เลขที่อ้างอิง", "จำนวนเงิน", "สาขา/ช่องทาง"},
+		Date:      "วันที่",
+		Amount:    "จำนวนเงิน",
+		Reference: "เลขที่อ้างอิง",
+		Payee:     []string{"รายการ"},
+		Memo:      []string{"สาขา/ช่องทาง"},
+	},
+	{
+		Format:     FormatBBL,
+		Bank:       "Bangkok Bank",
+		Signature:  []string{"เลขที่เช็ค", "เดบิต", "เครดิต"},
+		Date:       "วันที่",
+		Withdrawal: "เดบิต",
+		Deposit:    "เครดิต",
+		Payee:      []string{"รายละเอียด"},
+		Memo:       []string{"ช่องทาง", "เลขที่เช็ค"},
+	},
+}
+
+func (p *csvProfile) Detect(head []byte) bool {
+	for _, cell := range p.Signature {
+		if !bytes.Contains(head, []byte(cell)) && !bytes.Contains(head, encodeThai(cell)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *csvProfile) isHeader(row []string) bool {
+	cells := map[string]bool{}
+	for _, c := range row {
+		cells[strings.TrimSpace(c)] = true
+	}
+	for _, c := range p.Signature {
+		if !cells[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// account finds the account number in a preamble row such as
+// "เลขที่บัญชี: 123-4-56789-0" or "เลขที่บัญชี,123-4-56789-0".
+func account(row []string) string {
+	for i, c := range row {
+		if !strings.Contains(c, "เลขที่บัญชี") {
+			continue
+		}
+		if j := strings.Index(c, ":"); j >= 0 && strings.TrimSpace(c[j+1:]) != "" {
+			return strings.TrimSpace(c[j+1:])
+		}
+		for _, next := range row[i+1:] {
+			if next = strings.TrimSpace(next); next != "" {
+				return next
+			}
+		}
+	}
+	return ""
+}
+
+func (p *csvProfile) Parse(r io.Reader) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(decodeThai(data), "\ufeff")))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s statement: %s", p.Bank, err)
+	}
+
+	s := &Statement{Currency: "THB", Transactions: []Transaction{}}
+	header := -1
+	for i, row := range rows {
+		if p.isHeader(row) {
+			header = i
+			break
+		}
+		if a := account(row); a != "" && s.Account == "" {
+			s.Account = a
+		}
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("no %s statement header found", p.Bank)
+	}
+	columns := map[string]int{}
+	for i, c := range rows[header] {
+		columns[strings.TrimSpace(c)] = i
+	}
+	cell := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && name != "" && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	ids := lineIDs{}
+	for i, row := range rows[header+1:] {
+		line := header + i + 2
+		date := thaiDigits.Replace(cell(row, p.Date))
+		if date == "" || date[0] < '0' || date[0] > '9' {
+			// Blank rows and the totals some banks put at the end.
+			continue
+		}
+		if t := cell(row, p.Time); t != "" {
+			date += " " + t
+		}
+
+		var t Transaction
+		if t.Date, err = parseThaiDate(date); err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		if p.Amount != "" {
+			if t.Amount, err = parseThaiAmount(cell(row, p.Amount)); err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+		} else {
+			withdrawal, err := parseThaiAmount(cell(row, p.Withdrawal))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			deposit, err := parseThaiAmount(cell(row, p.Deposit))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+			// Some exports bracket the withdrawal column too; its sign comes
+			// from the column, not the cell.
+			t.Amount = math.Abs(deposit) - math.Abs(withdrawal)
+		}
+		for _, c := range p.Payee {
+			if t.Payee = cell(row, c); t.Payee != "" {
+				break
+			}
+		}
+		var memo []string
+		for _, c := range p.Memo {
+			if v := cell(row, c); v != "" && v != t.Payee {
+				memo = append(memo, v)
+			}
+		}
+		t.Memo = strings.Join(memo, " ")
+		if t.ID = cell(row, p.Reference); t.ID == "" {
+			t.ID = ids.next(p.Format+"-", row...)
+		}
+		s.Transactions = append(s.Transactions, t)
+	}
+	return s, nil
+}
@@ -0,0 +1,97 @@
+//go:build unit
+
+package statement
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	kbankCSV = `ชื่อบัญชี,นาย ทดสอบ ระบบ
+เลขที่บัญชี,123-4-56789-0
+วันที่ทำรายการ,เวลา,รายการ,ถอนเงิน (บาท),ฝากเงิน (บาท),ยอดคงเหลือ (บาท),ทำรายการผ่าน,รายละเอียด
+05-01-67,12:30,ชำระเงิน,"1,250.50",,"8,749.50",K PLUS,TOPS MARKET
+06-01-67,09:00,รับโอนเงิน,,"2,000.00","10,749.50",K PLUS,จาก นาย ก
+`
+	scbCSV = `วันที่,เวลา,รหัสรายการ,ช่องทาง,ถอนเงิน/หักบัญชี,ฝากเงิน/เข้าบัญชี,ยอดเงินคงเหลือ,รายละเอียด
+05/01/2567,12:30,X2,ENET,89.00,,911.00,CAFE AMAZON
+`
+	krungthaiCSV = `วันที่,รายการ,เลขที่อ้างอิง,จำนวนเงิน,ยอดคงเหลือ,สาขา/ช่องทาง
+5 ม.ค. 2567 12:30,ชำระค่าไฟฟ้า,KTB0001,"-1,024.00","5,000.00",Krungthai NEXT
+,รวม,,,,
+`
+	bblCSV = `วันที่,รายละเอียด,เลขที่เช็ค,เดบิต,เครดิต,ยอดคงเหลือ,ช่องทาง
+5 มกราคม 2567,ค่าน้ำมัน PTT,,(500.00),,"4,500.00",Bualuang mBanking
+`
+)
+
+func TestDetectThaiBanks(t *testing.T) {
+	assert.Equal(t, FormatKBank, Detect([]byte(kbankCSV)))
+	assert.Equal(t, FormatKBank, Detect(encodeThai(kbankCSV)))
+	assert.Equal(t, FormatSCB, Detect([]byte(scbCSV)))
+	assert.Equal(t, FormatKrungthai, Detect([]byte(krungthaiCSV)))
+	assert.Equal(t, FormatBBL, Detect([]byte(bblCSV)))
+}
+
+func TestParseThaiBanks(t *testing.T) {
+	t.Run("Parse KBank", func(t *testing.T) {
+		s, err := Parse(bytes.NewReader(encodeThai(kbankCSV)), FormatKBank)
+		again, _ := Parse(strings.NewReader(kbankCSV), FormatKBank)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "123-4-56789-0", s.Account)
+		assert.Equal(t, "THB", s.Currency)
+		if assert.Len(t, s.Transactions, 2) {
+			first := s.Transactions[0]
+			assert.Equal(t, time.Date(2024, 1, 5, 5, 30, 0, 0, time.UTC), first.Date)
+			assert.Equal(t, -1250.5, first.Amount)
+			assert.Equal(t, "TOPS MARKET", first.Payee)
+			assert.Equal(t, "ชำระเงิน K PLUS", first.Memo)
+			assert.Equal(t, 2000.0, s.Transactions[1].Amount)
+		}
+		assert.Equal(t, s, again, "the encoding does not change the IDs")
+	})
+
+	t.Run("Parse SCB", func(t *testing.T) {
+		s, err := Parse(strings.NewReader(scbCSV), FormatSCB)
+
+		assert.NoError(t, err)
+		if assert.Len(t, s.Transactions, 1) {
+			assert.Equal(t, -89.0, s.Transactions[0].Amount)
+			assert.Equal(t, "CAFE AMAZON", s.Transactions[0].Payee)
+			assert.Equal(t, "X2 ENET", s.Transactions[0].Memo)
+		}
+	})
+
+	t.Run("Parse Krungthai", func(t *testing.T) {
+		s, err := Parse(strings.NewReader(krungthaiCSV), FormatKrungthai)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Transaction{{
+			ID: "KTB0001", Date: time.Date(2024, 1, 5, 5, 30, 0, 0, time.UTC), Amount: -1024,
+			Payee: "ชำระค่าไฟฟ้า", Memo: "Krungthai NEXT",
+		}}, s.Transactions)
+	})
+
+	t.Run("Parse Bangkok Bank", func(t *testing.T) {
+		s, err := Parse(strings.NewReader(bblCSV), FormatBBL)
+
+		assert.NoError(t, err)
+		if assert.Len(t, s.Transactions, 1) {
+			assert.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), s.Transactions[0].Date)
+			assert.Equal(t, -500.0, s.Transactions[0].Amount)
+			assert.Equal(t, "ค่าน้ำมัน PTT", s.Transactions[0].Payee)
+		}
+	})
+
+	t.Run("Parse Without Header Should Return Error", func(t *testing.T) {
+		_, err := Parse(strings.NewReader(scbCSV), FormatKBank)
+
+		assert.EqualError(t, err, "no KBank statement header found")
+	})
+}
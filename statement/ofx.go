@@ -0,0 +1,130 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ofxEntities = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ")
+
+func detectOFX(head []byte) bool {
+	return bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>"))
+}
+
+// parseOFX reads OFX 1.x SGML as well as OFX 2.x XML. Both are read as a
+// stream of tags, since SGML leaves close tags out: a value belongs to the
+// last tag opened before it.
+func parseOFX(r io.Reader) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body := string(data)
+	start := strings.Index(strings.ToUpper(body), "<OFX>")
+	if start < 0 {
+		return nil, fmt.Errorf("not an OFX statement")
+	}
+	body = body[start:]
+
+	s := &Statement{Transactions: []Transaction{}}
+	var t *Transaction
+	for len(body) > 0 {
+		open := strings.Index(body, "<")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(body[open:], ">")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated tag")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(body[open+1 : open+end]))
+		body = body[open+end+1:]
+		next := strings.Index(body, "<")
+		if next < 0 {
+			next = len(body)
+		}
+		value := strings.TrimSpace(ofxEntities.Replace(body[:next]))
+
+		switch tag {
+		case "STMTTRN":
+			t = &Transaction{}
+		case "/STMTTRN":
+			if t == nil {
+				continue
+			}
+			if t.ID == "" {
+				return nil, fmt.Errorf("transaction on %s has no FITID", t.Date.Format("2006-01-02"))
+			}
+			s.Transactions = append(s.Transactions, *t)
+			t = nil
+		case "CURDEF":
+			s.Currency = value
+		case "ACCTID":
+			if s.Account == "" {
+				s.Account = value
+			}
+		}
+		if t == nil {
+			continue
+		}
+		switch tag {
+		case "FITID":
+			t.ID = value
+		case "DTPOSTED":
+			if t.Date, err = parseOFXDate(value); err != nil {
+				return nil, err
+			}
+		case "TRNAMT":
+			if t.Amount, err = parseAmount(value); err != nil {
+				return nil, err
+			}
+		case "NAME", "PAYEE":
+			if t.Payee == "" {
+				t.Payee = value
+			}
+		case "MEMO":
+			t.Memo = value
+		}
+	}
+	return s, nil
+}
+
+// parseOFXDate reads YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]], where a missing
+// offset means UTC.
+func parseOFXDate(s string) (time.Time, error) {
+	value := s
+	loc := time.UTC
+	if i := strings.Index(value, "["); i >= 0 {
+		tz := strings.TrimSuffix(value[i+1:], "]")
+		value = value[:i]
+		if j := strings.Index(tz, ":"); j >= 0 {
+			tz = tz[:j]
+		}
+		hours, err := strconv.ParseFloat(tz, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		loc = time.FixedZone("", int(hours*3600))
+	}
+	if i := strings.Index(value, "."); i >= 0 {
+		value = value[:i]
+	}
+	layouts := map[int]string{8: "20060102", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[len(value)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	if len(value) == 8 {
+		// A bare date is kept as that calendar day.
+		loc = time.UTC
+	}
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,81 @@
+//go:build unit
+
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOFX(t *testing.T) {
+	t.Run("Parse SGML Statement", func(t *testing.T) {
+		file := `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>THB
+<BANKACCTFROM><BANKID>004<ACCTID>123-4-56789-0<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240105
+<TRNAMT>-1,250.50
+<FITID>2024010501
+<NAME>TOPS &amp; CO
+<MEMO>card 1234
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240106093000.000[+7:ICT]
+<TRNAMT>500.00
+<FITID>2024010601
+<NAME>transfer in
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+		s, err := Parse(strings.NewReader(file), FormatQFX)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &Statement{
+			Account:  "123-4-56789-0",
+			Currency: "THB",
+			Transactions: []Transaction{
+				{ID: "2024010501", Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Amount: -1250.5, Payee: "TOPS & CO", Memo: "card 1234"},
+				{ID: "2024010601", Date: time.Date(2024, 1, 6, 2, 30, 0, 0, time.UTC), Amount: 500, Payee: "transfer in"},
+			},
+		}, s)
+	})
+
+	t.Run("Parse XML Statement", func(t *testing.T) {
+		file := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CURDEF>THB</CURDEF>
+<CCACCTFROM><ACCTID>4111XXXXXXXX1234</ACCTID></CCACCTFROM>
+<BANKTRANLIST><STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240110120000</DTPOSTED><TRNAMT>-89.00</TRNAMT><FITID>A1</FITID><NAME>Cafe</NAME></STMTTRN></BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>
+`
+
+		s, err := Parse(strings.NewReader(file), FormatOFX)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "4111XXXXXXXX1234", s.Account)
+		assert.Equal(t, []Transaction{{ID: "A1", Date: time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC), Amount: -89, Payee: "Cafe"}}, s.Transactions)
+	})
+
+	t.Run("Parse Transaction Without FITID Should Return Error", func(t *testing.T) {
+		file := `<OFX><STMTTRN><DTPOSTED>20240105<TRNAMT>-1</STMTTRN></OFX>`
+
+		_, err := Parse(strings.NewReader(file), FormatOFX)
+
+		assert.EqualError(t, err, "transaction on 2024-01-05 has no FITID")
+	})
+}
@@ -0,0 +1,114 @@
+package statement
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func detectQIF(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("!"))
+}
+
+// parseQIF reads the bank and card sections of a QIF file. QIF dates are
+// month first, as Quicken writes them, and QIF has no transaction IDs, so
+// each transaction gets one hashed from its fields and how many identical
+// transactions came before it in the file. Re-importing the same file
+// yields the same IDs.
+func parseQIF(r io.Reader) (*Statement, error) {
+	s := &Statement{Transactions: []Transaction{}}
+	ids := lineIDs{}
+	var t Transaction
+	var dated, skip bool
+
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\xef\xbb\xbf")
+		}
+		if line == "" {
+			continue
+		}
+		code, value := line[0], strings.TrimSpace(line[1:])
+		if code == '!' {
+			// Only transaction lists of bank and card accounts are read.
+			header := strings.ToLower(line)
+			skip = !strings.HasPrefix(header, "!type:") || strings.HasPrefix(header, "!type:invst") ||
+				strings.HasPrefix(header, "!type:cat") || strings.HasPrefix(header, "!type:class") || strings.HasPrefix(header, "!type:memorized")
+			continue
+		}
+		if skip {
+			continue
+		}
+
+		var err error
+		switch code {
+		case 'D':
+			if t.Date, err = parseQIFDate(value); err != nil {
+				return nil, fmt.Errorf("line %d: %s", n, err)
+			}
+			dated = true
+		case 'T', 'U':
+			if t.Amount, err = parseAmount(value); err != nil {
+				return nil, fmt.Errorf("line %d: %s", n, err)
+			}
+		case 'P':
+			t.Payee = value
+		case 'M':
+			t.Memo = value
+		case '^':
+			if !dated {
+				return nil, fmt.Errorf("line %d: transaction has no date", n)
+			}
+			t.ID = ids.next("qif-", t.Date.Format("2006-01-02"), strconv.FormatFloat(t.Amount, 'f', 2, 64), t.Payee, t.Memo)
+			s.Transactions = append(s.Transactions, t)
+			t, dated = Transaction{}, false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseQIFDate reads the month first dates Quicken writes, such as
+// 01/05/2024, 1/5'24 and 01-05-24, as well as ISO dates.
+func parseQIFDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '-' || r == '\'' || r == '.' })
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	var nums [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		nums[i] = v
+	}
+	month, day, year := nums[0], nums[1], nums[2]
+	if year < 100 {
+		// Quicken writes 2000s years after an apostrophe, so two digit
+		// years below 70 are taken to be in this century.
+		if year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Month() != time.Month(month) || t.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,66 @@
+//go:build unit
+
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQIF(t *testing.T) {
+	file := `!Type:Bank
+D01/05/2024
+T-120.00
+PSomtam Shop
+Mlunch
+^
+D1/5'24
+T-120.00
+PSomtam Shop
+Mlunch
+^
+D01/06/2024
+T2,000.00
+Psalary
+^
+!Type:Cat
+NFood
+^
+`
+
+	s, err := Parse(strings.NewReader(file), FormatQIF)
+	again, _ := Parse(strings.NewReader(file), FormatQIF)
+
+	assert.NoError(t, err)
+	if assert.Len(t, s.Transactions, 3) {
+		first, second := s.Transactions[0], s.Transactions[1]
+		assert.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), second.Date)
+		assert.Equal(t, -120.0, first.Amount)
+		assert.Equal(t, "Somtam Shop", first.Payee)
+		assert.Equal(t, "lunch", first.Memo)
+		assert.NotEqual(t, first.ID, second.ID, "identical transactions get their own IDs")
+		assert.Equal(t, 2000.0, s.Transactions[2].Amount)
+	}
+	assert.Equal(t, s, again, "the same file yields the same IDs")
+}
+
+func TestParseQIFDate(t *testing.T) {
+	for value, expect := range map[string]time.Time{
+		"01/05/2024": time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		"1/5'24":     time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		"12-31-99":   time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC),
+		"2024-01-05": time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+	} {
+		got, err := parseQIFDate(value)
+
+		assert.NoError(t, err, value)
+		assert.Equal(t, expect, got, value)
+	}
+
+	_, err := parseQIFDate("13/01/2024")
+
+	assert.EqualError(t, err, `invalid date "13/01/2024"`)
+}
@@ -0,0 +1,152 @@
+package statement
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of the bank statements Parse reads out of the box. QFX is
+// Quicken's name for OFX and parsed the same way.
+const (
+	FormatOFX       = "ofx"
+	FormatQFX       = "qfx"
+	FormatQIF       = "qif"
+	FormatCAMT053   = "camt053"
+	FormatKBank     = "kbank"
+	FormatSCB       = "scb"
+	FormatKrungthai = "krungthai"
+	FormatBBL       = "bbl"
+)
+
+// Transaction is one line of a bank statement. ID is the bank's own
+// transaction ID, unique within the statement's account, and Amount is
+// negative for money leaving the account.
+type Transaction struct {
+	ID     string    `json:"id"`
+	Date   time.Time `json:"date"`
+	Amount float64   `json:"amount"`
+	Payee  string    `json:"payee"`
+	Memo   string    `json:"memo"`
+}
+
+// Debit reports whether the transaction took money out of the account.
+func (t Transaction) Debit() bool {
+	return t.Amount < 0
+}
+
+// Statement is a parsed bank statement. Account identifies the bank
+// account it was downloaded for, when the format records one.
+type Statement struct {
+	Account      string        `json:"account"`
+	Currency     string        `json:"currency"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Parser reads the statements of one format.
+type Parser interface {
+	// Detect reports whether a file starting with head is in the format.
+	Detect(head []byte) bool
+	Parse(r io.Reader) (*Statement, error)
+}
+
+// funcParser adapts a pair of functions to Parser.
+type funcParser struct {
+	detect func(head []byte) bool
+	parse  func(r io.Reader) (*Statement, error)
+}
+
+func (p funcParser) Detect(head []byte) bool {
+	return p.detect != nil && p.detect(head)
+}
+
+func (p funcParser) Parse(r io.Reader) (*Statement, error) {
+	return p.parse(r)
+}
+
+var (
+	parsers = map[string]Parser{}
+	// detectOrder is the order formats were registered in, which is the
+	// order Detect tries them.
+	detectOrder []string
+)
+
+func init() {
+	Register(FormatOFX, funcParser{detect: detectOFX, parse: parseOFX})
+	// QFX files are detected as OFX.
+	Register(FormatQFX, funcParser{parse: parseOFX})
+	Register(FormatCAMT053, funcParser{detect: detectCAMT053, parse: parseCAMT053})
+	Register(FormatQIF, funcParser{detect: detectQIF, parse: parseQIF})
+	for _, p := range thaiBanks {
+		Register(p.Format, p)
+	}
+}
+
+// Register makes a parser available under a format name, for Parse and
+// for Detect. It panics when the name is taken.
+func Register(format string, p Parser) {
+	if _, ok := parsers[format]; ok {
+		panic("statement: format " + format + " registered twice")
+	}
+	parsers[format] = p
+	detectOrder = append(detectOrder, format)
+}
+
+// Formats lists the registered format names.
+func Formats() []string {
+	formats := append([]string(nil), detectOrder...)
+	sort.Strings(formats)
+	return formats
+}
+
+// Detect guesses the format of a statement from its first bytes, returning
+// "" when no registered parser recognizes it.
+func Detect(data []byte) string {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	for _, format := range detectOrder {
+		if parsers[format].Detect(head) {
+			return format
+		}
+	}
+	return ""
+}
+
+// Parse reads a statement in the given format.
+func Parse(r io.Reader, format string) (*Statement, error) {
+	p, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q, use one of %s", format, strings.Join(Formats(), ", "))
+	}
+	return p.Parse(r)
+}
+
+// parseAmount reads a decimal amount, ignoring thousands separators.
+func parseAmount(s string) (float64, error) {
+	n, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return n, nil
+}
+
+// lineIDs makes IDs for the lines of formats that carry none: a hash of
+// the line's fields and of how many identical lines came before it in the
+// file, so the same file always yields the same IDs.
+type lineIDs map[string]int
+
+func (ids lineIDs) next(prefix string, fields ...string) string {
+	key := strings.Join(fields, "\x00")
+	sum := sha256.Sum256([]byte(key + "\x00" + strconv.Itoa(ids[key])))
+	ids[key]++
+	return prefix + hex.EncodeToString(sum[:8])
+}
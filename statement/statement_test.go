@@ -0,0 +1,21 @@
+//go:build unit
+
+package statement
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, FormatOFX, Detect([]byte("OFXHEADER:100\nDATA:OFXSGML\n")))
+	assert.Equal(t, FormatOFX, Detect([]byte(`<?xml version="1.0"?><OFX>`)))
+	assert.Equal(t, FormatQIF, Detect([]byte("\xef\xbb\xbf!Type:Bank\nD01/05/2024\n")))
+	assert.Equal(t, "", Detect([]byte("date,amount\n")))
+}
+
+func TestRegister(t *testing.T) {
+	assert.Contains(t, Formats(), FormatKrungthai)
+	assert.Panics(t, func() { Register(FormatOFX, funcParser{}) })
+}
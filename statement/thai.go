@@ -0,0 +1,153 @@
+package statement
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// buddhistEra is how many years the Thai calendar runs ahead: 2567 BE is
+// 2024 CE.
+const buddhistEra = 543
+
+// bangkok is the time zone statement times are written in.
+var bangkok = time.FixedZone("ICT", 7*3600)
+
+var thaiMonths = map[string]time.Month{
+	"มกราคม": time.January, "ม.ค.": time.January,
+	"กุมภาพันธ์": time.February, "ก.พ.": time.February,
+	"มีนาคม": time.March, "มี.ค.": time.March,
+	"เมษายน": time.April, "เม.ย.": time.April,
+	"พฤษภาคม": time.May, "พ.ค.": time.May,
+	"มิถุนายน": time.June, "มิ.ย.": time.June,
+	"กรกฎาคม": time.July, "ก.ค.": time.July,
+	"สิงหาคม": time.August, "ส.ค.": time.August,
+	"กันยายน": time.September, "ก.ย.": time.September,
+	"ตุลาคม": time.October, "ต.ค.": time.October,
+	"พฤศจิกายน": time.November, "พ.ย.": time.November,
+	"ธันวาคม": time.December, "ธ.ค.": time.December,
+}
+
+// thaiDigits maps the Thai digits ๐ to ๙ to 0 to 9.
+var thaiDigits = strings.NewReplacer("๐", "0", "๑", "1", "๒", "2", "๓", "3", "๔", "4", "๕", "5", "๖", "6", "๗", "7", "๘", "8", "๙", "9")
+
+// decodeThai returns data as UTF-8, decoding it from TIS-620, the Thai
+// code page many bank exports still use, when it is not UTF-8 already.
+// TIS-620 maps the Thai block one to one, 0xA1 being U+0E01.
+func decodeThai(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	var b strings.Builder
+	for _, c := range data {
+		if c >= 0xA1 && c <= 0xFB {
+			b.WriteRune(rune(c) + 0x0E01 - 0xA1)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// encodeThai is the reverse of decodeThai, for finding Thai text in a
+// TIS-620 file.
+func encodeThai(s string) []byte {
+	var b []byte
+	for _, r := range s {
+		if r >= 0x0E01 && r <= 0x0E5B {
+			b = append(b, byte(r-0x0E01+0xA1))
+		} else {
+			b = utf8.AppendRune(b, r)
+		}
+	}
+	return b
+}
+
+// parseThaiAmount reads numbers as Thai statements write them: Thai or
+// Arabic digits, thousands separators, an optional baht sign or unit, and
+// negatives in parentheses or with a trailing minus. An empty cell or a
+// lone dash is zero.
+func parseThaiAmount(s string) (float64, error) {
+	v := thaiDigits.Replace(strings.TrimSpace(s))
+	v = strings.NewReplacer(",", "", "฿", "", "บาท", "", " ", "").Replace(v)
+	if v == "" || v == "-" {
+		return 0, nil
+	}
+	negative := false
+	if strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")") {
+		negative, v = true, v[1:len(v)-1]
+	}
+	if strings.HasSuffix(v, "-") {
+		negative, v = true, v[:len(v)-1]
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if negative {
+		n = -n
+	}
+	return n, nil
+}
+
+var (
+	thaiClock   = regexp.MustCompile(`\s*(\d{1,2})[:.](\d{2})(?:[:.](\d{2}))?(?:\s*น\.)?$`)
+	thaiNumeric = regexp.MustCompile(`^(\d{1,2})[/\-.](\d{1,2})[/\-.](\d{2}|\d{4})$`)
+	thaiNamed   = regexp.MustCompile(`^(\d{1,2})\s*(\S+)\s*(\d{2}|\d{4})$`)
+)
+
+// gregorian turns a statement year into a Gregorian one. Four digit years
+// past 2400 and all two digit years are Buddhist era, as Thai banks write
+// them.
+func gregorian(year string) int {
+	y, _ := strconv.Atoi(year)
+	switch {
+	case len(year) == 2:
+		return 2500 + y - buddhistEra
+	case y > 2400:
+		return y - buddhistEra
+	}
+	return y
+}
+
+// parseThaiDate reads a day first date such as 05/01/2567, 05-01-67 or
+// 5 ม.ค. 2567, optionally followed by a time. Dates are kept as their
+// calendar day; a time is taken to be Bangkok time.
+func parseThaiDate(s string) (time.Time, error) {
+	v := thaiDigits.Replace(strings.TrimSpace(s))
+	var clock []string
+	if m := thaiClock.FindStringSubmatch(v); m != nil {
+		clock, v = m[1:], strings.TrimSpace(v[:len(v)-len(m[0])])
+	}
+
+	var day, year int
+	var month time.Month
+	if m := thaiNumeric.FindStringSubmatch(v); m != nil {
+		day, _ = strconv.Atoi(m[1])
+		mon, _ := strconv.Atoi(m[2])
+		month, year = time.Month(mon), gregorian(m[3])
+	} else if m := thaiNamed.FindStringSubmatch(v); m != nil && thaiMonths[m[2]] != 0 {
+		day, _ = strconv.Atoi(m[1])
+		month, year = thaiMonths[m[2]], gregorian(m[3])
+	} else {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if t.Month() != month || t.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	if clock != nil {
+		hour, _ := strconv.Atoi(clock[0])
+		minute, _ := strconv.Atoi(clock[1])
+		second, _ := strconv.Atoi(clock[2])
+		if hour > 23 || minute > 59 || second > 59 {
+			return time.Time{}, fmt.Errorf("invalid time in %q", s)
+		}
+		t = time.Date(year, month, day, hour, minute, second, 0, bangkok).UTC()
+	}
+	return t, nil
+}
@@ -0,0 +1,62 @@
+//go:build unit
+
+package statement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseThaiDate(t *testing.T) {
+	for value, expect := range map[string]time.Time{
+		"05/01/2567":        time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		"05-01-67":          time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		"5 ม.ค. 2567":       time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		"29 กุมภาพันธ์ 67":  time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"๐๕/๐๑/๒๕๖๗":        time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		"05/01/2024":        time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		"05/01/2567 14:32":  time.Date(2024, 1, 5, 7, 32, 0, 0, time.UTC),
+		"05/01/67 06.15 น.": time.Date(2024, 1, 4, 23, 15, 0, 0, time.UTC),
+	} {
+		got, err := parseThaiDate(value)
+
+		assert.NoError(t, err, value)
+		assert.Equal(t, expect, got, value)
+	}
+
+	_, err := parseThaiDate("30 ก.พ. 2567")
+
+	assert.EqualError(t, err, `invalid date "30 ก.พ. 2567"`)
+}
+
+func TestParseThaiAmount(t *testing.T) {
+	for value, expect := range map[string]float64{
+		"1,250.50":     1250.5,
+		"฿1,250.50":    1250.5,
+		"1,250.50 บาท": 1250.5,
+		"(1,250.50)":   -1250.5,
+		"1,250.50-":    -1250.5,
+		"-1,250.50":    -1250.5,
+		"๑,๒๕๐.๕๐":     1250.5,
+		"":             0,
+		"-":            0,
+	} {
+		got, err := parseThaiAmount(value)
+
+		assert.NoError(t, err, value)
+		assert.Equal(t, expect, got, value)
+	}
+
+	_, err := parseThaiAmount("abc")
+
+	assert.EqualError(t, err, `invalid amount "abc"`)
+}
+
+func TestDecodeThai(t *testing.T) {
+	text := "วันที่,ถอนเงิน"
+
+	assert.Equal(t, text, decodeThai(encodeThai(text)))
+	assert.Equal(t, text, decodeThai([]byte(text)))
+}
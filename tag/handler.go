@@ -0,0 +1,431 @@
+package tag
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/lib/pq"
+)
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+type usage struct {
+	tags   []string
+	amount float64
+}
+
+// summarize counts each tag on its own and together with its descendants.
+// An expense tagged both food and food/dessert counts once towards food.
+func summarize(usages []usage, catalog []string) []Tag {
+	byName := map[string]*Tag{}
+	get := func(name string) *Tag {
+		t, ok := byName[name]
+		if !ok {
+			t = &Tag{Name: name, Parent: Parent(name)}
+			byName[name] = t
+		}
+		return t
+	}
+	for _, name := range catalog {
+		get(name)
+	}
+
+	for _, u := range usages {
+		within := map[string]bool{}
+		for _, name := range u.tags {
+			t := get(name)
+			t.Count++
+			t.Amount += u.amount
+			for n := name; n != ""; n = Parent(n) {
+				within[n] = true
+			}
+		}
+		for name := range within {
+			t := get(name)
+			t.TotalCount++
+			t.TotalAmount += u.amount
+		}
+	}
+
+	tags := make([]Tag, 0, len(byName))
+	for _, t := range byName {
+		tags = append(tags, *t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT name FROM tags")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var catalog []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		catalog = append(catalog, name)
+	}
+
+	rows, err = h.DB.Query("SELECT tags, amount FROM expenses WHERE cardinality(tags) > 0")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var usages []usage
+	for rows.Next() {
+		var u usage
+		if err := rows.Scan(pq.Array(&u.tags), &u.amount); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		usages = append(usages, u)
+	}
+
+	c.JSON(http.StatusOK, summarize(usages, catalog))
+}
+
+func (h *handler) Create(c *gin.Context) {
+	var t Tag
+	if err := c.BindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t = Tag{Name: Clean(t.Name)}
+	if t.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	t.Parent = Parent(t.Name)
+
+	// keep the whole path in the catalog so food/dessert brings food along
+	for n := t.Name; n != ""; n = Parent(n) {
+		if _, err := h.DB.Exec("INSERT INTO tags(name) VALUES ($1) ON CONFLICT DO NOTHING", n); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, t)
+}
+
+// rewrite applies renames to every expense but reconciled ones, the
+// catalog, the synonyms, the export mappings and the goal budgets in one
+// transaction, and records each old name as a synonym of the new one so
+// later input keeps landing on the right tag.
+func (h *handler) rewrite(renames map[string]string) (int, error) {
+	var froms, patterns []string
+	for from := range renames {
+		froms = append(froms, from)
+		patterns = append(patterns, DescendantPattern(from))
+	}
+
+	tx, err := h.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// froms are cleaned, so compare against lowercased tags in case any were
+	// stored before tags were normalized on write
+	rows, err := tx.Query(`
+		SELECT id, tags FROM expenses
+		WHERE EXISTS (SELECT 1 FROM unnest(tags) t WHERE lower(t) = ANY($1) OR lower(t) LIKE ANY($2))
+		AND `+ledger.Unreconciled+`
+		FOR UPDATE`, pq.Array(froms), pq.Array(patterns))
+	if err != nil {
+		return 0, err
+	}
+	type affected struct {
+		id   int
+		tags []string
+	}
+	var expenses []affected
+	for rows.Next() {
+		var e affected
+		if err := rows.Scan(&e.id, pq.Array(&e.tags)); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		expenses = append(expenses, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	ids := make([]int, 0, len(expenses))
+	for _, e := range expenses {
+		if _, err := tx.Exec("UPDATE expenses SET tags = $2 WHERE id = $1 AND "+ledger.Unreconciled, e.id, pq.Array(retag(cleanAll(e.tags), renames))); err != nil {
+			return 0, err
+		}
+		ids = append(ids, e.id)
+	}
+	if err := ledger.SyncExpenses(tx, ids); err != nil {
+		return 0, err
+	}
+
+	rows, err = tx.Query("DELETE FROM tags WHERE lower(name) = ANY($1) OR lower(name) LIKE ANY($2) RETURNING name", pq.Array(froms), pq.Array(patterns))
+	if err != nil {
+		return 0, err
+	}
+	var removed []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		removed = append(removed, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, name := range retag(cleanAll(removed), renames) {
+		if _, err := tx.Exec("INSERT INTO tags(name) VALUES ($1) ON CONFLICT DO NOTHING", name); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := rewriteMappings(tx, renames, froms, patterns); err != nil {
+		return 0, err
+	}
+	if err := rewriteBudgets(tx, renames, froms, patterns); err != nil {
+		return 0, err
+	}
+
+	for from, to := range renames {
+		if _, err := tx.Exec("UPDATE tag_synonyms SET tag = $2 WHERE tag = $1", from, to); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO tag_synonyms(alias, tag) VALUES ($1, $2)
+			ON CONFLICT (alias) DO UPDATE SET tag = EXCLUDED.tag`, from, to); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(expenses), tx.Commit()
+}
+
+// rewriteMappings moves the export mappings of renamed tags to the new
+// names. A mapping the new name has already is kept.
+func rewriteMappings(tx *sql.Tx, renames map[string]string, froms, patterns []string) error {
+	rows, err := tx.Query(`
+		SELECT tag, account FROM export_mappings
+		WHERE lower(tag) = ANY($1) OR lower(tag) LIKE ANY($2)
+		FOR UPDATE`, pq.Array(froms), pq.Array(patterns))
+	if err != nil {
+		return err
+	}
+	var tags, accounts []string
+	for rows.Next() {
+		var tag, account string
+		if err := rows.Scan(&tag, &account); err != nil {
+			rows.Close()
+			return err
+		}
+		tags, accounts = append(tags, tag), append(accounts, account)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.Exec("DELETE FROM export_mappings WHERE tag = $1", tag); err != nil {
+			return err
+		}
+	}
+	for i, tag := range tags {
+		if _, err := tx.Exec("INSERT INTO export_mappings(tag, account) VALUES ($1, $2) ON CONFLICT (tag) DO NOTHING", retag([]string{Clean(tag)}, renames)[0], accounts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteBudgets points goals budgeted on renamed tags at the new names.
+func rewriteBudgets(tx *sql.Tx, renames map[string]string, froms, patterns []string) error {
+	rows, err := tx.Query(`
+		SELECT id, budget_tag FROM goals
+		WHERE lower(budget_tag) = ANY($1) OR lower(budget_tag) LIKE ANY($2)
+		FOR UPDATE`, pq.Array(froms), pq.Array(patterns))
+	if err != nil {
+		return err
+	}
+	var ids []int
+	var tags []string
+	for rows.Next() {
+		var id int
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			rows.Close()
+			return err
+		}
+		ids, tags = append(ids, id), append(tags, tag)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		if _, err := tx.Exec("UPDATE goals SET budget_tag = $2 WHERE id = $1", id, retag([]string{Clean(tags[i])}, renames)[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *handler) Rename(c *gin.Context) {
+	var r Rename
+	if err := c.BindJSON(&r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r.From, r.To = Clean(r.From), Clean(r.To)
+	if r.From == "" || r.To == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to are required"})
+		return
+	}
+	if IsWithin(r.To, r.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot rename a tag into itself"})
+		return
+	}
+
+	updated, err := h.rewrite(map[string]string{r.From: r.To})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, Result{Updated: updated})
+}
+
+func (h *handler) Merge(c *gin.Context) {
+	var m Merge
+	if err := c.BindJSON(&m); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m.Into = Clean(m.Into)
+	if m.Into == "" || len(m.From) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and into are required"})
+		return
+	}
+
+	renames := map[string]string{}
+	for _, from := range m.From {
+		from = Clean(from)
+		if from == "" || from == m.Into {
+			continue
+		}
+		if IsWithin(m.Into, from) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot merge " + from + " into its own child"})
+			return
+		}
+		renames[from] = m.Into
+	}
+	if len(renames) == 0 {
+		c.JSON(http.StatusOK, Result{})
+		return
+	}
+
+	updated, err := h.rewrite(renames)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, Result{Updated: updated})
+}
+
+func (h *handler) GetSynonyms(c *gin.Context) {
+	rows, err := h.DB.Query("SELECT alias, tag FROM tag_synonyms ORDER BY tag, alias")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	synonyms := []Synonym{}
+	for rows.Next() {
+		var s Synonym
+		if err := rows.Scan(&s.Alias, &s.Tag); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		synonyms = append(synonyms, s)
+	}
+
+	c.JSON(http.StatusOK, synonyms)
+}
+
+func (h *handler) CreateSynonym(c *gin.Context) {
+	var s Synonym
+	if err := c.BindJSON(&s); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	s.Alias, s.Tag = Clean(s.Alias), Clean(s.Tag)
+	if s.Alias == "" || s.Tag == "" || s.Alias == s.Tag {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "alias and tag must be different, non-empty tags"})
+		return
+	}
+	if IsWithin(s.Tag, s.Alias) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot make a tag a synonym of its own child"})
+		return
+	}
+
+	// synonyms resolve in one step, so the tag must not be an alias itself;
+	// this also rules out cycles
+	var canonical string
+	err := h.DB.QueryRow("SELECT tag FROM tag_synonyms WHERE alias = $1", s.Tag).Scan(&canonical)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": s.Tag + " is a synonym of " + canonical + ", use that instead"})
+		return
+	}
+	if err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// existing expenses move over like a merge, and synonyms pointing at the
+	// alias are repointed to the tag
+	if _, err := h.rewrite(map[string]string{s.Alias: s.Tag}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, s)
+}
+
+func (h *handler) DeleteSynonym(c *gin.Context) {
+	if _, err := h.DB.Exec("DELETE FROM tag_synonyms WHERE alias = $1", Clean(c.Param("alias"))); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
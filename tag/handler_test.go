@@ -0,0 +1,244 @@
+//go:build unit
+
+package tag
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenameTag(t *testing.T) {
+	t.Run("Rename Tag Into Its Own Child Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/tags/rename", strings.NewReader(`{"from": "food", "to": "food/meals"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/tags/rename", h.Rename)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Rename Tag Should Rewrite Expenses In One Transaction", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/tags/rename", strings.NewReader(`{"from": "Foods", "to": "food"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		spentAt := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, tags FROM expenses").
+			WithArgs(pq.Array([]string{"foods"}), pq.Array([]string{"foods/%"})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tags"}).
+				AddRow(1, pq.Array([]string{"foods", "food"})).
+				AddRow(2, pq.Array([]string{"foods/dessert"})))
+		mock.ExpectExec("UPDATE expenses SET tags").
+			WithArgs(1, pq.Array([]string{"food"})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE expenses SET tags").
+			WithArgs(2, pq.Array([]string{"food/dessert"})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM journal_entries").
+			WithArgs(pq.Array([]int{1, 2})).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery("SELECT (.+) FROM expenses e LEFT JOIN accounts a").
+			WithArgs(pq.Array([]int{1, 2})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "kind", "tags", "category", "spent_at", "account_id", "name", "type"}).
+				AddRow(1, "lunch", 120.0, "expense", pq.Array([]string{"food"}), "", spentAt, nil, nil, nil).
+				AddRow(2, "cake", 80.0, "expense", pq.Array([]string{"food/dessert"}), "", spentAt, nil, nil, nil))
+		mock.ExpectQuery("INSERT INTO journal_entries").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(10, "Expenses:Food", nil, 120.0).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(10, "Assets:Unassigned", nil, -120.0).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectQuery("INSERT INTO journal_entries").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(11, "Expenses:Food:Dessert", nil, 80.0).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectExec("INSERT INTO postings").
+			WithArgs(11, "Assets:Unassigned", nil, -80.0).
+			WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectQuery("DELETE FROM tags").
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foods"))
+		mock.ExpectExec("INSERT INTO tags").
+			WithArgs("food").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT tag, account FROM export_mappings").
+			WithArgs(pq.Array([]string{"foods"}), pq.Array([]string{"foods/%"})).
+			WillReturnRows(sqlmock.NewRows([]string{"tag", "account"}).AddRow("foods/dessert", "Expenses:Sweets"))
+		mock.ExpectExec("DELETE FROM export_mappings").
+			WithArgs("foods/dessert").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO export_mappings").
+			WithArgs("food/dessert", "Expenses:Sweets").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT id, budget_tag FROM goals").
+			WithArgs(pq.Array([]string{"foods"}), pq.Array([]string{"foods/%"})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "budget_tag"}).AddRow(3, "Foods"))
+		mock.ExpectExec("UPDATE goals SET budget_tag").
+			WithArgs(3, "food").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE tag_synonyms").
+			WithArgs("foods", "food").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO tag_synonyms").
+			WithArgs("foods", "food").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/tags/rename", h.Rename)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"updated":2}`, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestGetAllTags(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/tags", nil)
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT name FROM tags").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("food"))
+	mock.ExpectQuery("SELECT tags, amount FROM expenses").
+		WillReturnRows(sqlmock.NewRows([]string{"tags", "amount"}).
+			AddRow(pq.Array([]string{"food/dessert"}), 50.0))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.GET("/tags", h.GetAll)
+	expect := `[{"name":"food","count":0,"amount":0,"total_count":1,"total_amount":50},{"name":"food/dessert","parent":"food","count":1,"amount":50,"total_count":1,"total_amount":50}]`
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+}
+
+func TestCreateSynonym(t *testing.T) {
+	t.Run("Create Synonym Of An Alias Should Return Conflict", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/tags/synonyms", strings.NewReader(`{"alias": "meal", "tag": "Foods"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT tag FROM tag_synonyms WHERE alias = \\$1").
+			WithArgs("foods").
+			WillReturnRows(sqlmock.NewRows([]string{"tag"}).AddRow("food"))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/tags/synonyms", h.CreateSynonym)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("Create Synonym Should Retag Existing Expenses", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/tags/synonyms", strings.NewReader(`{"alias": "Meal", "tag": "food"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT tag FROM tag_synonyms WHERE alias = \\$1").
+			WithArgs("food").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, tags FROM expenses (.+) lower\\(t\\) = ANY\\(\\$1\\)").
+			WithArgs(pq.Array([]string{"meal"}), pq.Array([]string{"meal/%"})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "tags"}))
+		mock.ExpectQuery("DELETE FROM tags").
+			WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		mock.ExpectQuery("SELECT tag, account FROM export_mappings").
+			WillReturnRows(sqlmock.NewRows([]string{"tag", "account"}))
+		mock.ExpectQuery("SELECT id, budget_tag FROM goals").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "budget_tag"}))
+		mock.ExpectExec("UPDATE tag_synonyms").
+			WithArgs("meal", "food").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO tag_synonyms").
+			WithArgs("meal", "food").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.POST("/tags/synonyms", h.CreateSynonym)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, `{"alias":"meal","tag":"food"}`, strings.TrimSpace(rec.Body.String()))
+	})
+}
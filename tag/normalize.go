@@ -0,0 +1,201 @@
+package tag
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/jsritawan/assessment/ledger"
+	"github.com/lib/pq"
+)
+
+// Separator splits a hierarchical tag such as food/dessert into its parent
+// and child.
+const Separator = "/"
+
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Clean lowercases and trims a tag, including each level of a hierarchical
+// one, so "Food / Dessert" becomes "food/dessert".
+func Clean(name string) string {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(name)), Separator)
+	kept := parts[:0]
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, Separator)
+}
+
+func cleanAll(tags []string) []string {
+	cleaned := make([]string, len(tags))
+	for i, t := range tags {
+		cleaned[i] = Clean(t)
+	}
+	return cleaned
+}
+
+// Parent returns the tag one level up, or an empty string for a top-level
+// tag.
+func Parent(name string) string {
+	if i := strings.LastIndex(name, Separator); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// IsWithin reports whether name is root itself or one of its descendants.
+func IsWithin(name, root string) bool {
+	return name == root || strings.HasPrefix(name, root+Separator)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// DescendantPattern is a LIKE pattern matching every tag below name.
+func DescendantPattern(name string) string {
+	return escapeLike(name+Separator) + "%"
+}
+
+func dedupe(tags []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, t := range tags {
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	return result
+}
+
+// Normalize cleans tags, replaces synonyms with their canonical tag and
+// drops duplicates, keeping the order they were given in.
+func Normalize(db Querier, tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return tags, nil
+	}
+
+	cleaned := cleanAll(tags)
+
+	rows, err := db.Query("SELECT alias, tag FROM tag_synonyms WHERE alias = ANY($1)", pq.Array(cleaned))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	synonyms := map[string]string{}
+	for rows.Next() {
+		var s Synonym
+		if err := rows.Scan(&s.Alias, &s.Tag); err != nil {
+			return nil, err
+		}
+		synonyms[s.Alias] = s.Tag
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, t := range cleaned {
+		if canonical, ok := synonyms[t]; ok {
+			cleaned[i] = canonical
+		}
+	}
+	return dedupe(cleaned), nil
+}
+
+// cleanPattern matches a tag Clean leaves as it is, short of lowercasing:
+// levels without surrounding spaces, joined by single separators.
+const cleanPattern = `^[^/\s]([^/]*[^/\s])?(/[^/\s]([^/]*[^/\s])?)*$`
+
+// Backfill cleans tags stored on expenses and in the catalog before tags
+// were normalized on write, so renames and merges reach them too.
+func Backfill(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, tags FROM expenses
+		WHERE EXISTS (SELECT 1 FROM unnest(tags) t WHERE t <> lower(t) OR t !~ $1)
+		FOR UPDATE`, cleanPattern)
+	if err != nil {
+		return err
+	}
+	tags := map[int][]string{}
+	var ids []int
+	for rows.Next() {
+		var id int
+		var t []string
+		if err := rows.Scan(&id, pq.Array(&t)); err != nil {
+			rows.Close()
+			return err
+		}
+		tags[id] = t
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec("UPDATE expenses SET tags = $2 WHERE id = $1", id, pq.Array(dedupe(cleanAll(tags[id])))); err != nil {
+			return err
+		}
+	}
+	if err := ledger.SyncExpenses(tx, ids); err != nil {
+		return err
+	}
+
+	rows, err = tx.Query("DELETE FROM tags WHERE name <> lower(name) OR name !~ $1 RETURNING name", cleanPattern)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, name := range dedupe(cleanAll(names)) {
+		if _, err := tx.Exec("INSERT INTO tags(name) VALUES ($1) ON CONFLICT DO NOTHING", name); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// retag renames every tag that is, or sits below, a key of renames. Children
+// move along with their parent, so renaming food to meals turns food/dessert
+// into meals/dessert.
+func retag(tags []string, renames map[string]string) []string {
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t
+		// the most specific rename wins when both a tag and its parent move
+		match := ""
+		for from := range renames {
+			if IsWithin(t, from) && len(from) > len(match) {
+				match = from
+			}
+		}
+		if match != "" {
+			result[i] = renames[match] + t[len(match):]
+		}
+	}
+	return dedupe(result)
+}
@@ -0,0 +1,76 @@
+//go:build unit
+
+package tag
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClean(t *testing.T) {
+	assert.Equal(t, "food", Clean(" Food "))
+	assert.Equal(t, "food/dessert", Clean("Food / Dessert/"))
+	assert.Equal(t, "", Clean(" / "))
+}
+
+func TestNormalize(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT alias, tag FROM tag_synonyms").
+		WithArgs(pq.Array([]string{"food", "foods", "beverage"})).
+		WillReturnRows(sqlmock.NewRows([]string{"alias", "tag"}).AddRow("foods", "food"))
+
+	// Act
+	tags, err := Normalize(db, []string{"Food", "foods", "beverage"})
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"food", "beverage"}, tags)
+	}
+}
+
+func TestRetag(t *testing.T) {
+	t.Run("Rename Should Move Children Along", func(t *testing.T) {
+		tags := retag([]string{"food/dessert", "beverage", "foodcourt"}, map[string]string{"food": "meals"})
+
+		assert.Equal(t, []string{"meals/dessert", "beverage", "foodcourt"}, tags)
+	})
+
+	t.Run("Merge Should Drop Duplicates", func(t *testing.T) {
+		tags := retag([]string{"Food", "foods", "food"}, map[string]string{"Food": "food", "foods": "food"})
+
+		assert.Equal(t, []string{"food"}, tags)
+	})
+
+	t.Run("Most Specific Rename Should Win", func(t *testing.T) {
+		tags := retag([]string{"food/dessert/cake"}, map[string]string{"food": "meals", "food/dessert": "sweets"})
+
+		assert.Equal(t, []string{"sweets/cake"}, tags)
+	})
+}
+
+func TestSummarize(t *testing.T) {
+	usages := []usage{
+		{tags: []string{"food", "food/dessert"}, amount: 100},
+		{tags: []string{"food/dessert"}, amount: 50},
+		{tags: []string{"beverage"}, amount: 20},
+	}
+
+	tags := summarize(usages, []string{"transport"})
+
+	assert.Equal(t, []Tag{
+		{Name: "beverage", Count: 1, Amount: 20, TotalCount: 1, TotalAmount: 20},
+		{Name: "food", Count: 1, Amount: 100, TotalCount: 2, TotalAmount: 150},
+		{Name: "food/dessert", Parent: "food", Count: 2, Amount: 150, TotalCount: 2, TotalAmount: 150},
+		{Name: "transport"},
+	}, tags)
+}
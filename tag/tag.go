@@ -0,0 +1,29 @@
+package tag
+
+type Tag struct {
+	Name        string  `json:"name"`
+	Parent      string  `json:"parent,omitempty"`
+	Count       int     `json:"count"`
+	Amount      float64 `json:"amount"`
+	TotalCount  int     `json:"total_count"`
+	TotalAmount float64 `json:"total_amount"`
+}
+
+type Synonym struct {
+	Alias string `json:"alias"`
+	Tag   string `json:"tag"`
+}
+
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Merge struct {
+	From []string `json:"from"`
+	Into string   `json:"into"`
+}
+
+type Result struct {
+	Updated int `json:"updated"`
+}
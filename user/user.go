@@ -0,0 +1,55 @@
+package user
+
+import (
+	"database/sql"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	Header     = "X-User"
+	contextKey = "user"
+)
+
+// Roles in the approval of expenses. Users without a role in user_roles
+// are employees.
+const (
+	RoleEmployee = "employee"
+	RoleManager  = "manager"
+	RoleFinance  = "finance"
+)
+
+type QueryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Identify records the user named in the X-User header on the request
+// context. The shared Authorization token says the caller may use the API;
+// this says who they are.
+func Identify(c *gin.Context) {
+	if name := c.GetHeader(Header); name != "" {
+		c.Set(contextKey, name)
+	}
+	c.Next()
+}
+
+// FromContext returns the current user, or an empty string when the
+// request did not name one.
+func FromContext(c *gin.Context) string {
+	return c.GetString(contextKey)
+}
+
+// Role looks up the role of a user in user_roles, which only operators
+// write, so callers cannot grant themselves one. Unknown and unnamed users
+// are employees.
+func Role(db QueryRower, name string) (string, error) {
+	if name == "" {
+		return RoleEmployee, nil
+	}
+	var role string
+	err := db.QueryRow("SELECT role FROM user_roles WHERE user_name = $1", name).Scan(&role)
+	if err == sql.ErrNoRows {
+		return RoleEmployee, nil
+	}
+	return role, err
+}
@@ -0,0 +1,99 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/query"
+)
+
+var columns = []string{"id", "title", "amount", "note", "tags", "spent_at", "category", "merchant_id", "account_id", "status", "tax", "deduction_id"}
+
+var sortable = map[string]bool{
+	"id":       true,
+	"title":    true,
+	"amount":   true,
+	"spent_at": true,
+	"category": true,
+}
+
+// validate checks the filter, sort and columns of v and fills in defaults.
+func validate(v *View) error {
+	if strings.TrimSpace(v.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if v.Filter != "" {
+		if _, err := query.Parse(v.Filter); err != nil {
+			return err
+		}
+	}
+
+	if v.Sort == "" {
+		v.Sort = "id"
+	}
+	if !sortable[strings.TrimPrefix(v.Sort, "-")] {
+		return fmt.Errorf("cannot sort by %q", v.Sort)
+	}
+
+	if len(v.Columns) == 0 {
+		v.Columns = append([]string(nil), columns...)
+	}
+	for _, col := range v.Columns {
+		if !contains(columns, col) {
+			return fmt.Errorf("unknown column %q", col)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// orderBy is only called with a sort that passed validate, so the column
+// name comes from the sortable whitelist.
+func orderBy(sort string) string {
+	if strings.HasPrefix(sort, "-") {
+		return " ORDER BY " + sort[1:] + " DESC, id"
+	}
+	return " ORDER BY " + sort + ", id"
+}
+
+func project(e expense.Expense, cols []string) map[string]interface{} {
+	row := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		switch col {
+		case "id":
+			row[col] = e.ID
+		case "title":
+			row[col] = e.Title
+		case "amount":
+			row[col] = e.Amount
+		case "note":
+			row[col] = e.Note
+		case "tags":
+			row[col] = e.Tags
+		case "spent_at":
+			row[col] = e.SpentAt
+		case "category":
+			row[col] = e.Category
+		case "merchant_id":
+			row[col] = e.MerchantID
+		case "account_id":
+			row[col] = e.AccountID
+		case "status":
+			row[col] = e.Status
+		case "tax":
+			row[col] = e.Tax
+		case "deduction_id":
+			row[col] = e.DeductionID
+		}
+	}
+	return row
+}
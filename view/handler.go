@@ -0,0 +1,269 @@
+package view
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/expense"
+	"github.com/jsritawan/assessment/query"
+	"github.com/jsritawan/assessment/user"
+	"github.com/lib/pq"
+)
+
+const selectView = `
+	SELECT v.id, v.owner, v.name, v.filter, v.sort, v.columns,
+		ARRAY(SELECT s.user_name FROM view_shares s WHERE s.view_id = v.id ORDER BY s.user_name)
+	FROM saved_views v`
+
+type handler struct {
+	DB *sql.DB
+}
+
+func NewHandler(db *sql.DB) *handler {
+	return &handler{
+		DB: db,
+	}
+}
+
+func currentUser(c *gin.Context) (string, bool) {
+	name := user.FromContext(c)
+	if name == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": user.Header + " header is required"})
+		return "", false
+	}
+	return name, true
+}
+
+func scanView(row interface{ Scan(...interface{}) error }, v *View) error {
+	return row.Scan(&v.ID, &v.Owner, &v.Name, &v.Filter, &v.Sort, pq.Array(&v.Columns), pq.Array(&v.SharedWith))
+}
+
+// load fetches the view in the id parameter if the current user owns it or
+// it was shared with them. Views the user cannot see are reported as not
+// found rather than forbidden.
+func (h *handler) load(c *gin.Context) (View, bool) {
+	var v View
+	name, ok := currentUser(c)
+	if !ok {
+		return v, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return v, false
+	}
+
+	err = scanView(h.DB.QueryRow(selectView+" WHERE v.id = $1", id), &v)
+	if err == sql.ErrNoRows || (err == nil && v.Owner != name && !contains(v.SharedWith, name)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "view not found"})
+		return v, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return v, false
+	}
+
+	v.ReadOnly = v.Owner != name
+	return v, true
+}
+
+func (h *handler) loadOwned(c *gin.Context) (View, bool) {
+	v, ok := h.load(c)
+	if ok && v.ReadOnly {
+		c.JSON(http.StatusForbidden, gin.H{"error": "view is shared read-only"})
+		return v, false
+	}
+	return v, ok
+}
+
+func (h *handler) Create(c *gin.Context) {
+	name, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var v View
+	if err := c.BindJSON(&v); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validate(&v); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	v.Owner = name
+	v.SharedWith = []string{}
+
+	row := h.DB.QueryRow(`
+		INSERT INTO saved_views(owner, name, filter, sort, columns)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+		`,
+		v.Owner, v.Name, v.Filter, v.Sort, pq.Array(v.Columns))
+	if err := row.Scan(&v.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, v)
+}
+
+func (h *handler) GetAll(c *gin.Context) {
+	name, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.DB.Query(selectView+`
+		WHERE v.owner = $1
+			OR EXISTS (SELECT 1 FROM view_shares s WHERE s.view_id = v.id AND s.user_name = $1)
+		ORDER BY v.id`, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	views := []View{}
+	for rows.Next() {
+		var v View
+		if err := scanView(rows, &v); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		v.ReadOnly = v.Owner != name
+		views = append(views, v)
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+func (h *handler) Get(c *gin.Context) {
+	if v, ok := h.load(c); ok {
+		c.JSON(http.StatusOK, v)
+	}
+}
+
+func (h *handler) Update(c *gin.Context) {
+	v, ok := h.loadOwned(c)
+	if !ok {
+		return
+	}
+
+	var update View
+	if err := c.BindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validate(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	v.Name, v.Filter, v.Sort, v.Columns = update.Name, update.Filter, update.Sort, update.Columns
+
+	_, err := h.DB.Exec("UPDATE saved_views SET name=$2, filter=$3, sort=$4, columns=$5 WHERE id=$1",
+		v.ID, v.Name, v.Filter, v.Sort, pq.Array(v.Columns))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	v, ok := h.loadOwned(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM saved_views WHERE id = $1", v.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *handler) Share(c *gin.Context) {
+	v, ok := h.loadOwned(c)
+	if !ok {
+		return
+	}
+
+	var share Share
+	if err := c.BindJSON(&share); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if share.User == "" || share.User == v.Owner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user"})
+		return
+	}
+
+	_, err := h.DB.Exec("INSERT INTO view_shares(view_id, user_name) VALUES ($1, $2) ON CONFLICT DO NOTHING", v.ID, share.User)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !contains(v.SharedWith, share.User) {
+		v.SharedWith = append(v.SharedWith, share.User)
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+func (h *handler) Unshare(c *gin.Context) {
+	v, ok := h.loadOwned(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.DB.Exec("DELETE FROM view_shares WHERE view_id = $1 AND user_name = $2", v.ID, c.Param("user")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *handler) Expenses(c *gin.Context) {
+	v, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	stmt := "SELECT " + expense.Columns + " FROM expenses WHERE kind = $1"
+	args := []interface{}{expense.KindExpense}
+	if v.Filter != "" {
+		node, err := query.Parse(v.Filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		cond, qargs := query.Compile(node, len(args))
+		stmt += " AND " + cond
+		args = append(args, qargs...)
+	}
+
+	rows, err := h.DB.Query(stmt+orderBy(v.Sort), args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		var e expense.Expense
+		if err := rows.Scan(e.Fields()...); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		result = append(result, project(e, v.Columns))
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,208 @@
+//go:build unit
+
+package view
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jsritawan/assessment/user"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var viewColumns = []string{"id", "owner", "name", "filter", "sort", "columns", "shared_with"}
+
+func TestCreateView(t *testing.T) {
+	t.Run("Create View Without User Should Return Unauthorized", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(`{"name": "food"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.POST("/views", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Create View With Invalid Filter Should Return Bad Request", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(`{"name": "food", "filter": "colour:red"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(user.Header, "ann")
+		rec := httptest.NewRecorder()
+
+		db, _, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.POST("/views", h.Create)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Create View Should Return Created With Defaults", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(`{"name": "food", "filter": "tag:food", "sort": "-amount"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(user.Header, "ann")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("INSERT INTO saved_views").
+			WithArgs("ann", "food", "tag:food", "-amount", pq.Array(columns)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.POST("/views", h.Create)
+		expect := `{"id":1,"owner":"ann","name":"food","filter":"tag:food","sort":"-amount","columns":["id","title","amount","note","tags","spent_at","category","merchant_id","account_id","status","tax","deduction_id"],"shared_with":[],"read_only":false}`
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+	})
+}
+
+func TestSharedView(t *testing.T) {
+	t.Run("Update Shared View Should Return Forbidden", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodPut, "/views/1", strings.NewReader(`{"name": "mine now"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(user.Header, "bob")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM saved_views").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(viewColumns).
+				AddRow(1, "ann", "food", "tag:food", "id", pq.Array(columns), pq.Array([]string{"bob"})))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.PUT("/views/:id", h.Update)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Get View Not Shared Should Return Not Found", func(t *testing.T) {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/views/1", nil)
+		req.Header.Set(user.Header, "eve")
+		rec := httptest.NewRecorder()
+
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectQuery("SELECT (.+) FROM saved_views").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(viewColumns).
+				AddRow(1, "ann", "food", "tag:food", "id", pq.Array(columns), pq.Array([]string{"bob"})))
+
+		gin.SetMode(gin.TestMode)
+		h := NewHandler(db)
+		r := gin.Default()
+		r.Use(user.Identify)
+		r.GET("/views/:id", h.Get)
+
+		// Act
+		r.ServeHTTP(rec, req)
+
+		// Assert
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestViewExpenses(t *testing.T) {
+	// Arrange
+	req := httptest.NewRequest(http.MethodGet, "/views/1/expenses", nil)
+	req.Header.Set(user.Header, "bob")
+	rec := httptest.NewRecorder()
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(selectView + " WHERE v.id = $1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(viewColumns).
+			AddRow(1, "ann", "food", "amount>=50", "-amount", pq.Array([]string{"title", "amount"}), pq.Array([]string{"bob"})))
+	mock.ExpectQuery("SELECT id, title, amount, note, tags, spent_at, category, merchant_id, account_id, status, tax, deduction_id FROM expenses WHERE kind = $1 AND (amount >= $2) ORDER BY amount DESC, id").
+		WithArgs("expense", 50.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "amount", "note", "tags", "spent_at", "category", "merchant_id", "account_id", "status", "tax", "deduction_id"}).
+			AddRow(2, "iPhone", 66900, "gift", pq.Array([]string{"gadget"}), time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), "", nil, nil, "draft", nil, nil).
+			AddRow(1, "smoothie", 79, "", pq.Array([]string{"food"}), time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC), "", nil, nil, "draft", nil, nil))
+
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(db)
+	r := gin.Default()
+	r.Use(user.Identify)
+	r.GET("/views/:id/expenses", h.Expenses)
+	expect := `[{"amount":66900,"title":"iPhone"},{"amount":79,"title":"smoothie"}]`
+
+	// Act
+	r.ServeHTTP(rec, req)
+
+	// Assert
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, expect, strings.TrimSpace(rec.Body.String()))
+}
@@ -0,0 +1,16 @@
+package view
+
+type View struct {
+	ID         int      `json:"id"`
+	Owner      string   `json:"owner"`
+	Name       string   `json:"name"`
+	Filter     string   `json:"filter"`
+	Sort       string   `json:"sort"`
+	Columns    []string `json:"columns"`
+	SharedWith []string `json:"shared_with"`
+	ReadOnly   bool     `json:"read_only"`
+}
+
+type Share struct {
+	User string `json:"user"`
+}